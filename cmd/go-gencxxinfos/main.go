@@ -17,11 +17,22 @@ var fname *string = flag.String("fname", "", "gccxml file or clang file from whi
 var oname *string = flag.String("o", "ids.db", "output file in which to store cxxinfos")
 var libname *string = flag.String("libname", "", "name of the C/C++ library we are providing cxxinfos for.")
 var hdrname *string = flag.String("hdrname", "", "name of the C/C++ header file holding declarations corresponding to the cxxinfos we are providing.")
+var target *string = flag.String("target", "", "GOOS/GOARCH platform the cxxinfos are built for (default: this host)")
 
 func main() {
 	fmt.Printf("== go-gencxxinfos ==\n")
 	flag.Parse()
 
+	if *target != "" {
+		t, err := cxxtypes.TargetByName(*target)
+		if err != nil {
+			fmt.Printf("**err** %v (known targets: %v)\n", err, cxxtypes.Targets())
+			os.Exit(1)
+		}
+		cxxtypes.SetTarget(t)
+	}
+	fmt.Printf("target: %v\n", cxxtypes.CurrentTarget())
+
 	f, err := os.Open(*fname)
 	if err != nil {
 		fmt.Printf("**err** %v\n", err)
@@ -15,6 +15,20 @@ import (
 const dbg = 0
 
 var fname *string = flag.String("fname", "", "path to the cxxinfos registry file")
+var sel *string = flag.String("select", "", "comma-separated patterns of additional identifiers to wrap (e.g. Copy*,NS::Foo)")
+var overloads *string = flag.String("overloads", "dispatch", "how to wrap overloaded functions (dispatch|typed|both)")
+var views *string = flag.String("views", "", "comma-separated patterns of functions whose contiguous containers and (T*, length) pairs map to zero-copy Go slices")
+var params *string = flag.String("params", "", "comma-separated directions of function parameters, returned as Go results when out|inout (e.g. get_range(lo)=out,Foo::update(v)=inout,sum(data)=slice)")
+var out_refs *string = flag.String("out-refs", "none", "how to wrap non-const references to fundamental types (none|out|inout)")
+var errors *string = flag.String("errors", "", "comma-separated status codes returned as Go errors, as type<op>value[@fct] success predicates, value being an integer or an enumerator (e.g. StatusCode==0,int>=0@App::*,Code==Ok)")
+var templates *string = flag.String("templates", "", "comma-separated template instances to select, with their Go names (e.g. NS::tmpl<int>=TmplInt,NS::tmpl=Tmpl)")
+var generics *bool = flag.Bool("generics", false, "emit Go generic facades (go>=1.18) of class templates whose instances share the same shape")
+var namespaces *string = flag.String("namespaces", "flat", "how to map C++ namespaces to Go (flat|strip|packages)")
+var ns_strip *string = flag.String("ns-strip", "", "comma-separated root namespaces to drop from the Go names (namespaces=strip)")
+var ns_import *string = flag.String("ns-import", "", "import path of the generated package, imported by the per-namespace packages (namespaces=packages)")
+var nested_sep *string = flag.String("nested-sep", "", "separator between the Go names of a class and of its nested types (e.g. '' for OuterInner, '_' for Outer_Inner)")
+var typedefs *string = flag.String("typedefs", "named", "how to wrap the selected typedefs as Go types (named|alias)")
+var using *string = flag.String("using", "", "comma-separated using-declarations of base class member functions (e.g. NS::D::f for 'using Base::f' in NS::D)")
 
 func main() {
 	fmt.Printf("== go-gencxxwrapper ==\n")
@@ -139,6 +153,20 @@ func main() {
 	gen.Fd.Name = "mylib"
 	gen.Fd.Package = gen.Fd.Name
 	gen.Fd.Header = "mylib.hh"
+	gen.Args["select"] = *sel
+	gen.Args["overloads"] = *overloads
+	gen.Args["views"] = *views
+	gen.Args["params"] = *params
+	gen.Args["out-refs"] = *out_refs
+	gen.Args["errors"] = *errors
+	gen.Args["templates"] = *templates
+	gen.Args["generics"] = *generics
+	gen.Args["namespaces"] = *namespaces
+	gen.Args["ns-strip"] = *ns_strip
+	gen.Args["ns-import"] = *ns_import
+	gen.Args["nested-sep"] = *nested_sep
+	gen.Args["typedefs"] = *typedefs
+	gen.Args["using"] = *using
 
 	err = gen.GenerateAllFiles()
 	if err != nil {
@@ -42,7 +42,9 @@ func LoadIds(distillerName string, r io.Reader) error {
 // 	Content []Id
 // }
 
-// SaveIds dumps all cxxtypes.Id into the specified io.Writer
+// SaveIds dumps all cxxtypes.Id into the specified io.Writer.
+// The description of the target platform is recorded in the metadata under
+// the "Target" key.
 func SaveIds(dst io.Writer, metadata map[string]interface{}) error {
 
 	enc := gob.NewEncoder(dst)
@@ -59,9 +61,14 @@ func SaveIds(dst io.Writer, metadata map[string]interface{}) error {
 	for _, k := range keys {
 		vals = append(vals, g_ids[k])
 	}
+	md := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md["Target"] = g_target
 	d["Keys"] = keys
 	d["Content"] = vals
-	d["MetaData"] = metadata
+	d["MetaData"] = md
 	return enc.Encode(d)
 }
 
@@ -84,6 +91,12 @@ func (g *gobDistiller) LoadIdentifiers(src io.Reader) error {
 	keys := d["Keys"].([]string)
 	ids := d["Content"].([]Id)
 
+	if md, ok := d["MetaData"].(map[string]interface{}); ok {
+		if t, ok := md["Target"].(Target); ok {
+			g_target = t
+		}
+	}
+
 	//fmt.Printf("n-keys: %v\n", len(keys))
 	//fmt.Printf("n-vals: %v\n", len(ids))
 
@@ -112,12 +125,14 @@ func init() {
 	// register identifiers with gob
 	gob.Register([]Id{})
 	gob.Register(&Namespace{})
+	gob.Register(&NamespaceAlias{})
 	gob.Register(&Function{})
 	gob.Register(&OverloadFunctionSet{})
 	gob.Register(&Member{})
 
-	// register the metadata type with gob.
+	// register the metadata types with gob.
 	gob.Register(map[string]interface{}{})
+	gob.Register(Target{})
 
 	// register the "default" distiller
 	RegisterDistiller("gob", &gobDistiller{})
@@ -0,0 +1,53 @@
+package cxxtypes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTargetMetaData(t *testing.T) {
+	host := CurrentTarget()
+	defer SetTarget(host)
+
+	for _, name := range []string{"linux/arm", "windows/amd64"} {
+		tgt, err := TargetByName(name)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		SetTarget(tgt)
+
+		buf := new(bytes.Buffer)
+		err = SaveIds(buf, map[string]interface{}{"Library": "mylib"})
+		if err != nil {
+			t.Fatalf("could not save ids: %v", err)
+		}
+
+		SetTarget(host)
+		err = LoadIds("gob", buf)
+		if err != nil {
+			t.Fatalf("could not load ids: %v", err)
+		}
+
+		if CurrentTarget() != tgt {
+			t.Errorf("expected [%v], got [%v]", tgt, CurrentTarget())
+		}
+	}
+}
+
+func TestPtrSizeFromTarget(t *testing.T) {
+	host := CurrentTarget()
+	defer SetTarget(host)
+
+	ptr := &PtrType{}
+	for _, name := range []string{"linux/386", "linux/arm64"} {
+		tgt, err := TargetByName(name)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		SetTarget(tgt)
+		if sz := ptr.TypeSize(); sz != tgt.PtrSize {
+			t.Errorf("[%s]: expected pointer size [%d], got [%d]",
+				name, tgt.PtrSize, sz)
+		}
+	}
+}
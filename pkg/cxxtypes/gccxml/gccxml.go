@@ -83,7 +83,7 @@ func init() {
 	g_n2tk = map[string]cxxtypes.TypeKind{
 		"void":           cxxtypes.TK_Void,
 		"bool":           cxxtypes.TK_Bool,
-		"char":           gccxml_get_char_type(),
+		"char":           cxxtypes.TK_Char_S, // see gccxml_char_kind
 		"signed char":    cxxtypes.TK_SChar,
 		"unsigned char":  cxxtypes.TK_UChar,
 		"wchar_t":        cxxtypes.TK_WChar,
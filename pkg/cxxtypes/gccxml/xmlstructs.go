@@ -268,9 +268,12 @@ func (x *xmlTree) gencxxtypes() error {
 		if !ok {
 			panic("no such builtin type [" + v.name() + "]")
 		}
+		if v.name() == "char" {
+			tk = gccxml_char_kind()
+		}
 		ct := cxxtypes.NewFundamentalType(
 			v.name(),
-			str_to_uintptr(v.Size),
+			str_to_size(v.Size),
 			tk,
 			"::", //scope,
 		)
@@ -283,6 +286,10 @@ func (x *xmlTree) gencxxtypes() error {
 		//fmt.Printf("%v\n", t)
 	}
 
+	for _, v := range x.NamespaceAliases {
+		gen_id_from_gccxml(v)
+	}
+
 	for _, v := range x.Arrays {
 		//fmt.Printf("\n%s... (%s) [%v]\n", v.name(), v.id(), genTypeName(v.id(), gtnCfg{}))
 		gen_id_from_gccxml(v)
@@ -409,6 +416,10 @@ func (x *xmlTree) gencxxtypes() error {
 			}
 			if len(id.Params) == 1 {
 				p := cxxtypes.IdByName(id.Params[0].Type).(cxxtypes.Type)
+				if p.TypeKind() == cxxtypes.TK_RValueRef {
+					// a move constructor
+					continue
+				}
 				cc := true
 				for cc {
 					switch pp := p.(type) {
@@ -515,7 +526,7 @@ func (x *xmlArray) idkind() cxxtypes.IdKind {
 }
 
 func (x *xmlArray) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 func (x *xmlArray) typename() string {
@@ -622,7 +633,7 @@ func (x *xml_record) offset() uintptr {
 }
 
 func (x *xml_record) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 func (x xml_record) infos() string {
@@ -842,7 +853,7 @@ func (x *xmlCvQualifiedType) idkind() cxxtypes.IdKind {
 }
 
 func (x *xmlCvQualifiedType) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlDestructor struct {
@@ -981,7 +992,7 @@ func (x *xmlEnumeration) offset() uintptr {
 }
 
 func (x *xmlEnumeration) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlField struct {
@@ -1061,8 +1072,10 @@ func (x *xmlField) access() cxxtypes.AccessSpecifier {
 	return str_to_access(x.Access)
 }
 
+// offset returns the offset of the field in bytes. (GCC-XML records it in
+// bits)
 func (x *xmlField) offset() uintptr {
-	return str_to_uintptr(x.Offset)
+	return str_to_size(x.Offset)
 }
 
 type xmlFile struct {
@@ -1231,7 +1244,7 @@ func (x *xmlFundamentalType) idkind() cxxtypes.IdKind {
 }
 
 func (x *xmlFundamentalType) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlMethod struct {
@@ -1566,7 +1579,7 @@ func (x *xmlPointerType) idkind() cxxtypes.IdKind {
 }
 
 func (x *xmlPointerType) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlReferenceType struct {
@@ -1613,7 +1626,7 @@ func (x *xmlReferenceType) idkind() cxxtypes.IdKind {
 }
 
 func (x *xmlReferenceType) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlStruct struct {
@@ -1762,7 +1775,7 @@ func (x *xmlUnion) offset() uintptr {
 }
 
 func (x *xmlUnion) size() uintptr {
-	return str_to_uintptr(x.Size)
+	return str_to_size(x.Size)
 }
 
 type xmlVariable struct {
@@ -1855,6 +1868,15 @@ func isAlpha(c uint8) bool {
 	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
 }
 
+// gccxml_char_kind returns the kind of a plain 'char' for the target
+// the registry is built for.
+func gccxml_char_kind() cxxtypes.TypeKind {
+	if cxxtypes.CurrentTarget().CharSigned {
+		return cxxtypes.TK_Char_S
+	}
+	return cxxtypes.TK_Char_U
+}
+
 // str_to_uintptr returns a uintptr from a string
 func str_to_uintptr(s string) uintptr {
 	if s == "" {
@@ -1870,6 +1892,11 @@ func str_to_uintptr(s string) uintptr {
 	return uintptr(i)
 }
 
+// str_to_size returns a size in bytes from a GCC-XML size string (in bits)
+func str_to_size(s string) uintptr {
+	return str_to_uintptr(s) / 8
+}
+
 // str_to_bool returns a bool from a string
 func str_to_bool(s string) bool {
 	if s == "" || s == "0" {
@@ -2567,7 +2594,7 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 			p := cxxtypes.NewParameter(
 				arg.Name,
 				tn,
-				arg.Default != "",
+				arg.Default,
 			)
 			params = append(params, *p)
 		}
@@ -2592,10 +2619,14 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 		ct = cxxtypes.IdByName(t.name())
 
 	case *xmlArray:
-		sz := str_to_uintptr(t.Size)
 		typ := gen_id_from_gccxml(g_ids[t.Type]).(cxxtypes.Type)
 		tn := typ.TypeName()
 		tsz := typ.TypeSize()
+		// number of elements
+		sz := uintptr(0)
+		if tsz > 0 {
+			sz = str_to_size(t.Size) / tsz
+		}
 		scope := getCxxtypesScope(t)
 		ct = cxxtypes.NewArrayType(sz, tn, tsz, scope)
 
@@ -2757,7 +2788,7 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 
 	case *xmlStruct:
 		scoped_name := genTypeName(t.id(), gtnCfg{})
-		sz := str_to_uintptr(t.Size)
+		sz := str_to_size(t.Size)
 		scope := getCxxtypesScope(t)
 		st := cxxtypes.NewStructType(scoped_name, sz, scope)
 		// un-mark from processing:
@@ -2771,7 +2802,7 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 
 	case *xmlClass:
 		scoped_name := genTypeName(t.id(), gtnCfg{})
-		sz := str_to_uintptr(t.Size)
+		sz := str_to_size(t.Size)
 		scope := getCxxtypesScope(t)
 		st := cxxtypes.NewClassType(scoped_name, sz, scope)
 		// un-mark from processing:
@@ -2800,6 +2831,9 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 		if str_to_bool(t.Virtual) {
 			spec |= cxxtypes.TS_Virtual
 		}
+		if str_to_bool(t.PureVirtual) {
+			spec |= cxxtypes.TS_Abstract
+		}
 		variadic := strings.Contains(scoped_name, "...")
 
 		scope := getCxxtypesScope(t)
@@ -2822,6 +2856,12 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 		scope := getCxxtypesScope(t)
 		ct = cxxtypes.NewNamespace(scoped_name, scope)
 
+	case *xmlNamespaceAlias:
+		scoped_name := genTypeName(t.id(), gtnCfg{})
+		scope := getCxxtypesScope(t)
+		ns := genTypeName(t.Namespace, gtnCfg{})
+		ct = cxxtypes.NewNamespaceAlias(scoped_name, ns, scope)
+
 	case *xmlOperatorMethod:
 		scoped_name := genTypeName(t.id(), gtnCfg{})
 		//fmt.Printf("+-(%s)[%s][%s]...\n", t.id(), t.name(), scoped_name)
@@ -2839,6 +2879,9 @@ func gen_id_from_gccxml(node i_id) cxxtypes.Id {
 		if str_to_bool(t.Virtual) {
 			spec |= cxxtypes.TS_Virtual
 		}
+		if str_to_bool(t.PureVirtual) {
+			spec |= cxxtypes.TS_Abstract
+		}
 		variadic := strings.Contains(scoped_name, "...")
 
 		scope := getCxxtypesScope(t)
@@ -276,3 +276,21 @@ func init() {
 	// test custom templated-class with user-provided template-defaults
 	g_stldeftable["MyFooCls"] = []string{"=", "std::less"}
 }
+
+func TestFieldOffset(t *testing.T) {
+	for _, table := range []struct {
+		offset   string
+		expected uintptr
+	}{
+		{"", 0},
+		{"0", 0},
+		{"32", 4},
+		{"64", 8},
+	} {
+		x := &xmlField{Offset: table.offset}
+		if o := x.offset(); o != table.expected {
+			t.Errorf("expected offset [%d] for [%s] bits, got [%d]",
+				table.expected, table.offset, o)
+		}
+	}
+}
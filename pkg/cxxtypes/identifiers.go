@@ -92,6 +92,11 @@ func NumId() int {
 	return len(g_ids)
 }
 
+// ResetIds removes all the currently defined identifiers
+func ResetIds() {
+	g_ids = make(map[string]Id)
+}
+
 // BaseId implements the Id interface
 type BaseId struct {
 	Name  string
@@ -116,6 +121,118 @@ func (id *BaseId) DeclScope() Id {
 	return get_scope_from_name(id.Scope)
 }
 
+// TemplateInstance describes the template a class or function identifier
+// is an instance of, if any.
+//  e.g. for NS::tmpl<int, std::vector<int> >:
+//       Template     = "NS::tmpl"
+//       TemplateArgs = ["int", "std::vector<int>"]
+type TemplateInstance struct {
+	Template     string   // the scoped name of the template (empty if none)
+	TemplateArgs []string // the template arguments of this instance
+}
+
+// new_template_instance returns the template description of the scoped
+// identifier name n
+func new_template_instance(n string) TemplateInstance {
+	tmpl, args := SplitTemplateName(n)
+	return TemplateInstance{
+		Template:     tmpl,
+		TemplateArgs: args,
+	}
+}
+
+// IsTemplateInstance returns whether this identifier is a template instance
+func (t *TemplateInstance) IsTemplateInstance() bool {
+	return t.Template != ""
+}
+
+// TemplateName returns the scoped name of the template this identifier is
+// an instance of, or the empty string.
+func (t *TemplateInstance) TemplateName() string {
+	return t.Template
+}
+
+// NumTemplateArg returns the number of template arguments of this instance
+func (t *TemplateInstance) NumTemplateArg() int {
+	return len(t.TemplateArgs)
+}
+
+// TemplateArg returns the i'th template argument of this instance
+// It panics if i is not in the range [0, NumTemplateArg())
+func (t *TemplateInstance) TemplateArg(i int) string {
+	if i < 0 || i >= len(t.TemplateArgs) {
+		panic("cxxtypes: TemplateArg index out of range")
+	}
+	return t.TemplateArgs[i]
+}
+
+// SplitTemplateName splits the name of a template instance into the name
+// of the template and its (top-level) template arguments.
+// It returns the empty string and nil if n does not name a template
+// instance.
+//  e.g. "NS::tmpl<int, std::map<int, int> >" -> "NS::tmpl", ["int", "std::map<int, int>"]
+//       "NS::tmpl<int>::inner"               -> "", nil
+//       "Foo::operator>>"                    -> "", nil
+func SplitTemplateName(n string) (string, []string) {
+	n = strings.TrimSpace(n)
+	if !strings.HasSuffix(n, ">") {
+		return "", nil
+	}
+	// look for the '<' opening the last template argument list
+	beg := -1
+	lvl := 0
+loop:
+	for i := len(n) - 1; i >= 0; i-- {
+		switch n[i] {
+		case '>', ')':
+			lvl += 1
+		case '<', '(':
+			lvl -= 1
+			if lvl == 0 {
+				beg = i
+				break loop
+			}
+		}
+	}
+	if beg <= 0 {
+		return "", nil
+	}
+	tmpl := strings.TrimSpace(n[:beg])
+	if c := tmpl[len(tmpl)-1]; !(c == '_' ||
+		('0' <= c && c <= '9') ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z')) {
+		// e.g. "operator< <int>"
+		return "", nil
+	}
+	if tmpl == "operator" || strings.HasSuffix(tmpl, "::operator") {
+		// e.g. "operator<=>"
+		return "", nil
+	}
+
+	args := []string{}
+	lvl = 0
+	cur := beg + 1
+	end := len(n) - 1
+	for i := cur; i < end; i++ {
+		switch n[i] {
+		case '<', '(':
+			lvl += 1
+		case '>', ')':
+			lvl -= 1
+		case ',':
+			if lvl == 0 {
+				args = append(args, strings.TrimSpace(n[cur:i]))
+				cur = i + 1
+			}
+		}
+	}
+	if arg := strings.TrimSpace(n[cur:end]); arg != "" || len(args) > 0 {
+		args = append(args, arg)
+	}
+	return tmpl, args
+}
+
 // Namespace represents a namespace identifier
 type Namespace struct {
 	BaseId  `cxxtypes:"namespace"`
@@ -151,6 +268,34 @@ func (t *Namespace) Member(i int) Id {
 	return IdByName(t.Members[i])
 }
 
+// NamespaceAlias represents a namespace alias identifier
+//  e.g. namespace fs = boost::filesystem;
+type NamespaceAlias struct {
+	BaseId    `cxxtypes:"namespace-alias"`
+	Namespace string // the scoped name of the aliased namespace
+}
+
+// NewNamespaceAlias creates a new namespace alias identifier
+func NewNamespaceAlias(name string, ns string, scope string) *NamespaceAlias {
+	id := &NamespaceAlias{
+		BaseId: BaseId{
+			Name:  name,
+			Kind:  IK_Nsp,
+			Scope: scope,
+		},
+		Namespace: ns,
+	}
+	add_id(id)
+	add_id_to_scope(name, scope)
+	return id
+}
+
+// Target returns the aliased namespace, or nil if it is not known
+func (id *NamespaceAlias) Target() *Namespace {
+	ns, _ := IdByName(id.Namespace).(*Namespace)
+	return ns
+}
+
 // Function represents a function identifier
 //  e.g. std::fabs
 type Function struct {
@@ -161,6 +306,7 @@ type Function struct {
 	Variadic bool            // whether this function is variadic
 	Params   []Parameter     // the parameters to this function
 	Ret      string          // return type of this function
+	TemplateInstance
 }
 
 // NewFunction returns a new function identifier
@@ -180,6 +326,7 @@ func NewFunction(name string, qual TypeQualifier, specifiers TypeSpecifier,
 		Params:   make([]Parameter, 0, len(params)),
 		Ret:      ret,
 	}
+	id.TemplateInstance = new_template_instance(name)
 	id.Params = append(id.Params, params...)
 	add_id(id)
 	add_id_to_scope(name, scope)
@@ -243,6 +390,16 @@ func (t *Function) IsCopyConstructor() bool {
 	return (t.Spec & TS_CopyCtor) != 0
 }
 
+// IsDeleted returns whether this function is defined as deleted (= delete)
+func (t *Function) IsDeleted() bool {
+	return (t.Spec & TS_Deleted) != 0
+}
+
+// IsPureVirtual returns whether this function is a pure virtual one (= 0)
+func (t *Function) IsPureVirtual() bool {
+	return (t.Spec & TS_Abstract) != 0
+}
+
 func (t *Function) IsOperator() bool {
 	return (t.Spec & TS_Operator) != 0
 }
@@ -286,12 +443,11 @@ func (t *Function) Param(i int) *Parameter {
 }
 
 // NumDefaultParam returns the number of parameters of a function's input which have a default value.
+// (ie: the number of trailing parameters with a default value)
 func (t *Function) NumDefaultParam() int {
 	n := 0
-	for i, _ := range t.Params {
-		if t.Params[i].DefVal {
-			n += 1
-		}
+	for i := len(t.Params) - 1; i >= 0 && t.Params[i].HasDefaultValue(); i-- {
+		n += 1
 	}
 	return n
 }
@@ -0,0 +1,125 @@
+package cxxtypes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitTemplateName(t *testing.T) {
+	for _, table := range []struct {
+		name string
+		tmpl string
+		args []string
+	}{
+		{"Foo", "", nil},
+		{"NS::tmpl<int>", "NS::tmpl", []string{"int"}},
+		{"std::map<int, std::pair<int, double> >", "std::map", []string{"int", "std::pair<int, double>"}},
+		{"std::vector<void (*)(int, int)>", "std::vector", []string{"void (*)(int, int)"}},
+		{"NS::tmpl<int>::inner", "", nil},
+		{"NS::tmpl<int>::get<double>", "NS::tmpl<int>::get", []string{"double"}},
+		{"NS::empty<>", "NS::empty", []string{}},
+		{"Foo::operator>", "", nil},
+		{"Foo::operator>>", "", nil},
+		{"Foo::operator->", "", nil},
+		{"Foo::operator<=>", "", nil},
+		{"operator< <int>", "", nil},
+	} {
+		tmpl, args := SplitTemplateName(table.name)
+		if tmpl != table.tmpl {
+			t.Errorf("expected template [%s], got [%s] for [%s]", table.tmpl, tmpl, table.name)
+		}
+		if strings.Join(args, "|") != strings.Join(table.args, "|") {
+			t.Errorf("expected %q, got %q for [%s]", table.args, args, table.name)
+		}
+	}
+}
+
+func TestTemplateInstance(t *testing.T) {
+	NewClassType("NS::tinst<int, double>", 8, "NS")
+	NewFunction("NS::tfct<float>", 0, 0, AS_Public, false, nil, "void", "NS")
+
+	buf := new(bytes.Buffer)
+	err := SaveIds(buf, nil)
+	if err != nil {
+		t.Fatalf("could not save ids: %v", err)
+	}
+	err = LoadIds("gob", buf)
+	if err != nil {
+		t.Fatalf("could not load ids: %v", err)
+	}
+
+	cls, ok := IdByName("NS::tinst<int, double>").(*ClassType)
+	if !ok {
+		t.Fatalf("could not retrieve class template instance")
+	}
+	if !cls.IsTemplateInstance() || cls.TemplateName() != "NS::tinst" {
+		t.Errorf("expected an instance of [NS::tinst], got [%s]", cls.TemplateName())
+	}
+	if n := cls.NumTemplateArg(); n != 2 {
+		t.Fatalf("expected 2 template arguments, got %d", n)
+	}
+	if arg := cls.TemplateArg(1); arg != "double" {
+		t.Errorf("expected [double], got [%s]", arg)
+	}
+
+	ovfct, ok := IdByName("NS::tfct<float>").(*OverloadFunctionSet)
+	if !ok {
+		t.Fatalf("could not retrieve function template instance")
+	}
+	fct := ovfct.Function(0)
+	if fct.TemplateName() != "NS::tfct" || fct.NumTemplateArg() != 1 || fct.TemplateArg(0) != "float" {
+		t.Errorf("expected an instance of [NS::tfct<float>], got [%s]%q",
+			fct.TemplateName(), fct.TemplateArgs)
+	}
+
+	foo := NewClassType("NS::TNotATemplate", 8, "NS")
+	if foo.IsTemplateInstance() {
+		t.Errorf("expected [%s] not to be a template instance", foo.IdScopedName())
+	}
+}
+
+func TestRefTypeKind(t *testing.T) {
+	NewClassType("NS::RefKind", 8, "NS")
+	for _, table := range []struct {
+		name string
+		kind TypeKind
+	}{
+		{"NS::RefKind&", TK_LValueRef},
+		{"NS::RefKind const&", TK_LValueRef},
+		{"NS::RefKind&&", TK_RValueRef},
+	} {
+		ref := NewRefType(table.name, "NS::RefKind", "NS")
+		if k := ref.TypeKind(); k != table.kind {
+			t.Errorf("expected kind %v, got %v for [%s]", table.kind, k, table.name)
+		}
+	}
+
+	fct := NewFunction("NS::deleted_fct", 0, TS_Deleted, AS_Public, false, nil, "void", "NS")
+	if !fct.IsDeleted() {
+		t.Errorf("expected [%s] to be deleted", fct.IdScopedName())
+	}
+
+	fct = NewFunction("NS::pure_fct", 0, TS_Method|TS_Virtual|TS_Abstract, AS_Public, false, nil, "void", "NS")
+	if !fct.IsPureVirtual() {
+		t.Errorf("expected [%s] to be pure virtual", fct.IdScopedName())
+	}
+}
+
+func TestDefaultParams(t *testing.T) {
+	for _, table := range []struct {
+		params []Parameter
+		ndef   int
+	}{
+		{[]Parameter{*NewParameter("a", "int", ""), *NewParameter("b", "int", "2")}, 1},
+		{[]Parameter{*NewParameter("a", "int", "1"), *NewParameter("b", "int", "2")}, 2},
+		// flagged w/o a default value (older registry files): required
+		{[]Parameter{{Name: "a", Type: "int", DefVal: true}}, 0},
+		{[]Parameter{*NewParameter("a", "int", "1"), {Name: "b", Type: "int", DefVal: true}}, 0},
+	} {
+		fct := NewFunction("NS::defaults_fct", 0, 0, AS_Public, false, table.params, "void", "NS")
+		if n := fct.NumDefaultParam(); n != table.ndef {
+			t.Errorf("%v: expected %d default parameters, got %d", table.params, table.ndef, n)
+		}
+	}
+}
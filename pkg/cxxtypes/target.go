@@ -0,0 +1,108 @@
+package cxxtypes
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"unsafe"
+)
+
+// Target describes the platform the C++ types registry was built for.
+// It is recorded in the registry metadata so bindings may be generated for
+// a platform other than the one running the generator.
+type Target struct {
+	GOOS        string  // target operating system (as in runtime.GOOS)
+	GOARCH      string  // target architecture (as in runtime.GOARCH)
+	PtrSize     uintptr // size of a pointer, in bytes
+	CharSigned  bool    // whether a plain C 'char' is signed
+	WCharSigned bool    // whether a C 'wchar_t' is signed
+}
+
+// Name returns the name of the target, as GOOS/GOARCH
+func (t Target) Name() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+func (t Target) String() string {
+	sign := func(signed bool) string {
+		if signed {
+			return "signed"
+		}
+		return "unsigned"
+	}
+	return fmt.Sprintf(
+		"Target{%s, ptr=%d, char=%s, wchar_t=%s}",
+		t.Name(), t.PtrSize, sign(t.CharSigned), sign(t.WCharSigned))
+}
+
+// g_targets is the table of known targets, indexed by GOOS/GOARCH
+var g_targets = map[string]Target{
+	"linux/386":     {"linux", "386", 4, true, true},
+	"linux/amd64":   {"linux", "amd64", 8, true, true},
+	"linux/arm":     {"linux", "arm", 4, false, false},
+	"linux/arm64":   {"linux", "arm64", 8, false, false},
+	"linux/ppc64":   {"linux", "ppc64", 8, false, true},
+	"linux/ppc64le": {"linux", "ppc64le", 8, false, true},
+	"linux/s390x":   {"linux", "s390x", 8, false, true},
+	"darwin/amd64":  {"darwin", "amd64", 8, true, true},
+	"darwin/arm64":  {"darwin", "arm64", 8, true, true},
+	"windows/386":   {"windows", "386", 4, true, false},
+	"windows/amd64": {"windows", "amd64", 8, true, false},
+	"freebsd/amd64": {"freebsd", "amd64", 8, true, true},
+}
+
+// Targets returns the names of the known targets.
+func Targets() []string {
+	names := make([]string, 0, len(g_targets))
+	for n, _ := range g_targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TargetByName returns the description of a known target (e.g. "linux/arm64")
+func TargetByName(name string) (Target, error) {
+	t, ok := g_targets[name]
+	if !ok {
+		return Target{}, fmt.Errorf("cxxtypes: unknown target %q", name)
+	}
+	return t, nil
+}
+
+// HostTarget returns the description of the platform running this program.
+func HostTarget() Target {
+	t, ok := g_targets[runtime.GOOS+"/"+runtime.GOARCH]
+	if ok {
+		return t
+	}
+	// best effort...
+	return Target{
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		PtrSize:     unsafe.Sizeof(uintptr(0)),
+		CharSigned:  true,
+		WCharSigned: runtime.GOOS != "windows",
+	}
+}
+
+// the target of the registry
+var g_target Target
+
+// CurrentTarget returns the description of the platform the registry is
+// built for.
+func CurrentTarget() Target {
+	return g_target
+}
+
+// SetTarget sets the platform the registry is built for.
+// It should be called before any identifier is loaded.
+func SetTarget(t Target) {
+	g_target = t
+}
+
+func init() {
+	g_target = HostTarget()
+}
+
+// EOF
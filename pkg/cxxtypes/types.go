@@ -5,7 +5,6 @@ package cxxtypes
 import (
 	"fmt"
 	"strings"
-	"unsafe"
 )
 
 // TypeKind represents the specific kind of type that a Type represents.
@@ -223,9 +222,6 @@ type Type interface {
 	//CanonicalType() Type
 }
 
-// size of a pointer for this platform
-var g_ptrsz uintptr
-
 // IsConstQualified returns whether this type is const-qualified.
 // This doesn't look through typedefs that may have added 'const' at 
 // different level.
@@ -545,7 +541,7 @@ func (t *PtrType) TypeName() string {
 }
 
 func (t *PtrType) TypeSize() uintptr {
-	return g_ptrsz
+	return g_target.PtrSize
 }
 
 func (t *PtrType) TypeKind() TypeKind {
@@ -614,10 +610,15 @@ func (t *RefType) TypeName() string {
 }
 
 func (t *RefType) TypeSize() uintptr {
-	return g_ptrsz
+	return g_target.PtrSize
 }
 
+// TypeKind returns TK_RValueRef for rvalue references (T&&) and
+// TK_LValueRef otherwise
 func (t *RefType) TypeKind() TypeKind {
+	if strings.HasSuffix(t.Name, "&&") {
+		return TK_RValueRef
+	}
 	return TK_LValueRef
 }
 
@@ -724,6 +725,7 @@ func NewStructType(n string, sz uintptr, scope string) *StructType {
 		Bases:   make([]Base, 0),
 		Members: make([]Member, 0),
 	}
+	t.TemplateInstance = new_template_instance(n)
 	// t.members = append(t.members, members...)
 	// set_scope(t.members, t, scope)
 	add_type(t)
@@ -735,6 +737,7 @@ type StructType struct {
 	BaseType `cxxtypes:"struct"`
 	Bases    []Base
 	Members  []Member
+	TemplateInstance
 }
 
 // NumMember returns a struct type's member count
@@ -912,6 +915,7 @@ func NewClassType(n string, sz uintptr, scope string) *ClassType {
 		Bases:   make([]Base, 0),
 		Members: make([]Member, 0),
 	}
+	t.TemplateInstance = new_template_instance(n)
 	add_type(t)
 	return t
 }
@@ -921,6 +925,7 @@ type ClassType struct {
 	BaseType `cxxtypes:"class"`
 	Bases    []Base
 	Members  []Member
+	TemplateInstance
 }
 
 // NumMember returns a class type's member count
@@ -1062,7 +1067,7 @@ type Member struct {
 	Type   string          // the type of this member
 	Kind   TypeKind        // the kind of this member
 	Access AccessSpecifier // the access specifier for this member
-	Offset uintptr         // the offset in the embedding scope
+	Offset uintptr         // the offset in the embedding scope, in bytes
 }
 
 func (t *Member) get_type() Type {
@@ -1082,6 +1087,12 @@ func (m *Member) IsFunctionMember() bool {
 		(m.Kind == TK_FunctionNoProto)
 }
 
+// IsTypeMember returns whether the member is a nested type (class, enum,
+// typedef, ...)
+func (m *Member) IsTypeMember() bool {
+	return (m.IdKind() == IK_Typ) && !m.IsFunctionMember()
+}
+
 func id_kind_from_tk(tk TypeKind) IdKind {
 	ik := IK_Typ
 	switch tk {
@@ -1189,6 +1200,16 @@ func (t *FunctionType) IsCopyConstructor() bool {
 	return (t.BaseType.Spec & TS_CopyCtor) != 0
 }
 
+// IsDeleted returns whether this function is defined as deleted (= delete)
+func (t *FunctionType) IsDeleted() bool {
+	return (t.BaseType.Spec & TS_Deleted) != 0
+}
+
+// IsPureVirtual returns whether this function is a pure virtual one (= 0)
+func (t *FunctionType) IsPureVirtual() bool {
+	return (t.BaseType.Spec & TS_Abstract) != 0
+}
+
 func (t *FunctionType) IsOperator() bool {
 	return (t.BaseType.Spec & TS_Operator) != 0
 }
@@ -1232,12 +1253,11 @@ func (t *FunctionType) Param(i int) *Parameter {
 }
 
 // NumDefaultParam returns a function type's input with default value parameter count.
+// (ie: the number of trailing parameters with a default value)
 func (t *FunctionType) NumDefaultParam() int {
 	n := 0
-	for i, _ := range t.Params {
-		if t.Params[i].DefVal {
-			n += 1
-		}
+	for i := len(t.Params) - 1; i >= 0 && t.Params[i].HasDefaultValue(); i-- {
+		n += 1
 	}
 	return n
 }
@@ -1249,24 +1269,35 @@ func (t *FunctionType) ReturnType() Type {
 }
 
 // NewParameter creates a new parameter.
-func NewParameter(n string, tn string, defval bool) *Parameter {
+// defval is the expression of the default value of that parameter, if any.
+func NewParameter(n string, tn string, defval string) *Parameter {
 	return &Parameter{
-		Name:   n,
-		Type:   tn,
-		DefVal: defval,
+		Name:    n,
+		Type:    tn,
+		DefVal:  defval != "",
+		Default: defval,
 	}
 }
 
 // Parameter represents a parameter of a function's signature
 type Parameter struct {
-	Name   string // name of the parameter
-	Type   string // type of this parameter
-	DefVal bool   // whether this parameter has a default value
+	Name    string // name of the parameter
+	Type    string // type of this parameter
+	DefVal  bool   // whether this parameter has a default value
+	Default string // expression of the default value (if any)
 }
 
-// HasDefaultValue returns whether this parameter has a default value
+// HasDefaultValue returns whether this parameter has a default value.
+// A parameter flagged with a default value whose expression is unknown
+// (e.g. loaded from an older registry file) is a required parameter.
 func (p *Parameter) HasDefaultValue() bool {
-	return p.DefVal
+	return p.DefVal && p.Default != ""
+}
+
+// DefaultValue returns the C++ expression of the default value of this
+// parameter, or "" if it has none.
+func (p *Parameter) DefaultValue() string {
+	return p.Default
 }
 
 // NewVar creates a new global variable
@@ -1310,6 +1341,7 @@ const (
 	TS_Abstract
 	TS_Transient
 	TS_Artificial
+	TS_Deleted
 )
 
 // AccessSpecifier represents the C++ access control level to a base class or a class' member
@@ -1401,8 +1433,4 @@ var _ Id = (*UnionType)(nil)
 var _ Id = (*ClassType)(nil)
 var _ Id = (*FunctionType)(nil)
 
-func init() {
-	g_ptrsz = unsafe.Sizeof(uintptr(0))
-}
-
 // EOF
@@ -0,0 +1,282 @@
+package cxxgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// cxxgo_callback describes a std::function, presented to Go as a func.
+// The Go func is registered on the Go side (see _gocxx_gofunc_new) and
+// called back from C++ with pointers to its arguments and to its result.
+// Only fundamental types and std::string are exchanged.
+type cxxgo_callback struct {
+	id    *cxxtypes.ClassType
+	ret   string   // C++ result type ("" for void)
+	rkind string   // how the result is exchanged (see callback_kind)
+	args  []string // C++ parameter types
+	kinds []string // how the parameters are exchanged (see callback_kind)
+}
+
+// get_cxxgo_callback returns the description of the std::function id
+// (modulo typedefs and cv-qualifiers), or nil if id is not a std::function
+// which can be mapped to a Go func.
+func get_cxxgo_callback(id cxxtypes.Id) *cxxgo_callback {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	cls, ok := resolve_typedef(t).(*cxxtypes.ClassType)
+	if !ok {
+		return nil
+	}
+	n := cls.IdScopedName()
+	if !strings.HasPrefix(n, "std::function<") {
+		return nil
+	}
+	targs := template_args(n)
+	if len(targs) != 1 {
+		return nil
+	}
+	// e.g. "double (int, std::string const&)"
+	sig := targs[0]
+	beg := strings.Index(sig, "(")
+	end := strings.LastIndex(sig, ")")
+	if beg < 0 || end < beg {
+		return nil
+	}
+	cb := &cxxgo_callback{
+		id:  cls,
+		ret: strings.TrimSpace(sig[:beg]),
+	}
+	if cb.ret == "void" {
+		cb.ret = ""
+	} else {
+		cb.rkind = callback_kind(cb.ret)
+		if cb.rkind == "" {
+			return nil
+		}
+	}
+	args := strings.TrimSpace(sig[beg+1 : end])
+	if args == "" || args == "void" {
+		return cb
+	}
+	for _, arg := range split_args(args) {
+		k := callback_kind(arg)
+		if k == "" {
+			return nil
+		}
+		cb.args = append(cb.args, arg)
+		cb.kinds = append(cb.kinds, k)
+	}
+	return cb
+}
+
+// callback_kind returns how values of the C++ type n are exchanged with
+// a Go func:
+//   - "value": fundamental types, copied as is
+//   - "string": std::string (by value or const reference), converted
+//     from/to a Go string
+//
+// or "" if such values are not supported.
+func callback_kind(n string) string {
+	id := cxxtypes.IdByName(n)
+	if id == nil {
+		return ""
+	}
+	if s := get_cxxgo_string(id); s != nil {
+		if s.kind == "std::string" && !s.ptr && (!s.ref || s.cst) {
+			return "string"
+		}
+		return ""
+	}
+	if container_elt_kind(id) == "value" {
+		return "value"
+	}
+	return ""
+}
+
+// strip_callback returns the std::function a (reference to a) std::function
+// type refers to.
+func strip_callback(id cxxtypes.Id) *cxxgo_callback {
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.TypedefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.RefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.ClassType:
+			return get_cxxgo_callback(iid)
+		}
+		return nil
+	}
+	panic("unreachable")
+}
+
+// kind_goname returns the Go type of a value of C++ type n, exchanged as k
+func kind_goname(n, k string) string {
+	if k == "string" {
+		return "string"
+	}
+	return gen_go_name_from_id(cxxtypes.IdByName(n))
+}
+
+// goname returns the Go func type of the std::function
+// (e.g. func(int32, string) float64)
+func (cb *cxxgo_callback) goname() string {
+	args := make([]string, 0, len(cb.args))
+	for i, arg := range cb.args {
+		args = append(args, kind_goname(arg, cb.kinds[i]))
+	}
+	n := "func(" + strings.Join(args, ", ") + ")"
+	if cb.ret != "" {
+		n += " " + kind_goname(cb.ret, cb.rkind)
+	}
+	return n
+}
+
+// cname returns the prefix of the C helpers for the std::function
+func (cb *cxxgo_callback) cname(pkg string) string {
+	return fmt.Sprintf("_gocxx_cb_%s_%s", pkg, get_iid_str(cb.id))
+}
+
+// go_helper returns the name of the Go function registering a Go func to
+// be called back from C++.
+func (cb *cxxgo_callback) go_helper() string {
+	return fmt.Sprintf("_gocxx_cb_%s_from_go", get_iid_str(cb.id))
+}
+
+// wrapCallback generates the C++ helper setting a std::function to call
+// back a Go func, together with the Go function registering the Go func.
+func (p *plugin) wrapCallback(cid *cxxgo_id, cb *cxxgo_callback) error {
+	var err error
+	fmt.Printf(":: wrapping callback [%s]...\n", cb.id.IdScopedName())
+
+	pkg := p.gen.Fd.Package
+	bufs := new_bufmap(
+		"cxx",
+		"cgo_head",
+		"go_impl",
+	)
+
+	fctn := cb.id.IdScopedName()
+	cn := cb.cname(pkg)
+
+	// C++ -> Go: the lambda packs its arguments and calls the Go func
+	params := make([]string, 0, len(cb.args))
+	c_args := make([]string, 0, len(cb.args))
+	body := new(bytes.Buffer)
+	for i, arg := range cb.args {
+		params = append(params, fmt.Sprintf("%s a%d", arg, i))
+		if cb.kinds[i] == "string" {
+			fmter(body, "    _gocxx_strview c_a%d = { a%d.data(), a%d.size() };\n", i, i, i)
+			c_args = append(c_args, fmt.Sprintf("(void*)&c_a%d", i))
+		} else {
+			c_args = append(c_args, fmt.Sprintf("(void*)&a%d", i))
+		}
+	}
+	if len(c_args) > 0 {
+		fmter(body, "    void *c_args[] = { %s };\n", strings.Join(c_args, ", "))
+	} else {
+		fmter(body, "    void **c_args = NULL;\n")
+	}
+	ret := ""
+	switch cb.rkind {
+	case "":
+		fmter(body, "    ref->call(c_args, NULL);\n")
+	case "string":
+		ret = " -> " + cb.ret
+		fmter(body,
+			"    _gocxx_strview c_ret = { NULL, 0 };\n    ref->call(c_args, (void*)&c_ret);\n    %s cxx_ret(c_ret.p ? c_ret.p : \"\", c_ret.n);\n    free((void*)c_ret.p);\n    return cxx_ret;\n",
+			cb.ret,
+		)
+	default:
+		ret = " -> " + cb.ret
+		fmter(body,
+			"    %s cxx_ret = %s();\n    ref->call(c_args, (void*)&cxx_ret);\n    return cxx_ret;\n",
+			cb.ret, cb.ret,
+		)
+	}
+
+	fmter(bufs["cgo_head"],
+		"\n/* helpers for [%s] */\nvoid %s_from_go(void *c_self, void *c_fct);\n",
+		fctn, cn,
+	)
+	fmter(bufs["cxx"],
+		"\n// helpers for [%s]\n// sets c_self to call back the Go func c_fct (or to nothing)\nvoid %s_from_go(void *c_self, void *c_fct)\n{\n  %s *cxx_self = (%s*)c_self;\n  _gocxx_gofunc *fct = (_gocxx_gofunc*)c_fct;\n  if (!fct->call) {\n    *cxx_self = nullptr;\n    return;\n  }\n  std::shared_ptr<_gocxx_gofunc_ref> ref(new _gocxx_gofunc_ref(*fct));\n  *cxx_self = [ref](%s)%s {\n%s  };\n}\n",
+		fctn, cn, fctn, fctn,
+		strings.Join(params, ", "), ret, body.String(),
+	)
+
+	// Go side: unpacks the arguments, calls f and stores its result
+	gotype := cb.goname()
+	fmter(bufs["go_impl"],
+		"\n// %s registers the Go func f to be called back from a C++ [%s]\n// (which does nothing if f is nil)\nfunc %s(f %s) C._gocxx_gofunc {\n\tif f == nil {\n\t\treturn C._gocxx_gofunc{}\n\t}\n\treturn _gocxx_gofunc_new(func(args, ret unsafe.Pointer) {\n",
+		cb.go_helper(), fctn, cb.go_helper(), gotype,
+	)
+	go_args := make([]string, 0, len(cb.args))
+	if len(cb.args) > 0 {
+		fmter(bufs["go_impl"],
+			"\t\tc_args := unsafe.Slice((*unsafe.Pointer)(args), %d)\n",
+			len(cb.args),
+		)
+	}
+	for i, arg := range cb.args {
+		if cb.kinds[i] == "string" {
+			fmter(bufs["go_impl"],
+				"\t\tc_arg_%d := (*C._gocxx_strview)(c_args[%d])\n\t\targ_%d := C.GoStringN(c_arg_%d.p, C.int(c_arg_%d.n))\n",
+				i, i, i, i, i,
+			)
+		} else {
+			fmter(bufs["go_impl"],
+				"\t\targ_%d := *(*%s)(c_args[%d])\n",
+				i, kind_goname(arg, cb.kinds[i]), i,
+			)
+		}
+		go_args = append(go_args, fmt.Sprintf("arg_%d", i))
+	}
+	switch cb.rkind {
+	case "":
+		fmter(bufs["go_impl"], "\t\tf(%s)\n", strings.Join(go_args, ", "))
+	case "string":
+		// the C copy is released by C++
+		fmter(bufs["go_impl"],
+			"\t\t*(*C._gocxx_strview)(ret) = _gocxx_strview_from_go(f(%s))\n",
+			strings.Join(go_args, ", "),
+		)
+	default:
+		fmter(bufs["go_impl"],
+			"\t\t*(*%s)(ret) = f(%s)\n",
+			kind_goname(cb.ret, cb.rkind), strings.Join(go_args, ", "),
+		)
+	}
+	fmter(bufs["go_impl"], "\t})\n}\n")
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping callback [%s]...[ok]\n", cb.id.IdScopedName())
+	return err
+}
+
+// EOF
@@ -0,0 +1,318 @@
+package cxxgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// compound values (std::pair, std::tuple, std::optional and std::variant of
+// fundamental types and strings) are converted from and to Go values, like
+// the other containers:
+//   - pairs and tuples are Go structs (fields First and Second, F0..Fn),
+//   - optional values are Go pointers (nil for a missing value), returned
+//     as a (value, ok) pair,
+//   - variants are Go interfaces, implemented by one Go type per
+//     alternative.
+
+// compound_includes returns the include directives of the C++17 headers of
+// the std::optional and std::variant of ids: they are only included when such
+// a value is wrapped, so the other wrappers still build with older standards.
+func compound_includes(ids []string) string {
+	used := map[string]bool{}
+	for _, n := range ids {
+		if c := get_cxxgo_container(cxxtypes.IdByName(n)); c != nil {
+			used[c.class] = true
+		}
+	}
+	o := ""
+	for _, hdr := range []string{"optional", "variant"} {
+		if used[hdr] {
+			o += "#include <" + hdr + ">\n"
+		}
+	}
+	return o
+}
+
+// field_goname returns the name of the Go struct field holding the i-th
+// element of a pair or a tuple
+func (c *cxxgo_container) field_goname(i int) string {
+	if c.class == "pair" {
+		return []string{"First", "Second"}[i]
+	}
+	return fmt.Sprintf("F%d", i)
+}
+
+// alt_goname returns the name of the Go type holding the i-th alternative
+// of a variant (e.g. VariantInt32String_String)
+func (c *cxxgo_container) alt_goname(i int) string {
+	words := strings.FieldsFunc(c.elt_goname(i), is_not_alnum)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return c.goname() + "_" + strings.Join(words, "")
+}
+
+// go_field_out emits the Go code receiving the element of type i from C
+// into the Go variable dst, and returns the pointer to pass to the C
+// function.
+// post holds the code to run once the element has been received.
+func (c *cxxgo_container) go_field_out(buf, post *bytes.Buffer, i int, dst string) string {
+	if c.ekind[i] == "string" {
+		fmter(buf, "\tvar c_f%d C._gocxx_strview\n", i)
+		fmter(post, "\t%s = C.GoStringN(c_f%d.p, C.int(c_f%d.n))\n", dst, i, i)
+		return fmt.Sprintf("unsafe.Pointer(&c_f%d)", i)
+	}
+	return fmt.Sprintf("unsafe.Pointer(&%s)", dst)
+}
+
+// go_field_in emits the Go code handing the Go variable src, holding an
+// element of type i, over to C and returns the pointer to pass to the C
+// function.
+func (c *cxxgo_container) go_field_in(buf *bytes.Buffer, i int, src string) string {
+	if c.ekind[i] == "string" {
+		fmter(buf,
+			"\tc_f%d := _gocxx_strview_from_go(%s)\n\tdefer C.free(unsafe.Pointer(c_f%d.p))\n",
+			i, src, i,
+		)
+		return fmt.Sprintf("unsafe.Pointer(&c_f%d)", i)
+	}
+	return fmt.Sprintf("unsafe.Pointer(&%s)", src)
+}
+
+// wrapCompound generates the C helpers of a compound value, together with
+// its Go type and conversion functions.
+func (p *plugin) wrapCompound(cid *cxxgo_id, c *cxxgo_container) error {
+	var err error
+	fmt.Printf(":: wrapping compound [%s]...\n", c.id.IdScopedName())
+
+	pkg := p.gen.Fd.Package
+	bufs := new_bufmap(
+		"cxx",
+		"cgo_head",
+		"go_impl",
+	)
+
+	cnt := c.id.IdScopedName()
+	cn := c.cname(pkg)
+
+	// C helpers: creation and destruction
+	fmter(bufs["cgo_head"],
+		"\n/* helpers for [%s] */\nvoid* %s_new();\nvoid %s_delete(void *c_self);\n",
+		cnt, cn, cn,
+	)
+	fmter(bufs["cxx"],
+		"\n// helpers for [%s]\nvoid* %s_new()\n{\n  return new %s;\n}\n",
+		cnt, cn, cnt,
+	)
+	fmter(bufs["cxx"],
+		"\nvoid %s_delete(void *c_self)\n{\n  delete (%s*)c_self;\n}\n",
+		cn, cnt,
+	)
+
+	// get: from the compound value into C buffers
+	// set: from C buffers into the compound value
+	switch c.class {
+	case "pair", "tuple":
+		args := make([]string, 0, len(c.elts))
+		gets := make([]string, 0, len(c.elts))
+		sets := make([]string, 0, len(c.elts))
+		for i, _ := range c.elts {
+			f := fmt.Sprintf("c_f%d", i)
+			v := fmt.Sprintf("std::get<%d>(*cxx_self)", i)
+			args = append(args, "void *"+f)
+			gets = append(gets, c.cxx_elt_out(i, f, "0", v))
+			sets = append(sets, fmt.Sprintf("%s = %s;", v, c.cxx_elt_in(i, f, "0")))
+		}
+		fmter(bufs["cgo_head"],
+			"void %s_get(void *c_self, %s);\nvoid %s_set(void *c_self, %s);\n",
+			cn, strings.Join(args, ", "), cn, strings.Join(args, ", "),
+		)
+		fmter(bufs["cxx"],
+			"\nvoid %s_get(void *c_self, %s)\n{\n  %s *cxx_self = (%s*)c_self;\n  %s\n}\n",
+			cn, strings.Join(args, ", "), cnt, cnt, strings.Join(gets, "\n  "),
+		)
+		fmter(bufs["cxx"],
+			"\nvoid %s_set(void *c_self, %s)\n{\n  %s *cxx_self = (%s*)c_self;\n  %s\n}\n",
+			cn, strings.Join(args, ", "), cnt, cnt, strings.Join(sets, "\n  "),
+		)
+
+	case "optional":
+		fmter(bufs["cgo_head"],
+			"int %s_get(void *c_self, void *c_val);\nvoid %s_set(void *c_self, void *c_val);\n",
+			cn, cn,
+		)
+		fmter(bufs["cxx"],
+			"\n// returns whether the optional value is set, and stores it into c_val\nint %s_get(void *c_self, void *c_val)\n{\n  %s *cxx_self = (%s*)c_self;\n  if (!*cxx_self) {\n    return 0;\n  }\n  %s\n  return 1;\n}\n",
+			cn, cnt, cnt, c.cxx_elt_out(0, "c_val", "0", "(**cxx_self)"),
+		)
+		fmter(bufs["cxx"],
+			"\nvoid %s_set(void *c_self, void *c_val)\n{\n  *(%s*)c_self = %s;\n}\n",
+			cn, cnt, c.cxx_elt_in(0, "c_val", "0"),
+		)
+
+	case "variant":
+		gets := make([]string, 0, len(c.elts))
+		sets := make([]string, 0, len(c.elts))
+		for i, _ := range c.elts {
+			gets = append(gets, fmt.Sprintf("case %d: %s break;",
+				i, c.cxx_elt_out(i, "c_val", "0", fmt.Sprintf("std::get<%d>(*cxx_self)", i))))
+			sets = append(sets, fmt.Sprintf("case %d: cxx_self->emplace<%d>(%s); break;",
+				i, i, c.cxx_elt_in(i, "c_val", "0")))
+		}
+		fmter(bufs["cgo_head"],
+			"int %s_index(void *c_self);\nvoid %s_get(void *c_self, void *c_val);\nvoid %s_set(void *c_self, int i, void *c_val);\n",
+			cn, cn, cn,
+		)
+		fmter(bufs["cxx"],
+			"\n// returns the index of the alternative held by the variant, or -1\nint %s_index(void *c_self)\n{\n  return (int)((%s*)c_self)->index();\n}\n",
+			cn, cnt,
+		)
+		fmter(bufs["cxx"],
+			"\nvoid %s_get(void *c_self, void *c_val)\n{\n  %s *cxx_self = (%s*)c_self;\n  switch (cxx_self->index()) {\n    %s\n  }\n}\n",
+			cn, cnt, cnt, strings.Join(gets, "\n    "),
+		)
+		fmter(bufs["cxx"],
+			"\nvoid %s_set(void *c_self, int i, void *c_val)\n{\n  %s *cxx_self = (%s*)c_self;\n  switch (i) {\n    %s\n  }\n}\n",
+			cn, cnt, cnt, strings.Join(sets, "\n    "),
+		)
+	}
+
+	p.wrapCompoundConversions(c, bufs["go_impl"])
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping compound [%s]...[ok]\n", c.id.IdScopedName())
+	return err
+}
+
+// wrapCompoundConversions generates the Go type of a compound value and
+// the Go functions converting it from and to C++.
+func (p *plugin) wrapCompoundConversions(c *cxxgo_container, buf *bytes.Buffer) {
+	cn := "C." + c.cname(p.gen.Fd.Package)
+	cnt := c.id.IdScopedName()
+	gotype := c.goname()
+	to_go := c.go_helper("to_go")
+	from_go := c.go_helper("from_go")
+
+	switch c.class {
+	case "pair", "tuple":
+		fmter(buf, "\n// %s holds the values of a C++ [%s]\ntype %s struct {\n", gotype, cnt, gotype)
+		for i, _ := range c.elts {
+			fmter(buf, "\t%s %s\n", c.field_goname(i), c.elt_goname(i))
+		}
+		fmter(buf, "}\n")
+
+		// C++ -> Go
+		fmter(buf,
+			"\n// %s converts a C++ [%s] into a Go %s\nfunc %s(c unsafe.Pointer) %s {\n\tvar o %s\n",
+			to_go, cnt, gotype, to_go, gotype, gotype,
+		)
+		post := new(bytes.Buffer)
+		ptrs := make([]string, 0, len(c.elts))
+		for i, _ := range c.elts {
+			ptrs = append(ptrs, c.go_field_out(buf, post, i, "o."+c.field_goname(i)))
+		}
+		fmter(buf, "\t%s_get(c, %s)\n", cn, strings.Join(ptrs, ", "))
+		buf.Write(post.Bytes())
+		fmter(buf, "\treturn o\n}\n")
+
+		// Go -> C++
+		fmter(buf,
+			"\n// %s converts a Go %s into a new C++ [%s]\nfunc %s(o %s) unsafe.Pointer {\n\tc := %s_new()\n",
+			from_go, gotype, cnt, from_go, gotype, cn,
+		)
+		ptrs = ptrs[:0]
+		for i, _ := range c.elts {
+			ptrs = append(ptrs, c.go_field_in(buf, i, "o."+c.field_goname(i)))
+		}
+		fmter(buf, "\t%s_set(c, %s)\n\treturn c\n}\n", cn, strings.Join(ptrs, ", "))
+
+	case "optional":
+		elt := c.elt_goname(0)
+
+		// C++ -> Go
+		fmter(buf,
+			"\n// %s converts a C++ [%s] into a Go value, and whether it is set\nfunc %s(c unsafe.Pointer) (%s, bool) {\n\tvar o %s\n",
+			to_go, cnt, to_go, elt, elt,
+		)
+		post := new(bytes.Buffer)
+		ptr := c.go_field_out(buf, post, 0, "o")
+		fmter(buf, "\tok := %s_get(c, %s) != 0\n", cn, ptr)
+		buf.Write(post.Bytes())
+		fmter(buf, "\treturn o, ok\n}\n")
+
+		// Go -> C++
+		fmter(buf,
+			"\n// %s converts a Go %s into a new C++ [%s], empty if o is nil\nfunc %s(o %s) unsafe.Pointer {\n\tc := %s_new()\n\tif o == nil {\n\t\treturn c\n\t}\n\tv := *o\n",
+			from_go, gotype, cnt, from_go, gotype, cn,
+		)
+		ptr = c.go_field_in(buf, 0, "v")
+		fmter(buf, "\t%s_set(c, %s)\n\treturn c\n}\n", cn, ptr)
+
+	case "variant":
+		alts := make([]string, 0, len(c.elts))
+		for i, _ := range c.elts {
+			alts = append(alts, c.alt_goname(i))
+		}
+		fmter(buf,
+			"\n// %s holds one of the alternatives of a C++ [%s]:\n//  %s\ntype %s interface {\n\tGocxxIs%s()\n}\n",
+			gotype, cnt, strings.Join(alts, "\n//  "), gotype, gotype,
+		)
+		for i, alt := range alts {
+			fmter(buf,
+				"\n// %s holds the alternative #%d of a %s\ntype %s %s\n\nfunc (%s) GocxxIs%s() {\n}\n",
+				alt, i, gotype, alt, c.elt_goname(i), alt, gotype,
+			)
+		}
+
+		// C++ -> Go
+		fmter(buf,
+			"\n// %s converts a C++ [%s] into a Go %s\n// (nil if the variant is valueless)\nfunc %s(c unsafe.Pointer) %s {\n\tswitch %s_index(c) {\n",
+			to_go, cnt, gotype, to_go, gotype, cn,
+		)
+		for i, alt := range alts {
+			bbuf := new(bytes.Buffer)
+			post := new(bytes.Buffer)
+			fmter(bbuf, "\tvar o %s\n", c.elt_goname(i))
+			ptr := c.go_field_out(bbuf, post, i, "o")
+			fmter(bbuf, "\t%s_get(c, %s)\n", cn, ptr)
+			bbuf.Write(post.Bytes())
+			fmter(bbuf, "\treturn %s(o)\n", alt)
+			fmter(buf, "\tcase %d:\n%s", i, strings.Replace(bbuf.String(), "\t", "\t\t", -1))
+		}
+		fmter(buf, "\t}\n\treturn nil\n}\n")
+
+		// Go -> C++
+		fmter(buf,
+			"\n// %s converts a Go %s into a new C++ [%s]\n// (holding the default value of its first alternative if o is nil)\nfunc %s(o %s) unsafe.Pointer {\n\tc := %s_new()\n\tswitch o := o.(type) {\n",
+			from_go, gotype, cnt, from_go, gotype, cn,
+		)
+		for i, alt := range alts {
+			bbuf := new(bytes.Buffer)
+			fmter(bbuf, "\tv := %s(o)\n", c.elt_goname(i))
+			ptr := c.go_field_in(bbuf, i, "v")
+			fmter(bbuf, "\t%s_set(c, %d, %s)\n", cn, i, ptr)
+			fmter(buf, "\tcase %s:\n%s", alt, strings.Replace(bbuf.String(), "\t", "\t\t", -1))
+		}
+		fmter(buf, "\t}\n\treturn c\n}\n")
+	}
+}
+
+// EOF
@@ -0,0 +1,786 @@
+package cxxgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// cxxgo_container describes a C++ STL container and how it is presented
+// to Go: containers of fundamental types and strings are converted from
+// and to Go slices and maps, containers of classes are wrapped into a typed
+// container handle.
+type cxxgo_container struct {
+	id    *cxxtypes.ClassType
+	kind  string        // e.g. "VECTOR", "HASHMAP" (see get_container_id)
+	class string        // "vector", "list", "set" or "map"
+	elts  []cxxtypes.Id // element type (key and value types for maps)
+	ekind []string      // how elements are handled (see container_elt_kind)
+	cxx   []string      // C++ names of the element types
+	cvt   bool          // whether the container is converted to a Go value
+}
+
+// get_cxxgo_container returns the description of the STL container id
+// (modulo typedefs and cv-qualifiers), or nil if id is not a container
+// which can be mapped to Go.
+func get_cxxgo_container(id cxxtypes.Id) *cxxgo_container {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	cls, ok := resolve_typedef(t).(*cxxtypes.ClassType)
+	if !ok {
+		return nil
+	}
+	kind, class := get_container_id(cls)
+	targs := template_args(cls.IdScopedName())
+	nelts := 1
+	switch kind {
+	case "VECTOR", "DEQUE", "LIST", "ARRAY",
+		"SET", "MULTISET", "HASHSET", "HASHMULTISET",
+		"OPTIONAL":
+		// ok
+	case "MAP", "HASHMAP", "PAIR":
+		nelts = 2
+	case "TUPLE", "VARIANT":
+		nelts = len(targs)
+	default:
+		return nil
+	}
+	if nelts == 0 || len(targs) < nelts {
+		return nil
+	}
+	c := &cxxgo_container{
+		id:    cls,
+		kind:  kind,
+		class: class,
+		elts:  make([]cxxtypes.Id, 0, nelts),
+		ekind: make([]string, 0, nelts),
+		cxx:   targs[:nelts],
+		cvt:   true,
+	}
+	for _, n := range c.cxx {
+		eid := cxxtypes.IdByName(n)
+		if eid == nil {
+			return nil
+		}
+		ek := container_elt_kind(eid)
+		if ek == "" {
+			return nil
+		}
+		c.elts = append(c.elts, eid)
+		c.ekind = append(c.ekind, ek)
+		if ek == "handle" {
+			c.cvt = false
+		}
+	}
+	if c.class == "map" && c.ekind[0] == "handle" {
+		// keys have to be comparable Go values
+		return nil
+	}
+	if c.class == "array" && !c.cvt {
+		// FIXME: fixed-size typed container handles
+		return nil
+	}
+	if c.is_compound() && !c.cvt {
+		// FIXME: compound values of classes
+		return nil
+	}
+	if c.class == "variant" {
+		// each alternative is told apart by its Go type
+		seen := make(map[string]bool, nelts)
+		for i, _ := range c.elts {
+			n := c.elt_goname(i)
+			if seen[n] {
+				return nil
+			}
+			seen[n] = true
+		}
+	}
+	return c
+}
+
+// is_compound returns whether the container is a fixed set of values
+// (std::pair, std::tuple, std::optional or std::variant) rather than a
+// sequence or an associative container. (see compound.go)
+func (c *cxxgo_container) is_compound() bool {
+	switch c.class {
+	case "pair", "tuple", "optional", "variant":
+		return true
+	}
+	return false
+}
+
+// is_contiguous returns whether the elements of the container are
+// fundamental values stored contiguously, and thus may be viewed from Go
+// w/o any copy.
+func (c *cxxgo_container) is_contiguous() bool {
+	if c.ekind[0] != "value" {
+		return false
+	}
+	switch c.kind {
+	case "VECTOR":
+		// std::vector<bool> is a bitset
+		return resolve_typedef(c.elts[0].(cxxtypes.Type)).TypeKind() != cxxtypes.TK_Bool
+	case "ARRAY":
+		return true
+	}
+	return false
+}
+
+// container_elt_kind returns how the elements of type id are stored into
+// a container:
+//   - "value": fundamental types, copied as is
+//   - "string": std::string, converted from/to a Go string
+//   - "handle": classes, accessed through their Go handle
+//
+// or "" if such elements are not supported.
+func container_elt_kind(id cxxtypes.Id) string {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return ""
+	}
+	switch tt := resolve_typedef(t).(type) {
+	case *cxxtypes.FundamentalType:
+		if tt.TypeKind() == cxxtypes.TK_Void || gen_go_fundamental_name(tt) == "" {
+			return ""
+		}
+		return "value"
+	case *cxxtypes.ClassType:
+		if is_std_string(tt.IdScopedName()) {
+			return "string"
+		}
+		if c := get_cxxgo_container(tt); c != nil && c.cvt {
+			// FIXME: nested conversions
+			return ""
+		}
+		return "handle"
+	case *cxxtypes.StructType:
+		return "handle"
+	}
+	return ""
+}
+
+// is_std_string returns whether n names the std::string class
+func is_std_string(n string) bool {
+	if n == "std::string" {
+		return true
+	}
+	const pfx = "std::basic_string<char"
+	return strings.HasPrefix(n, pfx) &&
+		len(n) > len(pfx) && strings.ContainsRune(",> ", rune(n[len(pfx)]))
+}
+
+// template_args returns the (top-level) template arguments of the
+// template instance n.
+// e.g. "std::map<int, std::vector<int> >" -> ["int", "std::vector<int>"]
+func template_args(n string) []string {
+	beg := strings.Index(n, "<")
+	end := strings.LastIndex(n, ">")
+	if beg < 0 || end < beg {
+		return nil
+	}
+	return split_args(n[beg+1 : end])
+}
+
+// split_args splits the comma-separated list of types s, ignoring the
+// commas of nested template arguments and parameter lists.
+// e.g. "int, std::map<int, int>" -> ["int", "std::map<int, int>"]
+func split_args(s string) []string {
+	args := []string{}
+	lvl := 0
+	cur := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<', '(':
+			lvl += 1
+		case '>', ')':
+			lvl -= 1
+		case ',':
+			if lvl == 0 {
+				args = append(args, strings.TrimSpace(s[cur:i]))
+				cur = i + 1
+			}
+		}
+	}
+	args = append(args, strings.TrimSpace(s[cur:]))
+	return args
+}
+
+// strip_container returns the container a (reference or pointer to a)
+// container type refers to.
+// indirect is true for pointers and for references to non-const
+// containers: such arguments are Go pointers, the content of which is
+// updated after the call.
+// cst is true if the container can not be modified through id.
+func strip_container(id cxxtypes.Id) (c *cxxgo_container, indirect bool, cst bool) {
+	ptr := false
+	ref := false
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			cst = cst || (iid.Qualifiers()&cxxtypes.TQ_Const) != 0
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.TypedefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.PtrType:
+			if ptr || ref {
+				return nil, false, false
+			}
+			ptr = true
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.RefType:
+			if ptr || ref {
+				return nil, false, false
+			}
+			ref = true
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.ClassType:
+			c = get_cxxgo_container(iid)
+		}
+		break
+	}
+	if c == nil {
+		return nil, false, false
+	}
+	if c.class == "optional" {
+		// the Go *T already stands for a missing value: it can not be
+		// updated after the call as well.
+		return c, false, cst
+	}
+	return c, ptr || (ref && !cst), cst
+}
+
+// elt_goname returns the Go type of the i-th element type
+func (c *cxxgo_container) elt_goname(i int) string {
+	if c.ekind[i] == "string" {
+		return "string"
+	}
+	return gen_go_name_from_id(c.elts[i])
+}
+
+// goname returns the Go type of the container: a slice or a map for
+// converted containers, the name of the typed container handle otherwise.
+// (e.g. []int32, map[string]float64, VectorFoo)
+// Compound values are Go structs or interfaces named after the container,
+// except optional values which are Go pointers.
+func (c *cxxgo_container) goname() string {
+	switch {
+	case c.class == "optional":
+		// nil stands for a missing value
+		return "*" + c.elt_goname(0)
+	case c.cvt && c.class == "map":
+		return "map[" + c.elt_goname(0) + "]" + c.elt_goname(1)
+	case c.cvt && !c.is_compound():
+		return "[]" + c.elt_goname(0)
+	}
+	// e.g. std::unordered_map<int, Foo> -> UnorderedMapInt32Foo
+	//      std::pair<int, std::string>  -> PairInt32String
+	n := c.id.IdScopedName()
+	n = n[:strings.Index(n, "<")]
+	n = n[strings.LastIndex(n, ":")+1:]
+	words := strings.FieldsFunc(n, is_not_alnum)
+	for i, _ := range c.elts {
+		words = append(words, strings.FieldsFunc(c.elt_goname(i), is_not_alnum)...)
+	}
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, "")
+}
+
+// cname returns the prefix of the C functions handling the container
+func (c *cxxgo_container) cname(pkg string) string {
+	return fmt.Sprintf("_gocxx_cnt_%s_%s", pkg, get_iid_str(c.id))
+}
+
+// go_helper returns the name of the Go function converting the container
+// from or to Go (dir is "to_go", "from_go" or "view")
+func (c *cxxgo_container) go_helper(dir string) string {
+	return fmt.Sprintf("_gocxx_cnt_%s_%s", get_iid_str(c.id), dir)
+}
+
+// cxx_elt_in returns the C++ expression of the idx-th element of type i
+// stored in the C buffer buf
+func (c *cxxgo_container) cxx_elt_in(i int, buf, idx string) string {
+	if c.ekind[i] == "string" {
+		return fmt.Sprintf("std::string(((_gocxx_strview*)%s)[%s].p, ((_gocxx_strview*)%s)[%s].n)",
+			buf, idx, buf, idx)
+	}
+	return fmt.Sprintf("((%s*)%s)[%s]", c.cxx[i], buf, idx)
+}
+
+// cxx_elt_out returns the C++ statement storing the element expr of type i
+// as the idx-th element of the C buffer buf
+func (c *cxxgo_container) cxx_elt_out(i int, buf, idx, expr string) string {
+	if c.ekind[i] == "string" {
+		return fmt.Sprintf("((_gocxx_strview*)%s)[%s].p = %s.data(); ((_gocxx_strview*)%s)[%s].n = %s.size();",
+			buf, idx, expr, buf, idx, expr)
+	}
+	return fmt.Sprintf("((%s*)%s)[%s] = %s;", c.cxx[i], buf, idx, expr)
+}
+
+// go_elts_out emits the Go code allocating the slice name, of n elements
+// of type i, to be filled by C through the pointer returned by the
+// function.
+// post holds the code to run once the C buffer has been filled.
+func (c *cxxgo_container) go_elts_out(buf, post *bytes.Buffer, i int, name string) string {
+	if c.ekind[i] == "string" {
+		fmter(buf, "\tc_%s := make([]C._gocxx_strview, n)\n", name)
+		fmter(post,
+			"\t%s := make([]string, n)\n\tfor i, _ := range c_%s {\n\t\t%s[i] = C.GoStringN(c_%s[i].p, C.int(c_%s[i].n))\n\t}\n",
+			name, name, name, name, name,
+		)
+		return fmt.Sprintf("unsafe.Pointer(&c_%s[0])", name)
+	}
+	fmter(buf, "\t%s := make([]%s, n)\n", name, c.elt_goname(i))
+	return fmt.Sprintf("unsafe.Pointer(&%s[0])", name)
+}
+
+// go_elts_in emits the Go code handing the Go slice name, of elements of
+// type i, over to C and returns the pointer to pass to the C function.
+func (c *cxxgo_container) go_elts_in(buf *bytes.Buffer, i int, name string) string {
+	if c.ekind[i] == "string" {
+		fmter(buf,
+			"\tc_%s := make([]C._gocxx_strview, len(%s))\n\tfor i, str := range %s {\n\t\tc_%s[i].p = C.CString(str)\n\t\tc_%s[i].n = C.size_t(len(str))\n\t}\n",
+			name, name, name, name, name,
+		)
+		fmter(buf,
+			"\tdefer func() {\n\t\tfor i, _ := range c_%s {\n\t\t\tC.free(unsafe.Pointer(c_%s[i].p))\n\t\t}\n\t}()\n",
+			name, name,
+		)
+		return fmt.Sprintf("unsafe.Pointer(&c_%s[0])", name)
+	}
+	return fmt.Sprintf("unsafe.Pointer(&%s[0])", name)
+}
+
+// go_key_in emits the Go code handing the map key k over to C and returns
+// the pointer to pass to the C function.
+func (c *cxxgo_container) go_key_in(buf *bytes.Buffer) string {
+	if c.ekind[0] == "string" {
+		fmter(buf,
+			"\tvar c_k C._gocxx_strview\n\tc_k.p = C.CString(k)\n\tdefer C.free(unsafe.Pointer(c_k.p))\n\tc_k.n = C.size_t(len(k))\n",
+		)
+		return "unsafe.Pointer(&c_k)"
+	}
+	fmter(buf, "\tc_k := k\n")
+	return "unsafe.Pointer(&c_k)"
+}
+
+// wrapContainer generates the C helpers of a STL container, together with
+// the Go conversion functions or the typed container handle.
+func (p *plugin) wrapContainer(cid *cxxgo_id, c *cxxgo_container) error {
+	var err error
+	if c.is_compound() {
+		return p.wrapCompound(cid, c)
+	}
+	fmt.Printf(":: wrapping container [%s]...\n", c.id.IdScopedName())
+
+	pkg := p.gen.Fd.Package
+	bufs := new_bufmap(
+		"cxx",
+		"cgo_head",
+		"go_impl",
+	)
+
+	cnt := c.id.IdScopedName()
+	cn := c.cname(pkg)
+	ismap := c.class == "map"
+
+	// C helpers: creation, destruction and size
+	fmter(bufs["cgo_head"],
+		"\n/* helpers for [%s] */\nvoid* %s_new();\nvoid %s_delete(void *c_self);\nsize_t %s_len(void *c_self);\n",
+		cnt, cn, cn, cn,
+	)
+	fmter(bufs["cxx"],
+		"\n// helpers for [%s]\nvoid* %s_new()\n{\n  return new %s;\n}\n",
+		cnt, cn, cnt,
+	)
+	fmter(bufs["cxx"],
+		"\nvoid %s_delete(void *c_self)\n{\n  delete (%s*)c_self;\n}\n",
+		cn, cnt,
+	)
+	fmter(bufs["cxx"],
+		"\nsize_t %s_len(void *c_self)\n{\n  return ((%s*)c_self)->size();\n}\n",
+		cn, cnt,
+	)
+
+	// copy: from the container into C buffers (keys, for maps)
+	if c.cvt || ismap {
+		out := "(*it)"
+		if ismap {
+			out = "it->first"
+		}
+		stmts := []string{c.cxx_elt_out(0, "c_elts", "i", out)}
+		if ismap {
+			stmts[0] = c.cxx_elt_out(0, "c_keys", "i", out)
+		}
+		args := "void *c_elts"
+		fct := "copy"
+		switch {
+		case ismap && c.cvt:
+			args = "void *c_keys, void *c_vals"
+			stmts = append(stmts, c.cxx_elt_out(1, "c_vals", "i", "it->second"))
+		case ismap:
+			args = "void *c_keys"
+			fct = "keys"
+		}
+		fmter(bufs["cgo_head"], "void %s_%s(void *c_self, %s);\n", cn, fct, args)
+		fmter(bufs["cxx"],
+			"\nvoid %s_%s(void *c_self, %s)\n{\n  %s *cxx_self = (%s*)c_self;\n  size_t i = 0;\n  for (%s::const_iterator it = cxx_self->begin(); it != cxx_self->end(); ++it, ++i) {\n    %s\n  }\n}\n",
+			cn, fct, args, cnt, cnt, cnt,
+			strings.Join(stmts, "\n    "),
+		)
+	}
+
+	if c.cvt {
+		// fill: from C buffers into the container
+		args := "void *c_elts"
+		stmt := fmt.Sprintf("cxx_self->insert(cxx_self->end(), %s);",
+			c.cxx_elt_in(0, "c_elts", "i"))
+		cond := "i != n"
+		if c.class == "array" {
+			// fixed size: extra elements are dropped
+			cond = "i != n && i != cxx_self->size()"
+			stmt = fmt.Sprintf("(*cxx_self)[i] = %s;", c.cxx_elt_in(0, "c_elts", "i"))
+		}
+		if ismap {
+			args = "void *c_keys, void *c_vals"
+			stmt = fmt.Sprintf("(*cxx_self)[%s] = %s;",
+				c.cxx_elt_in(0, "c_keys", "i"),
+				c.cxx_elt_in(1, "c_vals", "i"))
+		}
+		fmter(bufs["cgo_head"], "void %s_fill(void *c_self, size_t n, %s);\n", cn, args)
+		fmter(bufs["cxx"],
+			"\nvoid %s_fill(void *c_self, size_t n, %s)\n{\n  %s *cxx_self = (%s*)c_self;\n  for (size_t i = 0; %s; ++i) {\n    %s\n  }\n}\n",
+			cn, args, cnt, cnt, cond, stmt,
+		)
+		if c.is_contiguous() {
+			fmter(bufs["cgo_head"], "void* %s_data(void *c_self);\n", cn)
+			fmter(bufs["cxx"],
+				"\nvoid* %s_data(void *c_self)\n{\n  %s *cxx_self = (%s*)c_self;\n  if (cxx_self->empty()) {\n    return NULL;\n  }\n  return (void*)&(*cxx_self)[0];\n}\n",
+				cn, cnt, cnt,
+			)
+		}
+		p.wrapContainerConversions(c, bufs["go_impl"])
+	} else {
+		// element access
+		ielt := 0
+		if ismap {
+			ielt = 1
+			fmter(bufs["cgo_head"],
+				"void* %s_at(void *c_self, void *c_key);\nvoid %s_push(void *c_self, void *c_key, void *c_elt);\n",
+				cn, cn,
+			)
+			key := c.cxx_elt_in(0, "c_key", "0")
+			fmter(bufs["cxx"],
+				"\nvoid* %s_at(void *c_self, void *c_key)\n{\n  %s *cxx_self = (%s*)c_self;\n  %s::iterator it = cxx_self->find(%s);\n  if (it == cxx_self->end()) {\n    return NULL;\n  }\n  return (void*)&(it->second);\n}\n",
+				cn, cnt, cnt, cnt, key,
+			)
+			fmter(bufs["cxx"],
+				"\nvoid %s_push(void *c_self, void *c_key, void *c_elt)\n{\n  %s *cxx_self = (%s*)c_self;\n  cxx_self->erase(%s);\n  cxx_self->insert(%s::value_type(%s, *(%s*)c_elt));\n}\n",
+				cn, cnt, cnt, key, cnt, key, c.cxx[1],
+			)
+		} else {
+			fmter(bufs["cgo_head"],
+				"void* %s_at(void *c_self, size_t i);\nvoid %s_push(void *c_self, void *c_elt);\n",
+				cn, cn,
+			)
+			fmter(bufs["cxx"],
+				"\nvoid* %s_at(void *c_self, size_t i)\n{\n  %s *cxx_self = (%s*)c_self;\n  %s::iterator it = cxx_self->begin();\n  std::advance(it, i);\n  return (void*)&(*it);\n}\n",
+				cn, cnt, cnt, cnt,
+			)
+			fmter(bufs["cxx"],
+				"\nvoid %s_push(void *c_self, void *c_elt)\n{\n  %s *cxx_self = (%s*)c_self;\n  cxx_self->insert(cxx_self->end(), *(%s*)c_elt);\n}\n",
+				cn, cnt, cnt, c.cxx[0],
+			)
+		}
+		p.wrapContainerHandle(cid, c, ielt, bufs["go_impl"])
+	}
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping container [%s]...[ok]\n", c.id.IdScopedName())
+	return err
+}
+
+// wrapContainerConversions generates the Go functions converting a C++
+// container from and to its Go equivalent.
+func (p *plugin) wrapContainerConversions(c *cxxgo_container, buf *bytes.Buffer) {
+	cn := "C." + c.cname(p.gen.Fd.Package)
+	cnt := c.id.IdScopedName()
+	gotype := c.goname()
+
+	// C++ -> Go
+	fmter(buf,
+		"\n// %s converts a C++ [%s] into a Go %s\nfunc %s(c unsafe.Pointer) %s {\n\tn := int(%s_len(c))\n",
+		c.go_helper("to_go"), cnt, gotype, c.go_helper("to_go"), gotype, cn,
+	)
+	post := new(bytes.Buffer)
+	if c.class == "map" {
+		k := c.go_elts_out(buf, post, 0, "k")
+		v := c.go_elts_out(buf, post, 1, "v")
+		fmter(buf, "\tif n > 0 {\n\t\t%s_copy(c, %s, %s)\n\t}\n", cn, k, v)
+		buf.Write(post.Bytes())
+		fmter(buf,
+			"\to := make(%s, n)\n\tfor i, _ := range k {\n\t\to[k[i]] = v[i]\n\t}\n\treturn o\n}\n",
+			gotype,
+		)
+	} else {
+		o := c.go_elts_out(buf, post, 0, "o")
+		fmter(buf, "\tif n > 0 {\n\t\t%s_copy(c, %s)\n\t}\n", cn, o)
+		buf.Write(post.Bytes())
+		fmter(buf, "\treturn o\n}\n")
+	}
+
+	// Go -> C++
+	fmter(buf,
+		"\n// %s converts a Go %s into a new C++ [%s]\nfunc %s(o %s) unsafe.Pointer {\n\tc := %s_new()\n\tif len(o) == 0 {\n\t\treturn c\n\t}\n",
+		c.go_helper("from_go"), gotype, cnt, c.go_helper("from_go"), gotype, cn,
+	)
+	if c.class == "map" {
+		fmter(buf,
+			"\tk := make([]%s, 0, len(o))\n\tv := make([]%s, 0, len(o))\n\tfor kk, vv := range o {\n\t\tk = append(k, kk)\n\t\tv = append(v, vv)\n\t}\n",
+			c.elt_goname(0), c.elt_goname(1),
+		)
+		ck := c.go_elts_in(buf, 0, "k")
+		cv := c.go_elts_in(buf, 1, "v")
+		fmter(buf, "\t%s_fill(c, C.size_t(len(o)), %s, %s)\n", cn, ck, cv)
+	} else {
+		co := c.go_elts_in(buf, 0, "o")
+		fmter(buf, "\t%s_fill(c, C.size_t(len(o)), %s)\n", cn, co)
+	}
+	fmter(buf, "\treturn c\n}\n")
+
+	if c.is_contiguous() {
+		fmter(buf,
+			`
+// %s returns a Go %s aliasing the storage of a C++ [%s]
+// The slice is only valid as long as the C++ container is alive and is not
+// resized. Mutations are visible on both sides.
+func %s(c unsafe.Pointer) %s {
+	n := int(%s_len(c))
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*%s)(%s_data(c)), n)
+}
+`,
+			c.go_helper("view"), gotype, cnt,
+			c.go_helper("view"), gotype, cn, c.elt_goname(0), cn,
+		)
+	}
+}
+
+// wrapContainerHandle generates the typed Go handle of a C++ container of
+// classes.
+// ielt is the index of the element type (the value type, for maps)
+func (p *plugin) wrapContainerHandle(cid *cxxgo_id, c *cxxgo_container, ielt int, buf *bytes.Buffer) {
+	cn := "C." + c.cname(p.gen.Fd.Package)
+	cnt := c.id.IdScopedName()
+	goname := c.goname()
+	impl := "Gocxxcptr" + goname
+	elt := c.elt_goname(ielt)
+	elt_impl := "Gocxxcptr" + elt
+
+	ismap := c.class == "map"
+	key := "i int"
+	if ismap {
+		key = "k " + c.elt_goname(0)
+	}
+
+	fmter(buf,
+		`
+// %s wraps the C++ container %s
+type %s interface {
+    /* -- gocxx internals begin -- */
+	Gocxxcptr() uintptr
+	GocxxIs%s()
+    /* -- gocxx internals end -- */
+
+	// Len returns the number of elements in the container.
+	Len() int
+`,
+		goname, cnt, goname, goname,
+	)
+	if ismap {
+		fmter(buf,
+			"\t// At returns the element with key k, or nil if there is none.\n\tAt(%s) %s\n\t// Push sets the element with key k to a copy of v.\n\tPush(%s, v %s)\n\t// Range calls f on each element of the container, until f returns false.\n\tRange(f func(%s, v %s) bool)\n}\n",
+			key, elt, key, elt, key, elt,
+		)
+	} else {
+		fmter(buf,
+			"\t// At returns the i-th element of the container.\n\tAt(%s) %s\n\t// Push adds a copy of v to the container.\n\tPush(v %s)\n\t// Range calls f on each element of the container, until f returns false.\n\tRange(f func(%s, v %s) bool)\n}\n",
+			key, elt, elt, key, elt,
+		)
+	}
+
+	fmter(buf, "\ntype %s uintptr\n", impl)
+	fmter(buf,
+		"\nfunc (p %s) Gocxxcptr() uintptr {\n\treturn uintptr(p)\n}\n",
+		impl,
+	)
+	fmter(buf, "\nfunc (p %s) GocxxIs%s() {\n}\n", impl, goname)
+
+	fmter(buf,
+		"\n// New%s creates a new empty C++ [%s]\nfunc New%s() %s {\n\treturn %s(uintptr(%s_new()))\n}\n",
+		goname, cnt, goname, goname, impl, cn,
+	)
+	fmter(buf,
+		"\n// Delete%s destroys a C++ [%s]\nfunc Delete%s(arg %s) {\n\t%s_delete(unsafe.Pointer(arg.Gocxxcptr()))\n}\n",
+		goname, cnt, goname, goname, cn,
+	)
+	fmter(buf,
+		"\nfunc (p %s) Len() int {\n\treturn int(%s_len(unsafe.Pointer(p)))\n}\n",
+		impl, cn,
+	)
+
+	if ismap {
+		kbuf := new(bytes.Buffer)
+		ck := c.go_key_in(kbuf)
+		fmter(buf,
+			"\nfunc (p %s) At(%s) %s {\n%s\tc := %s_at(unsafe.Pointer(p), %s)\n\tif c == nil {\n\t\treturn nil\n\t}\n\treturn %s(uintptr(c))\n}\n",
+			impl, key, elt, kbuf.String(), cn, ck, elt_impl,
+		)
+		fmter(buf,
+			"\nfunc (p %s) Push(%s, v %s) {\n%s\t%s_push(unsafe.Pointer(p), %s, unsafe.Pointer(%s))\n}\n",
+			impl, key, elt, kbuf.String(), cn, ck, go_cptr(p.gen.Fd.Package, c.elts[ielt], "v"),
+		)
+		post := new(bytes.Buffer)
+		kbuf.Reset()
+		ks := c.go_elts_out(kbuf, post, 0, "keys")
+		fmter(buf,
+			"\nfunc (p %s) Range(f func(%s, v %s) bool) {\n\tn := p.Len()\n\tif n == 0 {\n\t\treturn\n\t}\n%s\t%s_keys(unsafe.Pointer(p), %s)\n%s\tfor _, k := range keys {\n\t\tif !f(k, p.At(k)) {\n\t\t\treturn\n\t\t}\n\t}\n}\n",
+			impl, key, elt, kbuf.String(), cn, ks, post.String(),
+		)
+	} else {
+		fmter(buf,
+			"\nfunc (p %s) At(%s) %s {\n\tif i < 0 || i >= p.Len() {\n\t\tpanic(\"%s.At: index out of range\")\n\t}\n\treturn %s(uintptr(%s_at(unsafe.Pointer(p), C.size_t(i))))\n}\n",
+			impl, key, elt, goname, elt_impl, cn,
+		)
+		fmter(buf,
+			"\nfunc (p %s) Push(v %s) {\n\t%s_push(unsafe.Pointer(p), unsafe.Pointer(%s))\n}\n",
+			impl, elt, cn, go_cptr(p.gen.Fd.Package, c.elts[ielt], "v"),
+		)
+		fmter(buf,
+			"\nfunc (p %s) Range(f func(%s, v %s) bool) {\n\tn := p.Len()\n\tfor i := 0; i < n; i++ {\n\t\tif !f(i, p.At(i)) {\n\t\t\treturn\n\t\t}\n\t}\n}\n",
+			impl, key, elt,
+		)
+	}
+}
+
+// view_ret returns the contiguous container the result of the function
+// is a view of, or nil if the result is copied.
+// Only containers returned by reference or by pointer have an owner, and
+// may be viewed.
+func (f *cxxgo_function) view_ret() *cxxgo_container {
+	if !f.view || f.f.Ret == "" || f.f.Ret == "void" {
+		return nil
+	}
+	id := cxxtypes.IdByName(f.f.Ret)
+	if !get_cxxgo_id(f.pkg, id).is_pointer_like() {
+		return nil
+	}
+	c, _, _ := strip_container(id)
+	if c == nil || !c.is_contiguous() {
+		return nil
+	}
+	return c
+}
+
+// is_view_ptr returns whether the i-th parameter is the pointer of a
+// (T*, length) pair of parameters, passed from Go as a single slice.
+func (f *cxxgo_function) is_view_ptr(i int) bool {
+	if !(f.view || f.dir(i) == "slice") || i+1 >= f.nreq() {
+		return false
+	}
+	if view_elt_type(f.param_id(i)) == nil {
+		return false
+	}
+	return is_view_len_type(f.param_id(i + 1))
+}
+
+// is_view_len_type returns whether the type id may hold the length of a
+// (T*, length) pair of parameters.
+func is_view_len_type(id cxxtypes.Id) bool {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return false
+	}
+	ft, ok := resolve_typedef(t).(*cxxtypes.FundamentalType)
+	if !ok {
+		return false
+	}
+	switch ft.TypeKind() {
+	case cxxtypes.TK_Short, cxxtypes.TK_Int, cxxtypes.TK_Long, cxxtypes.TK_LongLong,
+		cxxtypes.TK_UShort, cxxtypes.TK_UInt, cxxtypes.TK_ULong, cxxtypes.TK_ULongLong:
+		return true
+	}
+	return false
+}
+
+// is_view_len returns whether the i-th parameter is the length of a
+// (T*, length) pair of parameters.
+func (f *cxxgo_function) is_view_len(i int) bool {
+	return i > 0 && f.is_view_ptr(i-1)
+}
+
+// nview returns the number of (T*, length) pairs of parameters passed as
+// a single Go slice.
+func (f *cxxgo_function) nview() int {
+	n := 0
+	for i := 0; i < f.nreq(); i++ {
+		if f.is_view_len(i) {
+			n += 1
+		}
+	}
+	return n
+}
+
+// view_elt_type returns the fundamental type the pointer type id points
+// at, if a Go slice may be handed over to C++ as such a pointer.
+func view_elt_type(id cxxtypes.Id) *cxxtypes.FundamentalType {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	ptr, ok := resolve_typedef(t).(*cxxtypes.PtrType)
+	if !ok {
+		return nil
+	}
+	ft, ok := resolve_typedef(ptr.UnderlyingType()).(*cxxtypes.FundamentalType)
+	if !ok {
+		return nil
+	}
+	switch ft.TypeKind() {
+	case cxxtypes.TK_Char_S, cxxtypes.TK_Char_U:
+		// C strings
+		return nil
+	}
+	if container_elt_kind(ft) != "value" {
+		return nil
+	}
+	return ft
+}
+
+// EOF
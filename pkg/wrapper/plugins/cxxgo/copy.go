@@ -0,0 +1,211 @@
+package cxxgo
+
+import (
+	"fmt"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// self_ref_kind returns the kind of reference (TK_LValueRef or
+// TK_RValueRef) to its own class taken by the member function fct as its
+// only required parameter, or TK_Invalid.
+// (ie: whether fct is a copy or a move constructor/assignment operator)
+func self_ref_kind(fct *cxxtypes.Function) cxxtypes.TypeKind {
+	if !fct.IsMethod() || fct.NumParam() < 1 ||
+		fct.NumParam()-fct.NumDefaultParam() > 1 {
+		return cxxtypes.TK_Invalid
+	}
+	ref, ok := cxxtypes.IdByName(fct.Param(0).Type).(*cxxtypes.RefType)
+	if !ok {
+		return cxxtypes.TK_Invalid
+	}
+	cls := class_of(resolve_typedef(ref.UnderlyingType()).(cxxtypes.Id))
+	if cls == nil || cls.IdScopedName() != cxxtypes.IdByName(fct.BaseId.Scope).IdScopedName() {
+		return cxxtypes.TK_Invalid
+	}
+	return ref.TypeKind()
+}
+
+// class_by_value returns the wrapped class (or struct) passed by value as the
+// type id (ie: not through a pointer nor a reference), or nil.
+func class_by_value(id cxxtypes.Id) cxxtypes.Id {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	cls, ok := resolve_typedef(t).(cxxtypes.Id)
+	if !ok || !is_plain_class(cls) {
+		return nil
+	}
+	return cls
+}
+
+// is_move_param returns whether the parameter type id is an rvalue reference
+// to a wrapped class, or a wrapped class passed by value which can only be
+// moved: the Go handle is then passed by pointer, and invalidated once the
+// C++ object has been moved from.
+func is_move_param(id cxxtypes.Id) bool {
+	if cls := class_by_value(id); cls != nil {
+		return !can_copy(cls, true, true) && can_move(cls)
+	}
+	ref, ok := id.(*cxxtypes.RefType)
+	if !ok || ref.TypeKind() != cxxtypes.TK_RValueRef {
+		return false
+	}
+	cls := class_of(resolve_typedef(ref.UnderlyingType()).(cxxtypes.Id))
+	return cls != nil && is_plain_class(cls)
+}
+
+// can_copy returns whether the instances of the class id can be copied:
+// copy-constructed (ctor) or copy-assigned, from the outside of the class
+// (public) or from a derived class.
+// A copy operation is not available when it is private or deleted, when it
+// is implicitly declared but the class declares a move operation, or when
+// the copy operation of one of the bases is not available.
+func can_copy(id cxxtypes.Id, ctor, public bool) bool {
+	declared := false
+	usable := false
+	moves := false
+	for _, mbr := range class_member_list(id) {
+		if !mbr.IsFunctionMember() {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for ifct, _ := range ovfct.Fcts {
+			fct := ovfct.Function(ifct)
+			if !fct.IsConstructor() && !fct.IsCopyConstructor() && !fct.IsAssignOperator() {
+				continue
+			}
+			switch self_ref_kind(fct) {
+			case cxxtypes.TK_RValueRef:
+				moves = true
+			case cxxtypes.TK_LValueRef:
+				if fct.IsAssignOperator() == ctor {
+					continue
+				}
+				declared = true
+				if !fct.IsDeleted() && !fct.IsPrivate() &&
+					!(public && fct.IsProtected()) {
+					usable = true
+				}
+			}
+		}
+	}
+	if declared && !usable {
+		return false
+	}
+	if !declared && moves {
+		// implicitly deleted
+		return false
+	}
+	for _, base := range class_base_list(id) {
+		if !can_copy(cxxtypes.IdByName(base.TypeBase), ctor, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// can_move returns whether the instances of the class id can be
+// move-constructed from the outside of the class, with its move constructor
+// or (if it declares none) with its copy constructor.
+func can_move(id cxxtypes.Id) bool {
+	for _, mbr := range class_member_list(id) {
+		if !mbr.IsFunctionMember() {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for ifct, _ := range ovfct.Fcts {
+			fct := ovfct.Function(ifct)
+			if fct.IsConstructor() && self_ref_kind(fct) == cxxtypes.TK_RValueRef {
+				return !fct.IsDeleted() && !fct.IsPrivate() && !fct.IsProtected()
+			}
+		}
+	}
+	return can_copy(id, true, true)
+}
+
+// copy_method_names returns the Go names of the copy methods generated for
+// the class id (see wrapCopy), which are not used by the public methods of
+// the class or of its bases.
+func (p *plugin) copy_method_names(id cxxtypes.Id) []string {
+	names := []string{}
+	gonames := p.public_go_names(id)
+	if t, ok := id.(cxxtypes.Type); ok && !cxxtypes.IsAbstractType(t) &&
+		can_copy(id, true, true) && !gonames["Clone"] {
+		names = append(names, "Clone")
+	}
+	if can_copy(id, false, true) && !gonames["CopyFrom"] {
+		names = append(names, "CopyFrom")
+	}
+	return names
+}
+
+// wrapCopy adds to the Go handle of the class id (Gocxxcptr<Class>):
+//   - Clone() returning a new copy of the object (w/ the copy constructor),
+//   - CopyFrom(o) copying o into the object (w/ the assignment operator),
+//
+// when the class can be copied and these Go names are not already in use.
+// They are not part of the interface of the class: the handles of the
+// derived classes, whose copy methods deal with the derived class, still
+// implement it.
+func (p *plugin) wrapCopy(cid *cxxgo_id, id *cxxtypes.ClassType, cls_bufs bufmap_t) error {
+	var err error
+	pkg := p.gen.Fd.Package
+	names := p.copy_method_names(id)
+
+	bufs := new_bufmap("cxx", "cgo_head")
+	n := id.IdScopedName()
+	go_cls_impl_name := "Gocxxcptr" + cid.goname
+
+	for _, name := range names {
+		cn := fmt.Sprintf("_gocxx_%s_%s_%s", name, pkg, get_iid_str(id))
+		switch name {
+		case "Clone":
+			fmter(cls_bufs["go_impl"],
+				"\n// Clone returns a copy of the object, owned by the caller.\n// (wraps the copy constructor of [%s])\nfunc (p %s) Clone() %s {\n\treturn %s(C.%s(unsafe.Pointer(p)))\n}\n",
+				n, go_cls_impl_name, cid.goname, go_cls_impl_name, cn,
+			)
+			fmter(bufs["cxx"],
+				"\n// copies [%s]\nvoid* %s(void *c_self)\n{\n  return (void*)new ::%s(*(::%s*)c_self);\n}\n",
+				n, cn, n, n,
+			)
+			fmter(bufs["cgo_head"],
+				"\n/* copies [%s] */\nvoid* %s(void *c_self);\n",
+				n, cn,
+			)
+		case "CopyFrom":
+			fmter(cls_bufs["go_impl"],
+				"\n// CopyFrom assigns a copy of o to the object.\n// (wraps the assignment operator of [%s])\nfunc (p %s) CopyFrom(o %s) {\n\tC.%s(unsafe.Pointer(p), unsafe.Pointer(o.GocxxPtr%s()))\n}\n",
+				n, go_cls_impl_name, cid.goname, cn, cid.goname,
+			)
+			fmter(bufs["cxx"],
+				"\n// assigns a copy of [%s]\nvoid %s(void *c_self, void *c_other)\n{\n  *(::%s*)c_self = *(::%s*)c_other;\n}\n",
+				n, cn, n, n,
+			)
+			fmter(bufs["cgo_head"],
+				"\n/* assigns a copy of [%s] */\nvoid %s(void *c_self, void *c_other);\n",
+				n, cn,
+			)
+		}
+	}
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+// EOF
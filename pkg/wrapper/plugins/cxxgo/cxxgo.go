@@ -3,8 +3,10 @@ package cxxgo
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path"
+	"sort"
 	"strings"
 
 	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
@@ -12,12 +14,35 @@ import (
 )
 
 type bufmap_t map[string]*bytes.Buffer
-type idmap_t map[cxxtypes.Id]uint64
+type idmap_t map[string]uint64
 
 type plugin struct {
 	gen *wrapper.Generator // the generator which is invoking us
 	sel []string           // the identifiers to select
 	ids []string           // the selected identifiers
+
+	ovl_typed    bool // emit one statically typed Go function per overload
+	ovl_dispatch bool // emit a variadic Go function dispatching on overloads
+
+	views []string // the functions returning zero-copy slice views
+
+	params   []param_rule // the directions of selected function parameters
+	out_refs string       // direction of non-const T& parameters (out|inout)
+
+	status []status_rule // the status codes returned as Go errors
+
+	generics bool // emit Go generic facades of class templates
+
+	ns_mode   string // how to map C++ namespaces: flat|strip|packages
+	ns_import string // import path of the generated package (packages)
+
+	typedef_alias bool // wrap all the selected typedefs as Go aliases
+
+	usings map[string]bool // the using-declarations of member functions (e.g. D::f)
+
+	stringers map[string]bool // the classes printable to a std::ostream
+
+	protected map[string]*cxxgo_protected // the protected members of the classes
 }
 
 func (p *plugin) Name() string {
@@ -58,6 +83,273 @@ func (p *plugin) Init(g *wrapper.Generator) error {
 	}
 	p.ids = []string{}
 
+	// additional identifiers (patterns of scoped names) to select
+	if v, ok := g.Args["select"]; ok {
+		switch v := v.(type) {
+		case string:
+			for _, pat := range strings.Split(v, ",") {
+				if pat = strings.TrimSpace(pat); pat != "" {
+					p.sel = append(p.sel, pat)
+				}
+			}
+		case []string:
+			p.sel = append(p.sel, v...)
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'select' [%v] (expected a comma-separated list of patterns)",
+				v)
+		}
+	}
+
+	// how to wrap overloaded functions:
+	//  - "dispatch": one variadic Go function with a run-time type-switch
+	//  - "typed": one statically typed Go function per overload
+	//  - "both": statically typed Go functions and the variadic dispatcher
+	p.ovl_typed = false
+	p.ovl_dispatch = true
+	if v, ok := g.Args["overloads"]; ok {
+		switch v {
+		case "dispatch":
+			// default
+		case "typed":
+			p.ovl_typed = true
+			p.ovl_dispatch = false
+		case "both":
+			p.ovl_typed = true
+			p.ovl_dispatch = true
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'overloads' [%v] (expected dispatch|typed|both)",
+				v)
+		}
+	}
+
+	// functions (patterns of scoped names, e.g. "Class::doubles") which
+	// return Go slices aliasing the storage of C++ contiguous containers,
+	// instead of copies, and take T*+length pairs as Go slices.
+	p.views = []string{}
+	if v, ok := g.Args["views"]; ok {
+		switch v := v.(type) {
+		case string:
+			for _, pat := range strings.Split(v, ",") {
+				if pat = strings.TrimSpace(pat); pat != "" {
+					p.views = append(p.views, pat)
+				}
+			}
+		case []string:
+			p.views = append(p.views, v...)
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'views' [%v] (expected a comma-separated list of patterns)",
+				v)
+		}
+	}
+
+	// directions of function parameters (e.g. "get_range(lo)=out"): output
+	// parameters are returned as additional Go results. see
+	// parse_param_rules.
+	p.params = nil
+	if v, ok := g.Args["params"]; ok {
+		rules, err := parse_param_rules(v)
+		if err != nil {
+			return err
+		}
+		p.params = rules
+	}
+
+	// how to wrap the non-const lvalue references to fundamental types:
+	//  - "": as Go pointers (default)
+	//  - "out": as output parameters, returned as additional Go results
+	//  - "inout": as input-output parameters
+	p.out_refs = ""
+	if v, ok := g.Args["out-refs"]; ok {
+		switch v {
+		case "", "none":
+			// default
+		case "out", "inout":
+			p.out_refs = v.(string)
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'out-refs' [%v] (expected none|out|inout)",
+				v)
+		}
+	}
+
+	// status codes (e.g. "int==0@App::*") returned as Go errors.
+	// see parse_status_rules.
+	p.status = nil
+	if v, ok := g.Args["errors"]; ok {
+		rules, err := parse_status_rules(v)
+		if err != nil {
+			return err
+		}
+		p.status = rules
+	}
+
+	// template instances (e.g. "NS::tmpl<int>=TmplInt") to select, with
+	// their Go names. see parse_tmpl_sels.
+	g_tmpl_sels = nil
+	if v, ok := g.Args["templates"]; ok {
+		sels, err := parse_tmpl_sels(v)
+		if err != nil {
+			return err
+		}
+		g_tmpl_sels = sels
+	}
+
+	// whether to emit, for the class templates whose selected instances
+	// share the same shape, a Go generic facade (e.g. Vector[T])
+	// dispatching to the wrappers of the instances.
+	p.generics = false
+	if v, ok := g.Args["generics"]; ok {
+		switch v := v.(type) {
+		case bool:
+			p.generics = v
+		case string:
+			switch v {
+			case "", "false":
+				p.generics = false
+			case "true":
+				p.generics = true
+			default:
+				return fmt.Errorf(
+					"cxxgo: invalid value for argument 'generics' [%v] (expected true|false)",
+					v)
+			}
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'generics' [%v] (expected true|false)",
+				v)
+		}
+	}
+
+	// how to map C++ namespaces to Go:
+	//  - "flat": prefix the Go names with the namespaces (e.g. Math_do_hello)
+	//  - "strip": as "flat", without the root namespaces given by 'ns-strip'
+	//  - "packages": as "flat", plus one Go package per namespace
+	//    re-exporting its identifiers (e.g. math.Do_hello). the import path
+	//    of the generated package is given by 'ns-import'.
+	p.ns_mode = "flat"
+	if v, ok := g.Args["namespaces"]; ok {
+		switch v {
+		case "", "flat":
+			// default
+		case "strip", "packages":
+			p.ns_mode = v.(string)
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'namespaces' [%v] (expected flat|strip|packages)",
+				v)
+		}
+	}
+
+	g_ns_strip = nil
+	if v, ok := g.Args["ns-strip"]; ok {
+		switch v := v.(type) {
+		case string:
+			for _, ns := range strings.Split(v, ",") {
+				if ns = strings.Trim(strings.TrimSpace(ns), ":"); ns != "" {
+					g_ns_strip = append(g_ns_strip, ns)
+				}
+			}
+		case []string:
+			g_ns_strip = append(g_ns_strip, v...)
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'ns-strip' [%v] (expected a comma-separated list of namespaces)",
+				v)
+		}
+	}
+	if p.ns_mode != "strip" {
+		g_ns_strip = nil
+	} else if len(g_ns_strip) <= 0 {
+		return fmt.Errorf("cxxgo: argument 'ns-strip' is required by namespaces=strip")
+	}
+
+	p.ns_import = ""
+	if v, ok := g.Args["ns-import"]; ok {
+		switch v := v.(type) {
+		case string:
+			p.ns_import = v
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'ns-import' [%v] (expected an import path)",
+				v)
+		}
+	}
+	if p.ns_mode == "packages" && p.ns_import == "" {
+		return fmt.Errorf("cxxgo: argument 'ns-import' is required by namespaces=packages")
+	}
+
+	// how to wrap the selected typedefs:
+	//  - "named": Go named types (e.g. type Ssiz_t int32) for the typedefs
+	//    of fundamental types, enums and strings, Go aliases otherwise
+	//  - "alias": Go aliases (e.g. type Ssiz_t = int32)
+	p.typedef_alias = false
+	if v, ok := g.Args["typedefs"]; ok {
+		switch v {
+		case "", "named":
+			// default
+		case "alias":
+			p.typedef_alias = true
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'typedefs' [%v] (expected named|alias)",
+				v)
+		}
+	}
+
+	// the using-declarations of member functions of base classes (e.g.
+	// "NS::D::f" for a 'using Base::f' in NS::D): gccxml does not report
+	// their names, so they have to be given explicitly.
+	p.usings = make(map[string]bool)
+	if v, ok := g.Args["using"]; ok {
+		var names []string
+		switch v := v.(type) {
+		case string:
+			names = strings.Split(v, ",")
+		case []string:
+			names = v
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'using' [%v] (expected a comma-separated list of class::member)",
+				v)
+		}
+		for _, n := range names {
+			if n = strings.TrimLeft(strings.TrimSpace(n), ":"); n != "" {
+				p.usings[n] = true
+			}
+		}
+	}
+
+	// the separator between the Go names of a class and of its nested
+	// types (e.g. "" for OuterInner, "_" for Outer_Inner)
+	g_nested_sep = ""
+	if v, ok := g.Args["nested-sep"]; ok {
+		switch v := v.(type) {
+		case string:
+			g_nested_sep = v
+		default:
+			return fmt.Errorf(
+				"cxxgo: invalid value for argument 'nested-sep' [%v] (expected a string)",
+				v)
+		}
+	}
+
+	// start afresh: numbering of identifiers and wrapping status
+	// shall not leak from a previous generation
+	g_idmap = make(idmap_t)
+	g_iids = make(map[uint64]string)
+	g_cxxgo_idmap = make(cxxgo_idmap_t)
+	p.stringers = make(map[string]bool)
+	p.protected = make(map[string]*cxxgo_protected)
+	g_typedefs = make(map[string]bool)
+	g_opaques = make(map[string]bool)
+	_cxx2go_typemap = make(map[string]string, len(_cxx2go_builtins))
+	for k, v := range _cxx2go_builtins {
+		_cxx2go_typemap[k] = v
+	}
+
 	fmt.Printf("cxxgo.Init: args=%v\n", g.Args)
 	return nil
 }
@@ -66,7 +358,24 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 	fmt.Printf("cxxgo.Generate...\n")
 
 	// loop over identifiers and filter them out
-	for _, n := range cxxtypes.IdNames() {
+	// (in a definite order, so the generated files are reproducible)
+	names := cxxtypes.IdNames()
+	sort.Strings(names)
+
+	// default values are evaluated from the global scope of the generated
+	// C++ code
+	qualify_defaults(names)
+
+	for _, n := range names {
+		if ovfct, ok := cxxtypes.IdByName(n).(*cxxtypes.OverloadFunctionSet); ok {
+			// operator<< of classes, selected or not, provide their
+			// String method
+			for i, _ := range ovfct.Fcts {
+				if cls := stream_operator_class(ovfct.Function(i)); cls != "" {
+					p.stringers[cls] = true
+				}
+			}
+		}
 		selected := false
 		for _, sel := range p.sel {
 			matched, err := path.Match(sel, n)
@@ -74,15 +383,55 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 				selected = true
 				break
 			}
+			if strings.Contains(sel, "<") && match_template(sel, cxxtypes.IdByName(n)) {
+				selected = true
+				break
+			}
+		}
+		for _, sel := range g_tmpl_sels {
+			if selected {
+				break
+			}
+			selected = match_template(sel.pat, cxxtypes.IdByName(n))
 		}
 		if selected && is_anon(n) {
 			fmt.Printf(":: discarding [%s] (anonymous identifier)\n", n)
 			selected = false
 		}
+		if selected && get_cxxgo_string(cxxtypes.IdByName(n)) != nil && !is_go_typedef(cxxtypes.IdByName(n)) {
+			// strings are converted to Go strings, not wrapped
+			selected = false
+		}
+		if selected && strip_stream(cxxtypes.IdByName(n)) != nil && !is_go_typedef(cxxtypes.IdByName(n)) {
+			// streams are bridged to Go writers and readers, not wrapped
+			selected = false
+		}
 		if selected {
+			if ns, ok := cxxtypes.IdByName(n).(*cxxtypes.Namespace); ok {
+				// namespaces are wrapped through their members
+				// (see wrapNamespaces)
+				p.ids = append(p.ids, ns_members(ns)...)
+				continue
+			}
+			if _, ok := cxxtypes.IdByName(n).(*cxxtypes.NamespaceAlias); ok {
+				continue
+			}
+			p.ids = append(p.ids, n)
+			id := cxxtypes.IdByName(n)
+			if _, isfct := id.(*cxxtypes.OverloadFunctionSet); !isfct {
+				_cxx2go_typemap[n] = gen_go_name_from_id(id)
+			}
+		}
+	}
+	{
+		// select the nested types with their enclosing class
+		nested := []string{}
+		for _, n := range p.ids {
+			nested = append(nested, p.nested_ids(cxxtypes.IdByName(n))...)
+		}
+		for _, n := range nested {
 			p.ids = append(p.ids, n)
-			nn := gen_go_name_from_id(cxxtypes.IdByName(n))
-			_cxx2go_typemap[n] = nn
+			_cxx2go_typemap[n] = gen_go_name_from_id(cxxtypes.IdByName(n))
 		}
 	}
 	{
@@ -92,7 +441,18 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 			id := cxxtypes.IdByName(n)
 			sel_deps = append(sel_deps, get_dependent_ids(sel_deps, id)...)
 		}
-		p.ids = append(p.ids, sel_deps...)
+		select_opaques(p.ids, sel_deps)
+		for _, n := range sel_deps {
+			if get_cxxgo_string(cxxtypes.IdByName(n)) != nil && !is_go_typedef(cxxtypes.IdByName(n)) {
+				// strings are converted to Go strings, not wrapped
+				continue
+			}
+			if strip_stream(cxxtypes.IdByName(n)) != nil && !is_go_typedef(cxxtypes.IdByName(n)) {
+				// streams are bridged to Go writers and readers
+				continue
+			}
+			p.ids = append(p.ids, n)
+		}
 	}
 	{
 		// make sure we don't wrap a member twice: remove duplicates
@@ -143,6 +503,28 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 			}
 		}
 	}
+	{
+		// discard the nested types which are not accessible, and the types
+		// which can not be represented in Go
+		sel_ids := make([]string, 0, len(p.ids))
+		for _, n := range p.ids {
+			if !p.nested_filter(cxxtypes.IdByName(n)) {
+				fmt.Printf(":: discarding [%s] (non-accessible nested type)\n", n)
+				continue
+			}
+			if _, ok := cxxtypes.IdByName(n).(cxxtypes.Type); ok {
+				if why := unsupported_type(cxxtypes.IdByName(n)); why != "" {
+					fmt.Printf(":: discarding [%s] (unsupported %s)\n", n, why)
+					continue
+				}
+			}
+			sel_ids = append(sel_ids, n)
+		}
+		p.ids = sel_ids
+	}
+	sort.Strings(p.ids)
+	p.ids = order_nested(p.ids)
+	p.select_typedefs()
 	fmt.Printf("selected ids: ['%v']\n", strings.Join(p.ids, "', '"))
 	if len(p.ids) <= 0 {
 		fmt.Printf("nothing to wrap\n")
@@ -171,19 +553,33 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 	fd.Files["hdr"] = fd_hdr
 	fd.Files["go"] = fd_go
 
+	imports := ""
+	if len(p.status) > 0 {
+		imports = "import \"strconv\"\n"
+	}
 	_, err = fd_go.WriteString(fmt.Sprintf(
 		_go_hdr,
+		gen_go_build_constraint(cxxtypes.CurrentTarget()),
 		fd.Package,
 		fd_hdr.Name(),
 		fd.Name,
 		fd.Name+"_cxxgo.plugin",
+		imports,
 	))
 	if err != nil {
 		return err
 	}
 
+	if len(p.status) > 0 {
+		_, err = fd_go.WriteString(_go_status_error)
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err = fd_cxx.WriteString(fmt.Sprintf(
 		_cxx_hdr,
+		compound_includes(p.ids),
 		fd_hdr.Name(),
 		fd.Header,
 	))
@@ -191,6 +587,11 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 		return err
 	}
 
+	err = p.wrapAccessors()
+	if err != nil {
+		return err
+	}
+
 	_, err = fd_hdr.WriteString(fmt.Sprintf(
 		_hdr_hdr,
 		fd.Package,
@@ -200,11 +601,44 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 		return err
 	}
 
+	err = p.wrapStatusValues()
+	if err != nil {
+		return err
+	}
+
 	for _, n := range p.ids {
 		id := cxxtypes.IdByName(n)
 		cid := get_cxxgo_id(p.gen.Fd.Package, id)
 		switch id := id.(type) {
 		case *cxxtypes.ClassType:
+			if c := get_cxxgo_container(id); c != nil {
+				err := p.wrapContainer(cid, c)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if sp := get_cxxgo_smartptr(id); sp != nil {
+				err := p.wrapSmartPtr(cid, sp)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if cb := get_cxxgo_callback(id); cb != nil {
+				err := p.wrapCallback(cid, cb)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if g_opaques[n] {
+				err := p.wrapOpaque(cid, id)
+				if err != nil {
+					return err
+				}
+				break
+			}
 			err := p.wrapClass(cid, id)
 			if err != nil {
 				return err
@@ -222,11 +656,8 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 				return err
 			}
 
-		case *cxxtypes.Namespace:
-			err := p.wrapNamespace(cid, id)
-			if err != nil {
-				return err
-			}
+		case *cxxtypes.Namespace, *cxxtypes.NamespaceAlias:
+			// see wrapNamespaces
 
 		case *cxxtypes.Member:
 			// will be done by a scope-level thingy...
@@ -274,6 +705,23 @@ func (p *plugin) Generate(fd *wrapper.FileDescriptor) error {
 		}
 	}
 
+	if p.generics {
+		err = p.wrapGenerics()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.wrapDynamic()
+	if err != nil {
+		return err
+	}
+
+	err = p.wrapNamespaces()
+	if err != nil {
+		return err
+	}
+
 	_, err = fd_go.WriteString(fmt.Sprintf(
 		_go_footer,
 		fd.Package,
@@ -322,6 +770,7 @@ type %s interface {
     /* -- gocxx internals begin -- */
 	Gocxxcptr() uintptr
 	GocxxIs%s()
+	GocxxPtr%s() uintptr
     /* -- gocxx internals end -- */
 
 `,
@@ -329,6 +778,7 @@ type %s interface {
 		clf,
 		cid.goname,
 		cid.goname,
+		cid.goname,
 	)
 
 	fmter(bufs["go_impl"], "type %s uintptr\n", go_cls_impl_name)
@@ -341,51 +791,20 @@ type %s interface {
 		go_cls_impl_name,
 		cid.goname,
 	)
+	fmter(bufs["go_impl"],
+		"\nfunc (p %s) GocxxPtr%s() uintptr {\n\treturn uintptr(p)\n}\n",
+		go_cls_impl_name,
+		cid.goname,
+	)
 
 	// bases...
-	bufs_bases := make([]bufmap_t, 0, len(id.Bases))
-	for _, base := range id.Bases {
-		bufs_base := bufmap_t{
-			"go_impl":  bytes.NewBufferString(""),
-			"go_iface": bytes.NewBufferString(""),
-			"cxx":      bytes.NewBufferString(""),
-		}
-		bufs_bases = append(bufs_bases, bufs_base)
-		err := p.wrapBaseClass(&base, bufs)
+	for i, _ := range id.Bases {
+		err := p.wrapBaseClass(cid, id, &id.Bases[i], bufs)
 		if err != nil {
 			return err
 		}
-		if base.IsPublic() {
-			base_id := base.Type().(cxxtypes.Id)
-			bid := get_cxxgo_id(p.gen.Fd.Package, base_id)
-			go_base_cls_iface_name := bid.goname
-			fmter(bufs["go_iface"],
-				"\tGocxxGet%s() %s\n",
-				go_base_cls_iface_name,
-				go_base_cls_iface_name)
-
-			fmter(bufs["go_impl"],
-				"\nfunc (p %s) GocxxGet%s() %s {\n",
-				go_cls_impl_name,
-				go_base_cls_iface_name,
-				go_base_cls_iface_name,
-			)
-			fmter(bufs["go_impl"],
-				"return Gocxxcptr%s(p.Gocxxcptr())\n}\n",
-				go_base_cls_iface_name,
-			)
-
-			fmter(bufs["go_iface"],
-				"\tGocxxIs%s()\n",
-				go_base_cls_iface_name,
-			)
-			fmter(bufs["go_impl"],
-				"\nfunc (p %s) GocxxIs%s() {\n}\n",
-				go_cls_impl_name,
-				go_base_cls_iface_name,
-			)
-		}
 	}
+	p.wrapIndirectBases(cid, id, bufs)
 
 	fct_mbr_indices := make([]int, 0, len(id.Members))
 	fct_mbr_names := make([]string, 0, len(id.Members))
@@ -411,7 +830,7 @@ type %s interface {
 			fmt.Printf("==embr: %v\n", mbr.IsEnumMember())
 			fmt.Printf("==mkind: %v\n", mbr.Kind)
 			fmt.Printf("==mdind: %v\n", mbr.IdKind())
-			return fmt.Errorf("cxxgo: could not retrieve identifier [%s]\n%s", mbr.Name, mbr)
+			return fmt.Errorf("cxxgo: could not retrieve identifier [%s]\n%s", mbr.Name, &mbr)
 		}
 		//fmt.Printf("--> (%s)[%s]...\n", mbr.IdScopedName(), mbr)
 		err := p.wrapMember(&mbr, bufs)
@@ -429,6 +848,24 @@ type %s interface {
 		}
 	}
 
+	// inherited fct-members
+	err = p.wrapInheritedMethods(cid, id, bufs)
+	if err != nil {
+		return err
+	}
+
+	if p.stringers[id.IdScopedName()] {
+		err := p.wrapStringer(cid, id, bufs)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.wrapCopy(cid, id, bufs)
+	if err != nil {
+		return err
+	}
+
 	fmter(bufs["go_iface"], "}\n\n")
 
 	// commit buffers
@@ -462,6 +899,11 @@ type %s interface {
 		return err
 	}
 
+	err = p.wrapProtected(cid, id)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf(":: wrapping class [%s]...[ok]\n", id.IdScopedName())
 	return err
 }
@@ -469,14 +911,14 @@ type %s interface {
 func (p *plugin) wrapStruct(cid *cxxgo_id, id *cxxtypes.StructType) error {
 	fmt.Printf(":: wrapping struct [%s]...\n", id.IdScopedName())
 
-	fmt.Printf(":: wrapping struct [%s]...[ok]\n", id.IdScopedName())
-	return nil
-}
-
-func (p *plugin) wrapNamespace(cid *cxxgo_id, id *cxxtypes.Namespace) error {
-	fmt.Printf(":: wrapping namespace [%s]...\n", id.IdScopedName())
+	// FIXME: wrap the members of structs.
+	//        for now, structs are handled as opaque handles.
+	err := p.wrapOpaque(cid, id)
+	if err != nil {
+		return err
+	}
 
-	fmt.Printf(":: wrapping namespace [%s]...[ok]\n", id.IdScopedName())
+	fmt.Printf(":: wrapping struct [%s]...[ok]\n", id.IdScopedName())
 	return nil
 }
 
@@ -538,15 +980,24 @@ func (p *plugin) wrapTypedef(cid *cxxgo_id, id *cxxtypes.TypedefType) error {
 		cid.id.IdScopedName(),
 	)
 
-	uid := cxxtypes.UnqualifiedType(id.UnderlyingType())
+	uid := resolve_typedef(id)
 	//uid := id.UnderlyingType()
 	switch uid.(type) {
 	case *cxxtypes.FundamentalType:
+		ct := uid.TypeName()
+		if o, ok := _cxx2cgo_typemap[ct]; ok {
+			ct = o
+		}
 		fmter(bufs["cgo_head"],
 			"typedef %s %s;\n",
-			uid.TypeName(),
+			ct,
 			strings.Replace(cid.cgoname, "C.", "", 1),
 			)
+	case *cxxtypes.EnumType:
+		fmter(bufs["cgo_head"],
+			"typedef int %s;\n",
+			strings.Replace(cid.cgoname, "C.", "", 1),
+		)
 	default:
 		fmter(bufs["cgo_head"],
 			"typedef void* %s;\n",
@@ -554,12 +1005,31 @@ func (p *plugin) wrapTypedef(cid *cxxgo_id, id *cxxtypes.TypedefType) error {
 			)
 	}
 
+	if named, ok := g_typedefs[id.IdScopedName()]; ok {
+		// the Go type of the typedef
+		ut := gen_go_name_from_id(id.UnderlyingType().(cxxtypes.Id))
+		fmter(bufs["go_iface"],
+			"\n// %s wraps the typedef %s\n",
+			cid.goname, id.IdScopedName(),
+		)
+		if named {
+			fmter(bufs["go_iface"], "type %s %s\n", cid.goname, ut)
+		} else {
+			fmter(bufs["go_iface"], "type %s = %s\n", cid.goname, ut)
+		}
+	}
+
 	// commit buffers
 	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
 	if err != nil {
 		return err
 	}
 
+	_, err = bufs["go_iface"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf(":: wrapping typedef [%s]...[ok]\n", id.IdScopedName())
 	return err
 }
@@ -654,29 +1124,181 @@ func (p *plugin) wrapCvrQualType(cid *cxxgo_id, id *cxxtypes.CvrQualType) error
 	return nil
 }
 
-func (p *plugin) wrapBaseClass(id *cxxtypes.Base, bufs bufmap_t) error {
-	return nil
-}
+// wrapBaseClass gives access to the public base class base of the class id.
+// The pointer to the base class subobject is computed on the C++ side
+// (w/ a static_cast), as its address differs from the one of the derived
+// object for the second and later bases and for virtual bases.
+func (p *plugin) wrapBaseClass(cid *cxxgo_id, id *cxxtypes.ClassType, base *cxxtypes.Base, bufs bufmap_t) error {
+	var err error
+	if !base.IsPublic() {
+		return err
+	}
+	base_id := base.Type().(cxxtypes.Id)
+	bid := get_cxxgo_id(p.gen.Fd.Package, base_id)
+	go_cls_impl_name := "Gocxxcptr" + cid.goname
+	go_base_cls_iface_name := bid.goname
+	cn := fmt.Sprintf("_gocxx_upcast_%s_%s_%s",
+		p.gen.Fd.Package, get_iid_str(id), get_iid_str(base_id),
+	)
 
-func (p *plugin) wrapDataMember(id *cxxtypes.Member, bufs bufmap_t) (err error) {
-	fmt.Printf(":: wrapping data-member [%s]...\n", id.IdScopedName())
+	fmter(bufs["go_iface"],
+		"\tGocxxGet%s() %s\n",
+		go_base_cls_iface_name,
+		go_base_cls_iface_name)
 
-	dm_name := id.IdName()
-	dm_typename := id.Type
+	fmter(bufs["go_impl"],
+		"\nfunc (p %s) GocxxGet%s() %s {\n",
+		go_cls_impl_name,
+		go_base_cls_iface_name,
+		go_base_cls_iface_name,
+	)
+	fmter(bufs["go_impl"],
+		"return Gocxxcptr%s(C.%s(unsafe.Pointer(p)))\n}\n",
+		go_base_cls_iface_name, cn,
+	)
 
-	// declare getters and setters in the go-interface
-	fmter(bufs["go_iface"], "\tGet%s() %s\n\tSet%s(%s %s)\n",
-		strings.Title(dm_name),
-		cxx2go_typename(dm_typename),
-		strings.Title(dm_name),
-		dm_name,
-		cxx2go_typename(dm_typename),
+	fmter(bufs["go_iface"],
+		"\tGocxxIs%s()\n",
+		go_base_cls_iface_name,
+	)
+	fmter(bufs["go_impl"],
+		"\nfunc (p %s) GocxxIs%s() {\n}\n",
+		go_cls_impl_name,
+		go_base_cls_iface_name,
 	)
 
-	clsid := cxxtypes.IdByName(id.Scope)
-	if clsid == nil {
-		return fmt.Errorf("could not find parent-scope [%s] for member [%s]",
-			id.Scope, id.IdScopedName())
+	// the address of the base subobject, when passed as a base
+	fmter(bufs["go_iface"],
+		"\tGocxxPtr%s() uintptr\n",
+		go_base_cls_iface_name,
+	)
+	fmter(bufs["go_impl"],
+		"\nfunc (p %s) GocxxPtr%s() uintptr {\n\treturn p.GocxxGet%s().GocxxPtr%s()\n}\n",
+		go_cls_impl_name,
+		go_base_cls_iface_name,
+		go_base_cls_iface_name,
+		go_base_cls_iface_name,
+	)
+
+	virtual := ""
+	if base.IsVirtual() {
+		virtual = "virtual "
+	}
+	fmter(bufs["cxx_tail"],
+		"\n// upcasts [%s] to its %sbase [%s]\nvoid* %s(void *c_self)\n{\n  return static_cast< ::%s*>((::%s*)c_self);\n}\n",
+		id.IdScopedName(), virtual, base_id.IdScopedName(), cn,
+		base_id.IdScopedName(), id.IdScopedName(),
+	)
+
+	hdr := new_bufmap("cgo_head")
+	fmter(hdr["cgo_head"],
+		"\n/* upcasts [%s] to [%s] */\nvoid* %s(void *c_self);\n",
+		id.IdScopedName(), base_id.IdScopedName(), cn,
+	)
+	_, err = hdr["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	return err
+}
+
+// wrapIndirectBases gives access to the unambiguous public indirect bases of
+// the class id, through the upcasts to its direct bases, so that its
+// handles also implement the interfaces of these bases.
+func (p *plugin) wrapIndirectBases(cid *cxxgo_id, id *cxxtypes.ClassType, bufs bufmap_t) {
+	pkg := p.gen.Fd.Package
+	go_cls_impl_name := "Gocxxcptr" + cid.goname
+
+	wrapped := func(bid cxxtypes.Id) bool {
+		return str_is_in_slice(bid.IdScopedName(), p.ids) &&
+			!g_opaques[bid.IdScopedName()] && is_plain_class(bid)
+	}
+
+	// the indirect bases, in declaration order
+	direct := map[string]bool{}
+	for _, base := range id.Bases {
+		direct[base.TypeBase] = true
+	}
+	indirect := []cxxtypes.Id{}
+	seen := map[string]bool{}
+	var collect func(id cxxtypes.Id)
+	collect = func(id cxxtypes.Id) {
+		for _, base := range class_base_list(id) {
+			bid := cxxtypes.IdByName(base.TypeBase)
+			if !seen[bid.IdScopedName()] && !direct[bid.IdScopedName()] {
+				seen[bid.IdScopedName()] = true
+				indirect = append(indirect, bid)
+			}
+			collect(bid)
+		}
+	}
+	for _, base := range id.Bases {
+		collect(cxxtypes.IdByName(base.TypeBase))
+	}
+
+	for _, bid := range indirect {
+		path, ok := base_path(id, bid)
+		if !ok || !wrapped(bid) {
+			continue
+		}
+		upcasts := []string{}
+		for _, b := range path {
+			if !wrapped(b) {
+				upcasts = nil
+				break
+			}
+			upcasts = append(upcasts,
+				fmt.Sprintf("GocxxGet%s()", get_cxxgo_id(pkg, b).goname))
+		}
+		if upcasts == nil {
+			continue
+		}
+		call := "p." + strings.Join(upcasts, ".")
+		bn := get_cxxgo_id(pkg, bid).goname
+
+		fmter(bufs["go_iface"],
+			"\tGocxxGet%s() %s\n\tGocxxIs%s()\n\tGocxxPtr%s() uintptr\n",
+			bn, bn, bn, bn,
+		)
+		fmter(bufs["go_impl"],
+			"\nfunc (p %s) GocxxGet%s() %s {\n\treturn %s\n}\n",
+			go_cls_impl_name, bn, bn, call,
+		)
+		fmter(bufs["go_impl"],
+			"\nfunc (p %s) GocxxIs%s() {\n}\n",
+			go_cls_impl_name, bn,
+		)
+		fmter(bufs["go_impl"],
+			"\nfunc (p %s) GocxxPtr%s() uintptr {\n\treturn %s.GocxxPtr%s()\n}\n",
+			go_cls_impl_name, bn, call, bn,
+		)
+	}
+}
+
+func (p *plugin) wrapDataMember(id *cxxtypes.Member, bufs bufmap_t) (err error) {
+	fmt.Printf(":: wrapping data-member [%s]...\n", id.IdScopedName())
+
+	if why := unsupported_type(cxxtypes.IdByName(id.Type)); why != "" {
+		fmt.Printf(":: discarding [%s] (unsupported %s)\n", id.IdScopedName(), why)
+		return nil
+	}
+
+	dm_name := id.IdName()
+	dm_typename := cxx2go_typename(id.Type)
+	if dm_id := cxxtypes.IdByName(id.Type); dm_id != nil {
+		dm_typename = gen_go_name_from_id(dm_id)
+	}
+
+	// declare getters and setters in the go-interface
+	fmter(bufs["go_iface"], "\tGet%s() %s\n\tSet%s(%s %s)\n",
+		strings.Title(dm_name),
+		dm_typename,
+		strings.Title(dm_name),
+		dm_name,
+		dm_typename,
+	)
+
+	clsid := cxxtypes.IdByName(id.Scope)
+	if clsid == nil {
+		return fmt.Errorf("could not find parent-scope [%s] for member [%s]",
+			id.Scope, id.IdScopedName())
 	}
 	//clf := "::" + clsid.IdScopedName()
 	//clt := g_strtrans.Replace(clsid.IdScopedName())
@@ -693,8 +1315,8 @@ func (p %s) Get%s() %s {
 `,
 		go_cls_impl_name,
 		strings.Title(dm_name),
-		cxx2go_typename(dm_typename),
-		cxx2go_typename(dm_typename),
+		dm_typename,
+		dm_typename,
 	)
 
 	fmter(bufs["go_impl"],
@@ -704,7 +1326,7 @@ func (p %s) Set%s(arg %s) {
 `,
 		go_cls_impl_name,
 		strings.Title(dm_name),
-		cxx2go_typename(dm_typename),
+		dm_typename,
 	)
 	fmt.Printf(":: wrapping data-member [%s]...[ok]\n", id.IdScopedName())
 	return err
@@ -721,12 +1343,22 @@ func (p *plugin) wrapFctMember(id *cxxtypes.Member, bufs bufmap_t) error {
 		return err
 	}
 
-	cgo_ovfct := p.new_cxxgo_ovfct(ovfct)
-	if len(cgo_ovfct.fcts) > 0 {
+	for _, cgo_ovfct := range p.new_cxxgo_ovfcts(ovfct) {
 		fct := cgo_ovfct.fcts[0].f
-		if !fct.IsConstructor() &&
-			!fct.IsDestructor() &&
-			!fct.IsCopyConstructor() {
+		if fct.IsConstructor() ||
+			fct.IsDestructor() ||
+			fct.IsCopyConstructor() {
+			continue
+		}
+		if cgo_ovfct.needs_dispatch() && p.ovl_typed {
+			for i, _ := range cgo_ovfct.fcts {
+				fmter(bufs["go_iface"],
+					"\t%s\n",
+					cgo_ovfct.fcts[i].go_prototype(),
+				)
+			}
+		}
+		if !cgo_ovfct.needs_dispatch() || p.ovl_dispatch {
 			fmter(bufs["go_iface"],
 				"\t%s\n",
 				cgo_ovfct.go_prototype(),
@@ -743,7 +1375,6 @@ func (p *plugin) wrapFctMember(id *cxxtypes.Member, bufs bufmap_t) error {
 
 func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) error {
 	fmt.Printf(":: wrapping fct [%s]...\n", id.IdScopedName())
-	ovfct := id
 	var err error = nil
 	if cid.wrapped {
 		fmt.Printf(":: wrapping fct [%s]... [already wrapped]\n",
@@ -751,6 +1382,22 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		return err
 	}
 
+	for _, cgo_ovfct := range p.new_cxxgo_ovfcts(id) {
+		err = p.wrapOverloads(cid, cgo_ovfct)
+		if err != nil {
+			return err
+		}
+	}
+
+	cid.wrapped = true
+	fmt.Printf(":: wrapping fct [%s]...[ok]\n", id.IdScopedName())
+	return nil
+}
+
+// wrapOverloads wraps the overloads of a C++ function which share the same
+// Go name.
+func (p *plugin) wrapOverloads(cid *cxxgo_id, cgo_ovfct *cxxgo_overload_fct_set_t) error {
+	var err error = nil
 	pkg := p.gen.Fd.Package
 
 	bufs := new_bufmap(
@@ -762,8 +1409,7 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		"go_impl",
 	)
 
-	cgo_ovfct := p.new_cxxgo_ovfct(ovfct)
-	needs_dispatch := len(cgo_ovfct.fcts) > 1
+	needs_dispatch := cgo_ovfct.needs_dispatch() && p.ovl_dispatch
 	// the table to regroup cgo-functions by number of args
 	dispatch_table := map[int][]*cxxgo_function{}
 	go_receiver := ""
@@ -773,6 +1419,7 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		cfct := cgo_ovfct.fcts[ifct]
 		fct := cfct.f
 		nargs := fct.NumParam()
+		nreq := cfct.nreq()
 
 		// // discard private function-member
 		// if fct.IsPrivate() {
@@ -782,12 +1429,12 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		go_ret_type := ""
 		cid_args := make([]*cxxgo_id, 0, nargs)
 
-		if _, ok := dispatch_table[nargs]; !ok {
-			dispatch_table[nargs] = []*cxxgo_function{&cfct}
-		} else {
-			dispatch_table[nargs] = append(
-				dispatch_table[nargs],
-				&cfct)
+		// optional arguments may be passed positionally to the dispatcher
+		// (T*, length) pairs are passed as a single Go slice
+		// output parameters are returned as Go results
+		for n := nreq; n <= nargs; n++ {
+			ngo := n - cfct.nview() - cfct.nout()
+			dispatch_table[ngo] = append(dispatch_table[ngo], &cfct)
 		}
 
 		for i, _ := range fct.Params {
@@ -802,6 +1449,14 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 			go_receiver = fmt.Sprintf("(p Gocxxcptr%s)",
 				cid_scope.goname,
 			)
+			if cgo_ovfct.protected {
+				go_receiver = fmt.Sprintf("(p gocxxcptr%sProtected)",
+					cid_scope.goname,
+				)
+			}
+		}
+		if nreq < nargs {
+			fmter(bufs["go_impl"], "%s", cfct.go_opts_decl())
 		}
 		fmter(bufs["go_impl"],
 			"\n// wraps [%s]\nfunc %s%s {\n",
@@ -809,6 +1464,12 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 			go_receiver,
 			cfct.go_prototype(),
 		)
+		if nreq < nargs {
+			fmter(bufs["go_impl"],
+				"\tvar opt %sOpts\n\tfor _, o := range opts {\n\t\to(&opt)\n\t}\n",
+				cfct.go_opts_name(),
+			)
+		}
 
 		// CGo decl.
 		cgo_in := []string{}
@@ -832,7 +1493,8 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 			cid_scope := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.BaseId.Scope))
 			if fct.IsDestructor() {
 				fmter(bufs["go_impl"],
-					"\tc_this := unsafe.Pointer(arg.Gocxxcptr())\n",
+					"\tc_this := unsafe.Pointer(%s)\n",
+					go_cptr(pkg, cid_scope.id, "arg"),
 				)
 			} else if fct.IsConstructor() {
 				fmter(bufs["go_impl"],
@@ -842,6 +1504,11 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 				fmter(bufs["go_impl"],
 					"\tc_this := unsafe.Pointer(&c_ptr)\n",
 				)
+			} else if cgo_ovfct.protected {
+				fmter(bufs["go_impl"],
+					"\tc_this := unsafe.Pointer(p.Gocxxcptr%s)\n",
+					cid_scope.goname,
+				)
 			} else {
 				fmter(bufs["go_impl"],
 					"\tc_this := unsafe.Pointer(p)\n",
@@ -866,51 +1533,187 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 
 		for i, _ := range fct.Params {
 			cid_arg := cid_args[i]
-			if cid_arg.is_string_like() {
-				fmter(bufs["go_impl"],
-					"\tc_arg_%d := C.CString(arg_%d)\n", i, i)
-				fmter(bufs["go_impl"],
-					"\tdefer C.free(unsafe.Pointer(c_arg_%d))\n", i)
-				cgo_in = append(cgo_in,
-					fmt.Sprintf("unsafe.Pointer(c_arg_%d)", i))
-			} else if cid_arg.is_cstring_like() {
-				fmter(bufs["go_impl"],
-					"\tc_arg_%d := C.CString(arg_%d)\n", i, i)
-				fmter(bufs["go_impl"],
-					"\tdefer C.free(unsafe.Pointer(c_arg_%d))\n", i)
-				cgo_in = append(cgo_in,
-					fmt.Sprintf("unsafe.Pointer(c_arg_%d)", i))
+			// optional arguments are converted only when set.
+			// otherwise, a nil pointer is passed to the C++ wrapper
+			// which then uses the default value.
+			buf := bufs["go_impl"]
+			if i >= nreq {
+				buf = new(bytes.Buffer)
+			}
+			c_in := ""
+			if cfct.is_view_ptr(i) {
+				// the C++ function works on the storage of the Go slice
+				fmter(buf,
+					"\tvar c_arg_%d unsafe.Pointer\n\tif len(arg_%d) > 0 {\n\t\tc_arg_%d = unsafe.Pointer(&arg_%d[0])\n\t}\n",
+					i, i, i, i,
+				)
+				c_in = fmt.Sprintf("c_arg_%d", i)
+			} else if cfct.is_view_len(i) {
+				fmter(buf,
+					"\tc_arg_%d := %s(len(arg_%d))\n",
+					i, cid_arg.cgoname, i-1,
+				)
+				c_in = fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i)
+			} else if cfct.is_out(i) {
+				// the C++ function writes into the Go variable, returned
+				// as a Go result
+				if cfct.dir(i) == "out" {
+					fmter(buf,
+						"\tvar arg_%d %s\n",
+						i, cfct.go_param_name(i),
+					)
+				}
+				fmter(buf,
+					"\tc_arg_%d := unsafe.Pointer(&arg_%d)\n",
+					i, i,
+				)
+				c_in = fmt.Sprintf("c_arg_%d", i)
+			} else if c, indirect, cst := strip_container(cid_arg.id); c != nil && c.cvt {
+				// STL container: converted into a temporary C++ one.
+				cn := "C." + c.cname(pkg)
+				if indirect {
+					fmter(buf,
+						"\tvar c_arg_%d unsafe.Pointer\n\tif arg_%d != nil {\n",
+						i, i,
+					)
+					fmter(buf,
+						"\t\tc_arg_%d = %s(*arg_%d)\n\t\tdefer %s_delete(c_arg_%d)\n",
+						i, c.go_helper("from_go"), i, cn, i,
+					)
+					if !cst {
+						// runs before the deletion of the C++ container
+						fmter(buf,
+							"\t\tdefer func() { *arg_%d = %s(c_arg_%d) }()\n",
+							i, c.go_helper("to_go"), i,
+						)
+					}
+					fmter(buf, "\t}\n")
+				} else {
+					fmter(buf,
+						"\tc_arg_%d := %s(arg_%d)\n\tdefer %s_delete(c_arg_%d)\n",
+						i, c.go_helper("from_go"), i, cn, i,
+					)
+				}
+				c_in = fmt.Sprintf("c_arg_%d", i)
+			} else if str := get_cxxgo_string(cid_arg.id); str != nil {
+				// strings are passed as a C copy, and copied back into
+				// the Go string for output parameters.
+				if str.indirect() {
+					fmter(buf,
+						"\tvar c_arg_%d C._gocxx_strview\n\tc_ptr_%d := unsafe.Pointer(nil)\n\tif arg_%d != nil {\n",
+						i, i, i,
+					)
+					fmter(buf,
+						"\t\tc_arg_%d = %s\n\t\tdefer C.free(unsafe.Pointer(c_arg_%d.p))\n",
+						i, str.go_from_go(fmt.Sprintf("*arg_%d", i)), i,
+					)
+					fmter(buf,
+						"\t\tc_ptr_%d = unsafe.Pointer(&c_arg_%d)\n\t\tdefer func() { *arg_%d = %s }()\n\t}\n",
+						i, i, i, str.go_to_go(fmt.Sprintf("c_arg_%d", i)),
+					)
+					c_in = fmt.Sprintf("c_ptr_%d", i)
+				} else {
+					fmter(buf,
+						"\tc_arg_%d := %s\n\tdefer C.free(unsafe.Pointer(c_arg_%d.p))\n",
+						i, str.go_from_go(fmt.Sprintf("arg_%d", i)), i,
+					)
+					c_in = fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i)
+				}
+			} else if sp, ref, cst := strip_smartptr(cid_arg.id); sp != nil {
+				// the C++ wrapper refers to the smart pointer held by the handle
+				fmter(buf,
+					"\tc_arg_%d := unsafe.Pointer(nil)\n\tif arg_%d != nil {\n\t\tc_arg_%d = arg_%d.c\n",
+					i, i, i, i,
+				)
+				switch {
+				case sp.kind == "unique" && !ref:
+					fmter(buf,
+						"\t\t// the ownership of the object is transferred to C++\n\t\tdefer arg_%d.Close()\n",
+						i,
+					)
+				case ref && !cst:
+					// the C++ function may reset the smart pointer
+					fmter(buf,
+						"\t\tdefer func() { arg_%d.%s = Gocxxcptr%s(C.%s_get(c_arg_%d)) }()\n",
+						i, sp.elt_goname(), sp.elt_goname(), sp.cname(pkg), i,
+					)
+				}
+				fmter(buf, "\t}\n")
+				c_in = fmt.Sprintf("c_arg_%d", i)
+			} else if st := strip_stream(cid_arg.id); st != nil {
+				// the C++ stream calls the Go writer/reader back
+				fmter(buf,
+					"\tc_arg_%d := %s(arg_%d)\n",
+					i, st.go_helper(), i,
+				)
+				c_in = fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i)
+			} else if cb := strip_callback(cid_arg.id); cb != nil {
+				// the Go func is called back through a std::function
+				fmter(buf,
+					"\tc_arg_%d := %s(arg_%d)\n",
+					i, cb.go_helper(), i,
+				)
+				c_in = fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i)
+			} else if is_move_param(cid_arg.id) {
+				// the C++ object is moved from: the Go handle is
+				// invalidated. (the object is not destroyed, as it may not
+				// be owned by the caller)
+				fmter(buf,
+					"\tc_arg_%d := unsafe.Pointer(%s)\n\tdefer func() { *arg_%d = nil }()\n",
+					i, go_cptr(pkg, cid_arg.id, fmt.Sprintf("(*arg_%d)", i)), i,
+				)
+				c_in = fmt.Sprintf("c_arg_%d", i)
 			} else if cid_arg.is_class_like() {
-				fmter(bufs["go_impl"],
-					"\tc_arg_%d := unsafe.Pointer(arg_%d.Gocxxcptr())\n",
-					i,
+				fmter(buf,
+					"\tc_arg_%d := unsafe.Pointer(%s)\n",
 					i,
+					go_cptr(pkg, cid_arg.id, fmt.Sprintf("arg_%d", i)),
 				)
-				cgo_in = append(cgo_in,
-					fmt.Sprintf("c_arg_%d", i))
+				c_in = fmt.Sprintf("c_arg_%d", i)
+			} else if cid_arg.goname == "unsafe.Pointer" {
+				// void*: passed as such
+				fmter(buf, "\tc_arg_%d := arg_%d\n", i, i)
+				c_in = fmt.Sprintf("c_arg_%d", i)
 			} else if cid_arg.is_pointer_like() {
-				fmter(bufs["go_impl"],
+				fmter(buf,
 					"\tc_arg_%d := unsafe.Pointer(&arg_%d)\n",
 					i, i,
 				)
-				cgo_in = append(cgo_in,
-					fmt.Sprintf("c_arg_%d", i))
+				c_in = fmt.Sprintf("c_arg_%d", i)
 
 			} else {
-				fmter(bufs["go_impl"],
+				fmter(buf,
 					"\tc_arg_%d := %s(arg_%d)\n",
 					i,
 					cid_arg.cgoname,
 					i,
 				)
-				cgo_in = append(cgo_in,
-					fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i))
+				c_in = fmt.Sprintf("unsafe.Pointer(&c_arg_%d)", i)
 			}
+			if i >= nreq {
+				fmter(bufs["go_impl"],
+					"\tvar c_opt_%d unsafe.Pointer\n\tif opt.arg_%d != nil {\n\t\targ_%d := *opt.arg_%d\n",
+					i, i, i, i,
+				)
+				fmter(bufs["go_impl"], "%s",
+					strings.Replace(buf.String(), "\t", "\t\t", -1))
+				fmter(bufs["go_impl"], "\t\tc_opt_%d = %s\n\t}\n", i, c_in)
+				c_in = fmt.Sprintf("c_opt_%d", i)
+			}
+			cgo_in = append(cgo_in, c_in)
 		}
 
+		// closes the expression started in cxx_body for the return value
+		cxx_ret_close := ""
 		if go_ret_type != "" {
 			cid_ret := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.Ret))
 			cxx_type := cid_ret.id.IdScopedName()
+			ret_cnt, _, ret_cst := strip_container(cid_ret.id)
+			ret_str := get_cxxgo_string(cid_ret.id)
+			ret_sp, ret_ref, _ := strip_smartptr(cid_ret.id)
+			ret_hdl := cid_ret.is_class_like() && cid_ret.is_pointer_like() &&
+				!strings.HasSuffix(cxx_type, ":*")
+			ret_val := class_by_value(cid_ret.id)
 			// drop const-qualifier...
 			if idt, ok := cid_ret.id.(cxxtypes.Type); ok && (idt.Qualifiers()&cxxtypes.TQ_Const) != 0 {
 				//noconst_id := cid_ret.id
@@ -921,39 +1724,154 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 				//cxx_type = strings.Replace(cxx_type, " const", "", 1)
 				//println("<=== const:", cxx_type, "[[", cfct.goname, "]]")
 			}
-			if strings.HasSuffix(cxx_type, "*") {
+			if ret_cnt != nil {
+				// STL container: the C++ wrapper returns a pointer to it
+				fmter(bufs["go_impl"], "\tvar c_ret unsafe.Pointer\n")
+				cxx_ret_close = ")"
+				switch {
+				case strings.HasSuffix(cxx_type, "*"):
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)(")
+				case strings.HasSuffix(cxx_type, "&"):
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)&(")
+				default:
+					// a copy, owned by the Go side
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)new %s(",
+						ret_cnt.id.IdScopedName())
+				}
+				switch {
+				case cfct.view_ret() != nil:
+					if strings.HasSuffix(cxx_type, "*") {
+						cgo_out = append(cgo_out,
+							"\tif c_ret == nil {\n\t\treturn nil\n\t}\n",
+						)
+					}
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\treturn %s(c_ret)\n", ret_cnt.go_helper("view")),
+					)
+				case !ret_cnt.cvt:
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\treturn Gocxxcptr%s(uintptr(c_ret))\n", ret_cnt.goname()),
+					)
+				case ret_cnt.class == "optional":
+					// a missing value (or a nil pointer) is reported as
+					// not ok
+					if strings.HasSuffix(cxx_type, "*") {
+						cgo_out = append(cgo_out,
+							fmt.Sprintf("\tif c_ret == nil {\n\t\treturn *new(%s), false\n\t}\n", ret_cnt.elt_goname(0)),
+						)
+					}
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\tgo_ret, ok := %s(c_ret)\n", ret_cnt.go_helper("to_go")),
+					)
+					if !strings.HasSuffix(cxx_type, "*") && !strings.HasSuffix(cxx_type, "&") {
+						cgo_out = append(cgo_out,
+							fmt.Sprintf("\tC.%s_delete(c_ret)\n", ret_cnt.cname(pkg)),
+						)
+					}
+					cgo_out = append(cgo_out, "\treturn go_ret, ok\n")
+				case strings.HasSuffix(cxx_type, "*"):
+					cgo_out = append(cgo_out,
+						"\tif c_ret == nil {\n\t\treturn nil\n\t}\n",
+						fmt.Sprintf("\tgo_ret := %s(c_ret)\n", ret_cnt.go_helper("to_go")),
+						"\treturn &go_ret\n",
+					)
+				case strings.HasSuffix(cxx_type, "&"):
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\tgo_ret := %s(c_ret)\n", ret_cnt.go_helper("to_go")),
+					)
+					if ret_cst {
+						cgo_out = append(cgo_out, "\treturn go_ret\n")
+					} else {
+						cgo_out = append(cgo_out, "\treturn &go_ret\n")
+					}
+				default:
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\tgo_ret := %s(c_ret)\n", ret_cnt.go_helper("to_go")),
+						fmt.Sprintf("\tC.%s_delete(c_ret)\n", ret_cnt.cname(pkg)),
+						"\treturn go_ret\n",
+					)
+				}
+			} else if ret_str != nil {
+				// strings are returned as a C copy, owned by the Go side.
+				// (NULL pointers are returned as empty strings)
+				fmter(bufs["go_impl"], "\tvar c_ret C._gocxx_strview\n")
+				fmter(bufs["cxx_body"], "  %s", ret_str.cxx_ret_decl())
+				data, size := ret_str.cxx_data("cxx_ret")
+				fmter(bufs["cxx_tail"],
+					"  _gocxx_strview_set((_gocxx_strview*)c_ret, %s, %s);\n",
+					data, size,
+				)
+				cgo_out = append(cgo_out,
+					fmt.Sprintf("\treturn %s\n", ret_str.go_to_go("c_ret")),
+				)
+			} else if ret_sp != nil {
+				spn := ret_sp.id.IdScopedName()
+				fmter(bufs["go_impl"], "\tvar c_ret unsafe.Pointer\n")
+				switch {
+				case ret_sp.kind == "unique" && ret_ref:
+					// the object is still owned by the C++ unique_ptr
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)(")
+					cxx_ret_close = ").get()"
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\treturn Gocxxcptr%s(uintptr(c_ret))\n", ret_sp.elt_goname()),
+					)
+				default:
+					// a copy (shared) or the object itself (unique) is
+					// handed over to the Go handle
+					if ret_ref {
+						fmter(bufs["cxx_body"], "  const %s& cxx_ret = ", spn)
+					} else {
+						fmter(bufs["cxx_body"], "  %s cxx_ret = ", spn)
+					}
+					cxx_ret_close = fmt.Sprintf("; *(void**)c_ret = %s_take((void*)&cxx_ret)",
+						ret_sp.cname(pkg))
+					cgo_out = append(cgo_out,
+						fmt.Sprintf("\treturn %s(c_ret)\n", ret_sp.go_helper()),
+					)
+				}
+			} else if ret_hdl {
+				// pointer or reference to a class: the Go handle holds
+				// the address of the C++ object
+				fmter(bufs["go_impl"], "\tvar c_ret unsafe.Pointer\n")
+				if strings.HasSuffix(cxx_type, "*") {
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)(")
+					cgo_out = append(cgo_out,
+						"\tif c_ret == nil {\n\t\treturn nil\n\t}\n",
+					)
+				} else {
+					fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)&(")
+				}
+				cxx_ret_close = ")"
+				cgo_out = append(cgo_out,
+					fmt.Sprintf("\treturn Gocxxcptr%s(c_ret)\n",
+						gen_go_name_from_id(class_of(cid_ret.id))),
+				)
+			} else if ret_val != nil {
+				// class returned by value: moved (or copied) into a new
+				// C++ object, owned by the Go side
+				fmter(bufs["go_impl"], "\tvar c_ret unsafe.Pointer\n")
+				fmter(bufs["cxx_body"], "  *(void**)c_ret = (void*)new %s(",
+					ret_val.IdScopedName())
+				cxx_ret_close = ")"
+				cgo_out = append(cgo_out,
+					fmt.Sprintf("\treturn Gocxxcptr%s(c_ret)\n",
+						gen_go_name_from_id(ret_val)),
+				)
+			} else if strings.HasSuffix(cxx_type, "*") {
 				// pointer to data member
 				if strings.HasSuffix(cxx_type, ":*") {
 					fmter(bufs["cxx_head"],
 						"  %s* cxx_ret = (%s*)(c_ret);\n",
 						cxx_type, cxx_type,
 					)
-				} else if cid_ret.is_string_like() {
+				} else if cid_ret.goname == "unsafe.Pointer" {
+					// void*: returned as such
 					fmter(bufs["cxx_head"],
-						"  %s cxx_ret( ((_gostring_*)c_ret)->p, ((_gostring_*)c_ret)->n);\n",
-						cxx_type[:len(cxx_type)-1],
-					)
-					fmter(bufs["go_impl"],
-						"\tc_ret := C.CString(\"\")\n")
-					fmter(bufs["go_impl"],
-						"\tdefer C.free(unsafe.Pointer(c_ret))\n")
-					cgo_out = append(cgo_out,
-						"\tgo_ret := C.GoString(c_ret)\n",
-						"\treturn go_ret\n",
-						)
-				} else if cid_ret.is_cstring_like() {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_ret = (%s*)c_ret;\n",
+						"  %s* cxx_ret = (%s*)(c_ret);\n",
 						cxx_type, cxx_type,
 					)
-					fmter(bufs["go_impl"],
-						"\tc_ret := C.CString(\"\")\n")
-					fmter(bufs["go_impl"],
-						"\tdefer C.free(unsafe.Pointer(c_ret))\n")
-					cgo_out = append(cgo_out,
-						"\tgo_ret := C.GoString(c_ret)\n",
-						"\treturn go_ret\n",
-					)
+					fmter(bufs["go_impl"], "\tvar c_ret unsafe.Pointer\n")
+					cgo_out = append(cgo_out, "\treturn c_ret\n")
 				} else {
 					fmter(bufs["cxx_head"],
 						"  %s* cxx_ret = (%s*)(&c_ret);\n",
@@ -976,121 +1894,57 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 					}
 				}
 			} else if strings.HasSuffix(cxx_type, "&") {
-				if cid_ret.is_string_like() {
-					fmter(bufs["cxx_head"],
-						"  %s cxx_ret_storage; %s* cxx_ret = &cxx_ret_storage;\n",
-						cxx_type[:len(cxx_type)-1],
-						cxx_type[:len(cxx_type)-1],
-					)
-					fmter(bufs["cxx_tail"],
-						"  *(_gostring_*)c_ret = _gocxx_makegostring(cxx_ret->c_str(), cxx_ret->size());\n",
-						)
-					fmter(bufs["go_impl"],
-						"\tvar c_ret string\n//\tc_ret := unsafe.Pointer(&c_ret_storage)\n")
-					fmter(bufs["go_impl"],
-						"\t//defer C.free(unsafe.Pointer(c_ret_storage))\n")
+				fmter(bufs["cxx_head"],
+					"  %s* cxx_ret = *(%s**)(&c_ret);\n",
+					cxx_type[:len(cxx_type)-1],
+					cxx_type[:len(cxx_type)-1],
+				)
+				fmter(bufs["go_impl"],
+					"\tvar c_ret %s\n",
+					cid_ret.cgoname,
+				)
+				if fct.IsAssignOperator() || cid_ret.is_class_like() {
 					cgo_out = append(cgo_out,
-						"\tgo_ret := c_ret\n",
+						fmt.Sprintf("\tgo_ret := Gocxxcptr%s(c_ret)\n", cid_ret.goname),
 						"\treturn go_ret\n",
 					)
-				} else if cid_ret.is_cstring_like() {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_ret = *(%s**)(&c_ret);\n",
-						cxx_type[:len(cxx_type)-1],
-						cxx_type[:len(cxx_type)-1],
-					)
-
-					fmter(bufs["go_impl"],
-						"\tc_ret := C.CString(\"\")\n")
-					fmter(bufs["go_impl"],
-						"\tdefer C.free(unsafe.Pointer(c_ret))\n")
+				} else {
 					cgo_out = append(cgo_out,
-						"\tgo_ret := C.GoString(c_ret)\n",
+						fmt.Sprintf("\tgo_ret := %s(c_ret)\n", cid_ret.goname),
 						"\treturn go_ret\n",
-						)
-
-				} else {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_ret = *(%s**)(&c_ret);\n",
-						cxx_type[:len(cxx_type)-1],
-						cxx_type[:len(cxx_type)-1],
-					)
-					fmter(bufs["go_impl"],
-						"\tvar c_ret %s\n",
-						cid_ret.cgoname,
 					)
-					if fct.IsAssignOperator() || cid_ret.is_class_like() {
-						cgo_out = append(cgo_out,
-							fmt.Sprintf("\tgo_ret := Gocxxcptr%s(c_ret)\n", cid_ret.goname),
-							"\treturn go_ret\n",
-						)
-					} else {
-						cgo_out = append(cgo_out,
-							fmt.Sprintf("\tgo_ret := %s(c_ret)\n", cid_ret.goname),
-							"\treturn go_ret\n",
-						)
-					}
 				}
 			} else {
-				if cid_ret.is_string_like() {
-					fmter(bufs["cxx_head"],
-						"  %s cxx_ret( ((_gostring_*)c_ret)->p, ((_gostring_*)c_ret)->n);\n",
-						cxx_type[:len(cxx_type)-1],
-					)
-					fmter(bufs["go_impl"],
-						"\tc_ret := C.CString(\"\")\n")
-					fmter(bufs["go_impl"],
-						"\tdefer C.free(unsafe.Pointer(c_ret))\n")
-					cgo_out = append(cgo_out,
-						"\tgo_ret := C.GoString(c_ret)\n",
-						"\treturn go_ret\n",
-						)
-
-				} else if cid_ret.is_cstring_like() {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_ret = (%s*)c_ret;\n",
-						cxx_type, cxx_type,
-					)
-
-					fmter(bufs["go_impl"],
-						"\tc_ret := C.CString(\"\")\n")
-					fmter(bufs["go_impl"],
-						"\tdefer C.free(unsafe.Pointer(c_ret))\n")
+				fmter(bufs["cxx_head"],
+					"  %s* cxx_ret = (%s*)c_ret;\n",
+					cxx_type, cxx_type,
+				)
+				fmter(bufs["go_impl"],
+					"\tvar c_ret %s\n",
+					cid_ret.cgoname,
+				)
+				if fct.IsAssignOperator() || cid_ret.is_class_like() {
 					cgo_out = append(cgo_out,
-						"\tgo_ret := C.GoString(c_ret)\n",
+						fmt.Sprintf("\tgo_ret := Gocxxcptr%s(c_ret)\n", cid_ret.goname),
 						"\treturn go_ret\n",
 					)
-
 				} else {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_ret = (%s*)c_ret;\n",
-						cxx_type, cxx_type,
-					)
-					fmter(bufs["go_impl"],
-						"\tvar c_ret %s\n",
-						cid_ret.cgoname,
-					)
-					if fct.IsAssignOperator() || cid_ret.is_class_like() {
+					if is_bool(cid_ret.id) {
 						cgo_out = append(cgo_out,
-							fmt.Sprintf("\tgo_ret := Gocxxcptr%s(c_ret)\n", cid_ret.goname),
+							fmt.Sprintf("\tgo_ret := %s(_gocxx_int2bool(c_ret))\n", cid_ret.goname),
 							"\treturn go_ret\n",
 						)
 					} else {
-						if cid_ret.goname == "bool" {
-							cgo_out = append(cgo_out,
-								fmt.Sprintf("\tgo_ret := %s(_gocxx_int2bool(c_ret))\n", cid_ret.goname),
-								"\treturn go_ret\n",
-							)
-						} else {
-							cgo_out = append(cgo_out,
-								fmt.Sprintf("\tgo_ret := %s(c_ret)\n", cid_ret.goname),
-								"\treturn go_ret\n",
-							)
-						}
+						cgo_out = append(cgo_out,
+							fmt.Sprintf("\tgo_ret := %s(c_ret)\n", cid_ret.goname),
+							"\treturn go_ret\n",
+						)
 					}
 				}
 			}
-			fmter(bufs["cxx_body"], "  (*cxx_ret) = (%s)", cxx_type)
+			if ret_cnt == nil && ret_str == nil && ret_sp == nil && !ret_hdl && ret_val == nil {
+				fmter(bufs["cxx_body"], "  (*cxx_ret) = (%s)", cxx_type)
+			}
 		} else {
 			fmter(bufs["cxx_body"], "  ")
 		}
@@ -1108,20 +1962,28 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 					"delete cxx_this; cxx_this = NULL;\n")
 
 			} else {
+				cxx_this := cid_scope.id.IdScopedName()
+				if cgo_ovfct.protected {
+					cxx_this = protected_accessor_name(pkg, cid_scope.id)
+				}
 				fmter(bufs["cxx_head"],
 					"  %s *cxx_this = (%s*)(c_this);\n",
-					cid_scope.id.IdScopedName(),
-					cid_scope.id.IdScopedName(),
+					cxx_this,
+					cxx_this,
 				)
 				fmter(bufs["cxx_body"], "cxx_this->")
 			}
 		}
 
+		go_results := cfct.with_outs(cgo_out)
+		if cfct.status != nil {
+			go_results = cfct.with_status(cgo_out)
+		}
 		fmter(bufs["go_impl"],
 			"\t%s(%s)\n%s",
 			cfct.cgoname,
 			strings.Join(cgo_in, ", "),
-			strings.Join(cgo_out, ""),
+			strings.Join(go_results, ""),
 		)
 		fmter(bufs["go_impl"], "}\n")
 
@@ -1129,76 +1991,125 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		for i, _ := range fct.Params {
 			cid_arg := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.Params[i].Type))
 			cxx_type := cid_arg.id.IdScopedName()
-			if strings.HasSuffix(cxx_type, "*") ||
-				strings.HasSuffix(cxx_type, "* const") {
-				// pointer to data member
-				if strings.HasSuffix(cxx_type, ":*") ||
-					strings.HasSuffix(cxx_type, ":* const") {
+			if str := get_cxxgo_string(cid_arg.id); str != nil {
+				v := fmt.Sprintf("(*(const _gocxx_strview*)c_arg_%d)", i)
+				if i < nreq && !str.indirect() {
 					fmter(bufs["cxx_head"],
-						"  %s* cxx_arg_%d = (%s*)(c_arg_%d);\n",
-						cxx_type, i, cxx_type, i,
+						"  %s cxx_arg_%d = %s;\n",
+						str.cxx, i, str.cxx_init(v),
 					)
-					cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
-				} else if cid_arg.is_string_like() {
-					fmter(bufs["cxx_head"],
-						"  %s cxx_arg_%d( ((_gostring_*)c_arg_%d)->p, ((_gostring_*)c_arg_%d)->n);\n",
-						cxx_type[:len(cxx_type)-1], i, i, i,
-					)
-					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
-				} else if cid_arg.is_cstring_like() {
-					fmter(bufs["cxx_head"],
-						"  %s cxx_arg_%d = (%s)c_arg_%d;\n",
-						cxx_type, i, cxx_type, i,
-					)
-					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
 				} else {
+					// optional argument or nil Go *string
 					fmter(bufs["cxx_head"],
-						"  %s cxx_arg_%d = (%s)(c_arg_%d);\n",
-						cxx_type, i, cxx_type, i,
+						"  %s cxx_arg_%d = %s; if (c_arg_%d) { cxx_arg_%d = %s; }\n",
+						str.cxx, i, str.cxx_init(""), i, i, str.cxx_init(v),
 					)
+				}
+				switch {
+				case str.ptr && str.indirect():
+					cxx_in = append(cxx_in, fmt.Sprintf("(c_arg_%d ? &cxx_arg_%d : NULL)", i, i))
+				case str.ptr:
+					cxx_in = append(cxx_in, fmt.Sprintf("&cxx_arg_%d", i))
+				default:
 					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
 				}
-			} else if strings.HasSuffix(cxx_type, "&") {
-				if cid_arg.is_string_like() {
-					fmter(bufs["cxx_head"],
-						"  %s cxx_arg_%d((const char*)c_arg_%d);\n",
-						cxx_type[:len(cxx_type)-1], i, i,
+				if str.indirect() {
+					data, size := str.value().cxx_data(fmt.Sprintf("cxx_arg_%d", i))
+					fmter(bufs["cxx_tail"],
+						"  if (c_arg_%d) { _gocxx_strview_set((_gocxx_strview*)c_arg_%d, %s, %s); }\n",
+						i, i, data, size,
 					)
+				}
+			} else if sp, ref, _ := strip_smartptr(cid_arg.id); sp != nil {
+				fmter(bufs["cxx_head"], "  %s\n",
+					sp.cxx_arg(fmt.Sprintf("cxx_arg_%d", i), fmt.Sprintf("c_arg_%d", i)),
+				)
+				if sp.kind == "unique" && !ref {
+					cxx_in = append(cxx_in, fmt.Sprintf("std::move(cxx_arg_%d)", i))
+				} else {
 					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
+				}
+			} else if st := strip_stream(cid_arg.id); st != nil {
+				// a nil Go writer/reader gives a stream w/o buffer, on
+				// which all operations fail.
+				fmter(bufs["cxx_head"],
+					"  %s cxx_buf_%d(c_arg_%d); std::%s cxx_arg_%d(cxx_buf_%d.get());\n",
+					st.cxx_buf(), i, i, st.kind, i, i,
+				)
+				if st.ptr {
+					cxx_in = append(cxx_in, fmt.Sprintf("(cxx_buf_%d.get() ? &cxx_arg_%d : NULL)", i, i))
 				} else {
-					fmter(bufs["cxx_head"],
-						"  %s* cxx_arg_%d = *(%s**)(&c_arg_%d);\n",
-						cxx_type[:len(cxx_type)-1], i,
-						cxx_type[:len(cxx_type)-1], i,
-					)
-					cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
+					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
 				}
-			} else {
-				if cid_arg.is_string_like() {
+			} else if cb := strip_callback(cid_arg.id); cb != nil {
+				fmter(bufs["cxx_head"],
+					"  %s cxx_arg_%d; if (c_arg_%d) { %s_from_go((void*)&cxx_arg_%d, c_arg_%d); }\n",
+					cb.id.IdScopedName(), i, i, cb.cname(pkg), i, i,
+				)
+				cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
+			} else if strings.HasSuffix(cxx_type, "*") ||
+				strings.HasSuffix(cxx_type, "* const") {
+				// pointer to data member
+				if strings.HasSuffix(cxx_type, ":*") ||
+					strings.HasSuffix(cxx_type, ":* const") {
 					fmter(bufs["cxx_head"],
-						"  %s cxx_arg_%d( ((_gostring_*)c_arg_%d)->p, ((_gostring_*)c_arg_%d)->n);\n",
-						cxx_type[:len(cxx_type)-1], i, i, i,
+						"  %s* cxx_arg_%d = (%s*)(c_arg_%d);\n",
+						cxx_type, i, cxx_type, i,
 					)
-					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
+					cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
 				} else {
 					fmter(bufs["cxx_head"],
-						"  %s* cxx_arg_%d = (%s*)c_arg_%d;\n",
+						"  %s cxx_arg_%d = (%s)(c_arg_%d);\n",
 						cxx_type, i, cxx_type, i,
 					)
-					cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
+					cxx_in = append(cxx_in, fmt.Sprintf("cxx_arg_%d", i))
 				}
+			} else if strings.HasSuffix(cxx_type, "&") {
+				// (lvalue or rvalue) reference
+				fmter(bufs["cxx_head"],
+					"  %s* cxx_arg_%d = *(%s**)(&c_arg_%d);\n",
+					strings.TrimRight(cxx_type, "&"), i,
+					strings.TrimRight(cxx_type, "&"), i,
+				)
+				cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
+			} else {
+				fmter(bufs["cxx_head"],
+					"  %s* cxx_arg_%d = (%s*)c_arg_%d;\n",
+					cxx_type, i, cxx_type, i,
+				)
+				cxx_in = append(cxx_in, fmt.Sprintf("*cxx_arg_%d", i))
+			}
+			if t, ok := cid_arg.id.(cxxtypes.Type); (ok && t.TypeKind() == cxxtypes.TK_RValueRef || is_move_param(cid_arg.id)) &&
+				!strings.HasPrefix(cxx_in[i], "std::move(") {
+				cxx_in[i] = fmt.Sprintf("std::move(%s)", cxx_in[i])
+			}
+			if i >= nreq {
+				// optional argument: use the default value when not set.
+				cxx_in[i] = fmt.Sprintf("(c_arg_%d ? (%s) : (%s))",
+					i, cxx_in[i], fct.Param(i).DefaultValue())
 			}
 		}
 		if !fct.IsDestructor() {
 			call := cid.id.IdName()
+			if !fct.IsMethod() && decl_scope(&fct) != "" {
+				// free function in a namespace
+				call = cid.id.IdScopedName()
+			}
 			if fct.IsConstructor() {
 				cid_scope := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.BaseId.Scope))
 				call = cid_scope.id.IdScopedName()
 			}
+			if cgo_ovfct.protected {
+				// the implementation of the class, not the Go override
+				// of the extension class (see wrapProtected)
+				cid_scope := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.BaseId.Scope))
+				call = protected_accessor_name(pkg, cid_scope.id) + "::" + call
+			}
 			fmter(bufs["cxx_body"],
-				"%s(%s);\n",
+				"%s(%s)%s;\n",
 				call,
 				strings.Join(cxx_in, ", "),
+				cxx_ret_close,
 			)
 		} else {
 			// noop.
@@ -1238,11 +2149,9 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 	}
 
 	if needs_dispatch {
-		go_ret := cgo_ovfct.fcts[0].f.Ret
-		if go_ret != "" && go_ret != "void" {
+		go_ret := ""
+		if cgo_ovfct.fcts[0].go_ret_name() != "" {
 			go_ret = "return"
-		} else {
-			go_ret = ""
 		}
 		cxx_protos := make([]string, 0, len(cgo_ovfct.fcts))
 		for i, _ := range cgo_ovfct.fcts {
@@ -1257,7 +2166,13 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		)
 		fmter(bufs["go_impl"], "\targc := len(args)\n")
 		fmter(bufs["go_impl"], "\tswitch argc {\n")
-		for nargs, cfcts := range dispatch_table {
+		dispatch_nargs := make([]int, 0, len(dispatch_table))
+		for nargs, _ := range dispatch_table {
+			dispatch_nargs = append(dispatch_nargs, nargs)
+		}
+		sort.Ints(dispatch_nargs)
+		for _, nargs := range dispatch_nargs {
+			cfcts := dispatch_table[nargs]
 			fmter(bufs["go_impl"], "\tcase %d:\n", nargs)
 			for _, cfct := range cfcts {
 				fmter(bufs["go_impl"], "\t{// %s\n", cfct.cxx_prototype())
@@ -1271,18 +2186,36 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 						go_receiver = "p."
 					}
 				}
-				for iarg, arg_t := range cfct.f.Params {
-					arg_cid := get_cxxgo_id(pkg, cxxtypes.IdByName(arg_t.Type))
+				igo := 0 // index of the Go argument
+				for iarg, _ := range cfct.f.Params[:nargs+cfct.nview()+cfct.nout()] {
+					if cfct.is_view_len(iarg) || cfct.dir(iarg) == "out" {
+						continue
+					}
+					arg_goname := cfct.go_param_name(iarg)
 					fmter(bufs["go_impl"],
 						"\targ_%d, ok_%d := args[%d].(%s)\n",
-						iarg, iarg, iarg,
-						arg_cid.goname,
+						iarg, iarg, igo,
+						arg_goname,
 					)
+					if is_go_sized_int(arg_goname) {
+						// also accept (untyped constants converted to) int
+						fmter(bufs["go_impl"],
+							"\tif v, ok := args[%d].(int); ok && !ok_%d {\n\t\targ_%d, ok_%d = %s(v), true\n\t}\n",
+							igo, iarg, iarg, iarg,
+							arg_goname,
+						)
+					}
+					igo += 1
 					go_casts = append(go_casts, fmt.Sprintf("ok_%d", iarg))
-					go_args = append(go_args, fmt.Sprintf("arg_%d", iarg))
+					if iarg < cfct.nreq() {
+						go_args = append(go_args, fmt.Sprintf("arg_%d", iarg))
+					} else {
+						go_args = append(go_args,
+							fmt.Sprintf("%s(arg_%d)", cfct.go_opt_name(iarg), iarg))
+					}
 				}
 				if_cond := "true"
-				if len(cfct.f.Params) >= 1 {
+				if nargs >= 1 {
 					if_cond = strings.Join(go_casts, " && ")
 				}
 				fmter(bufs["go_impl"],
@@ -1290,7 +2223,7 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 					if_cond,
 				)
 
-				if go_ret == "" && go_receiver != "" {
+				if go_ret == "" {
 					fmter(bufs["go_impl"],
 						"\t\t%s%s(%s)\n\t\treturn\n",
 						go_receiver,
@@ -1302,8 +2235,7 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 					// handle overload: 
 					//    void fct(T1, T2)
 					//    T3   fct(T0)
-					if (cfct.f.Ret != "" && cfct.f.Ret != "void") ||
-						cfct.f.IsConstructor() {
+					if cfct.go_ret_name() != "" {
 						fmter(bufs["go_impl"],
 							"\t\treturn %s%s(%s)\n",
 							go_receiver,
@@ -1314,7 +2246,7 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 						go_proto := strings.Split(cgo_ovfct.go_prototype(), " ")
 						
 						fmter(bufs["go_impl"],
-							"\t\tvar out %s\n\t\t%s%s(%s)\n\t\treturn out",
+							"\t\tvar out %s\n\t\t%s%s(%s)\n\t\treturn out\n",
 							go_proto[len(go_proto)-1],
 							go_receiver,
 							cfct.goname,
@@ -1336,8 +2268,6 @@ func (p *plugin) wrapFunction(cid *cxxgo_id, id *cxxtypes.OverloadFunctionSet) e
 		}
 	}
 
-	cid.wrapped = true
-	fmt.Printf(":: wrapping fct [%s]...[ok]\n", id.IdScopedName())
 	return nil
 }
 
@@ -1354,6 +2284,30 @@ type cxxgo_id struct {
 	cgoname string // the CGo name for this C/C++ identifier
 }
 
+// go_cptr returns the Go expression of the address of the C++ object held
+// by the Go handle v, passed as the type id: for a wrapped class (or a
+// pointer or reference to it), the address of the subobject of that class,
+// as the handles of the derived classes implement the interface of their
+// bases.
+func go_cptr(pkg string, id cxxtypes.Id, v string) string {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return v + ".Gocxxcptr()"
+	}
+	t = resolve_typedef(t)
+	switch tt := t.(type) {
+	case *cxxtypes.PtrType:
+		t = resolve_typedef(tt.UnderlyingType())
+	case *cxxtypes.RefType:
+		t = resolve_typedef(tt.UnderlyingType())
+	}
+	cls, ok := t.(cxxtypes.Id)
+	if !ok || !is_plain_class(cls) || g_opaques[cls.IdScopedName()] {
+		return v + ".Gocxxcptr()"
+	}
+	return fmt.Sprintf("%s.GocxxPtr%s()", v, get_cxxgo_id(pkg, cls).goname)
+}
+
 func (cid *cxxgo_id) is_class_like() bool {
 	id := cid.id
 	for {
@@ -1369,6 +2323,9 @@ func (cid *cxxgo_id) is_class_like() bool {
 		case *cxxtypes.RefType:
 			id = iid.UnderlyingType().(cxxtypes.Id)
 			continue
+		case *cxxtypes.TypedefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
 		default:
 			return false
 		}
@@ -1376,68 +2333,6 @@ func (cid *cxxgo_id) is_class_like() bool {
 	panic("unreachable")
 }
 
-func (cid *cxxgo_id) is_cstring_like() bool {
-	n := cid.id.IdName()
-	switch n {
-	case "string", "std::string", "TString":
-		return true
-	case "char*":
-		return true
-	}
-
-	c := true
-	id := cid.id
-	for c {
-		switch iid := id.(type) {
-		case *cxxtypes.CvrQualType:
-			id = cxxtypes.IdByName(iid.Type)
-		case *cxxtypes.TypedefType:
-			id = cxxtypes.IdByName(iid.UnderlyingType().TypeName())
-		default:
-			c = false
-			break
-		}
-	}
-	n = id.IdName()
-	if n == "const char*" || n == "char const*" || n == "char*" {
-		return true
-	}
-	return false
-}
-
-func (cid *cxxgo_id) is_string_like() bool {
-	n := cid.id.IdScopedName()
-	switch n {
-	case "string", "TString", "char*",
-		"std::string",
-		"std::string&",
-		"std::string*",
-		"std::string const",
-		"std::string const&",
-		"std::string const*":
-		return true
-	}
-	c := true
-	id := cid.id
-	for c {
-		switch iid := id.(type) {
-		case *cxxtypes.CvrQualType:
-			id = cxxtypes.IdByName(iid.Type)
-		case *cxxtypes.TypedefType:
-			id = cxxtypes.IdByName(iid.UnderlyingType().TypeName())
-		default:
-			c = false
-			break
-		}
-	}
-	n = id.IdScopedName()
-	switch n {
-	case "string", "std::string", "TString", "char*":
-		return true
-	}
-	return false
-}
-
 func (cid *cxxgo_id) is_pointer_like() bool {
 	iid := cid.id
 	for {
@@ -1477,29 +2372,49 @@ func get_cxxgo_id(pkgname string, id cxxtypes.Id) *cxxgo_id {
 // from a C++ OverloadFunctionSet (handling different signatures and default
 // parameters)
 type cxxgo_overload_fct_set_t struct {
-	cid    *cxxgo_id
-	pkg    string
-	ovfct  *cxxtypes.OverloadFunctionSet
-	fcts   []cxxgo_function
-	goname string
+	cid       *cxxgo_id
+	pkg       string
+	ovfct     *cxxtypes.OverloadFunctionSet
+	fcts      []cxxgo_function
+	goname    string
+	protected bool // whether it holds protected overloads (see wrapProtected)
+}
+
+// new_cxxgo_ovfcts creates the cxxgo overload sets for the public overloads
+// of a C++ OverloadFunctionSet.
+// Overloads are grouped by Go name: a C++ overload set may map to more than
+// one Go function (e.g. unary and binary operator-, prefix and postfix
+// operator++.)
+func (p *plugin) new_cxxgo_ovfcts(ovfct *cxxtypes.OverloadFunctionSet) []*cxxgo_overload_fct_set_t {
+	return p.new_cxxgo_ovfcts_access(ovfct, false)
+}
+
+// new_cxxgo_protected_ovfcts creates the cxxgo overload sets for the
+// protected overloads of a C++ OverloadFunctionSet.
+func (p *plugin) new_cxxgo_protected_ovfcts(ovfct *cxxtypes.OverloadFunctionSet) []*cxxgo_overload_fct_set_t {
+	return p.new_cxxgo_ovfcts_access(ovfct, true)
 }
 
-func (p *plugin) new_cxxgo_ovfct(ovfct *cxxtypes.OverloadFunctionSet) cxxgo_overload_fct_set_t {
+func (p *plugin) new_cxxgo_ovfcts_access(ovfct *cxxtypes.OverloadFunctionSet, protected bool) []*cxxgo_overload_fct_set_t {
 	pkg := p.gen.Fd.Package
-	o := cxxgo_overload_fct_set_t{
-		cid:    get_cxxgo_id(pkg, ovfct),
-		pkg:    pkg,
-		ovfct:  ovfct,
-		fcts:   make([]cxxgo_function, 0, len(ovfct.Fcts)),
-		goname: gen_go_name_from_id(ovfct),
-	}
-	needs_dispatch := fctset_need_dispatch(ovfct)
+	sets := []*cxxgo_overload_fct_set_t{}
 	for ifct, _ := range ovfct.Fcts {
 		fct := ovfct.Function(ifct)
-		if fct.IsPrivate() {
+		if fct.IsPrivate() || fct.IsProtected() != protected {
 			// discard from cxxgo-overload set
-			// FIXME: what about protected methods which are meant
-			//        to be implemented by, say, derived classes ?
+			// (protected methods are wrapped apart, see wrapProtected)
+			continue
+		}
+		if fct.IsDeleted() {
+			continue
+		}
+		if protected && fct.IsPureVirtual() {
+			// no implementation to call: overridden by Go (see wrapProtected)
+			continue
+		}
+		if fct.IsCopyConstructor() && !can_copy(cxxtypes.IdByName(fct.BaseId.Scope), true, !protected) {
+			fmt.Printf(":: discarding [%s] (class can not be copied)\n",
+				fct.Signature())
 			continue
 		}
 		if fct.IsMethod() && fct.IsConstructor() {
@@ -1524,28 +2439,105 @@ func (p *plugin) new_cxxgo_ovfct(ovfct *cxxtypes.OverloadFunctionSet) cxxgo_over
 				continue
 			}
 		}
-		nargs := fct.NumParam()
-		ndargs := fct.NumDefaultParam()
-		imax := ndargs + 1
-		for i := 0; i < imax; i++ {
-			idx := len(o.fcts)
-			cfct := cxxgo_function{
-				f:     *fct, // copy
-				pkg:   pkg,
-				idx:   idx,
-				ovfct: &o,
-			}
-			cfct.goname = gen_go_name_from_id(ovfct)
-			cfct.cgoname = gen_cgo_name_from_id(pkg, ovfct)
-			if needs_dispatch {
-				cfct.goname = cfct.goname + fmt.Sprintf("__GOCXX_%d", idx)
+		goname := gen_go_name_from_id(fct)
+		if goname == "" {
+			fmt.Printf(":: discarding [%s] (unsupported operator)\n",
+				fct.Signature())
+			continue
+		}
+		if why := unsupported_fct(fct); why != "" {
+			fmt.Printf(":: discarding [%s] (unsupported %s)\n",
+				fct.Signature(), why)
+			continue
+		}
+		if fct.Ret != "" && strip_callback(cxxtypes.IdByName(fct.Ret)) != nil {
+			// FIXME: calling a C++ std::function from Go
+			fmt.Printf(":: discarding [%s] (std::function result)\n",
+				fct.Signature())
+			continue
+		}
+		if fct.Ret != "" && strip_stream(cxxtypes.IdByName(fct.Ret)) != nil {
+			// e.g. operator<<, see wrapStringer
+			fmt.Printf(":: discarding [%s] (stream result)\n",
+				fct.Signature())
+			continue
+		}
+		var o *cxxgo_overload_fct_set_t
+		for _, set := range sets {
+			if set.goname == goname {
+				o = set
+				break
+			}
+		}
+		if o == nil {
+			o = &cxxgo_overload_fct_set_t{
+				cid:       get_cxxgo_id(pkg, ovfct),
+				pkg:       pkg,
+				ovfct:     ovfct,
+				fcts:      make([]cxxgo_function, 0, len(ovfct.Fcts)),
+				goname:    goname,
+				protected: protected,
+			}
+			sets = append(sets, o)
+		}
+		// parameters with a default value are handled by the Go
+		// function as optional arguments.
+		cfct := cxxgo_function{
+			f:     *fct, // copy
+			pkg:   pkg,
+			idx:   len(o.fcts),
+			ovfct: o,
+			view:  p.use_view(fct),
+			dirs:  p.param_dirs(fct),
+		}
+		cfct.status = p.status_rule(fct)
+		cfct.goname = goname
+		cfct.cgoname = gen_cgo_name_from_id(pkg, ovfct)
+		if protected {
+			cfct.cgoname += "_protected"
+		}
+		o.fcts = append(o.fcts, cfct)
+	}
+
+	// C symbols have to be unique across all the Go names of the C++ set.
+	idx := 0
+	for _, o := range sets {
+		gonames := make(map[string]bool, len(o.fcts))
+		for i, _ := range o.fcts {
+			cfct := &o.fcts[i]
+			if o.needs_dispatch() {
+				if p.ovl_typed {
+					// e.g. AddInt, AddFloat64
+					n := cfct.goname + gen_go_overload_suffix(pkg, &cfct.f)
+					clash := gonames[n]
+					if n == o.goname {
+						// clashes with the dispatcher, if any
+						clash = clash || p.ovl_dispatch
+					} else {
+						clash = clash || scope_has_go_name(&cfct.f, n)
+					}
+					if clash {
+						n = n + fmt.Sprintf("_%d", cfct.idx)
+					}
+					gonames[n] = true
+					cfct.goname = n
+				} else {
+					cfct.goname = cfct.goname + fmt.Sprintf("__GOCXX_%d", cfct.idx)
+				}
+			}
+			if len(sets) > 1 || o.needs_dispatch() {
 				cfct.cgoname = cfct.cgoname + fmt.Sprintf("_%d", idx)
 			}
-			cfct.f.Params = cfct.f.Params[:nargs-ndargs+i]
-			o.fcts = append(o.fcts, cfct)
+			idx += 1
 		}
 	}
-	return o
+	return sets
+}
+
+// needs_dispatch returns whether more than one overload share the Go name
+// of this set, and thus need a run-time dispatch.
+func (f *cxxgo_overload_fct_set_t) needs_dispatch() bool {
+	return len(f.fcts) > 1
 }
 
 func (f *cxxgo_overload_fct_set_t) cxx_prototype() string {
@@ -1554,77 +2546,206 @@ func (f *cxxgo_overload_fct_set_t) cxx_prototype() string {
 
 func (f *cxxgo_overload_fct_set_t) go_prototype() string {
 
-	fct := f.ovfct.Fcts[0]
+	if !f.needs_dispatch() {
+		return f.fcts[0].go_prototype()
+	}
+
+	s := []string{f.goname, "(", "args ...interface{}", ")"}
+	if ret := f.fcts[0].go_ret_name(); ret != "" {
+		s = append(s, " ", ret)
+	}
+	return strings.Join(s, "")
+}
+
+type cxxgo_function struct {
+	f       cxxtypes.Function
+	pkg     string
+	idx     int // index in the overload fct set
+	ovfct   *cxxgo_overload_fct_set_t
+	goname  string
+	cgoname string
+	view    bool         // whether to exchange zero-copy slice views with Go
+	dirs    []string     // the directions of the parameters (see param_dirs)
+	status  *status_rule // the rule mapping the result to a Go error, if any
+}
+
+func (f *cxxgo_function) go_prototype() string {
+
+	fct := f.f
 	s := []string{f.goname, "("}
 
-	if fctset_need_dispatch(f.ovfct) {
-		s = append(s, "args ...interface{}")
+	if fct.IsDestructor() {
+		scope_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
+		s = append(s,
+			"arg",
+			" ", scope_id.goname)
 	} else {
-		if fct.IsDestructor() {
-			scope_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
-			s = append(s,
-				"arg",
-				" ", scope_id.goname)
-		} else {
-			for i, _ := range fct.Params {
-				arg_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.Param(i).Type))
-				s = append(s,
-					fmt.Sprintf("arg_%d ", i),
-					arg_id.goname)
-				if i < len(fct.Params)-1 {
-					s = append(s, ", ")
-				}
+		args := []string{}
+		nreq := f.nreq()
+		for i, _ := range fct.Params[:nreq] {
+			if f.is_view_len(i) {
+				// passed as the length of the previous slice
+				continue
 			}
+			if f.dir(i) == "out" {
+				// returned as a Go result
+				continue
+			}
+			args = append(args, fmt.Sprintf("arg_%d %s", i, f.go_param_name(i)))
+		}
+		if nreq < len(fct.Params) {
+			args = append(args, "opts ..."+f.go_opts_name()+"Opt")
+		}
+		s = append(s, strings.Join(args, ", "))
+	}
+	s = append(s, ")")
+	if ret := f.go_ret_name(); ret != "" {
+		s = append(s, " ", ret)
+	}
+	return strings.Join(s, "")
+}
+
+// go_param_name returns the Go type of the i-th parameter
+func (f *cxxgo_function) go_param_name(i int) string {
+	if f.is_view_ptr(i) {
+		return "[]" + gen_go_name_from_id(view_elt_type(f.param_id(i)))
+	}
+	if f.is_out(i) {
+		return get_cxxgo_id(f.pkg, out_type(f.param_id(i))).goname
+	}
+	if is_move_param(f.param_id(i)) {
+		// the handle is invalidated once moved from
+		return "*" + get_cxxgo_id(f.pkg, f.param_id(i)).goname
+	}
+	return get_cxxgo_id(f.pkg, f.param_id(i)).goname
+}
+
+// go_ret_name returns the Go type of the results of the function, if any:
+// the result of the C++ function followed by the output parameters.
+// A status code mapped to a Go error (see status_rule) comes last.
+func (f *cxxgo_function) go_ret_name() string {
+	ret := f.go_cxx_ret_name()
+	if len(f.go_outs()) == 0 && f.status == nil {
+		return ret
+	}
+	outs := []string{}
+	switch {
+	case f.status != nil:
+		// returned last, as a Go error
+	case strings.HasPrefix(ret, "("):
+		// e.g. (T, bool)
+		outs = append(outs, strings.Split(ret[1:len(ret)-1], ", ")...)
+	case ret != "":
+		outs = append(outs, ret)
+	}
+	for i, _ := range f.f.Params {
+		if f.is_out(i) {
+			outs = append(outs, f.go_param_name(i))
 		}
 	}
-	s = append(s, ")")
+	if f.status != nil {
+		outs = append(outs, "error")
+	}
+	if len(outs) == 1 {
+		return outs[0]
+	}
+	return "(" + strings.Join(outs, ", ") + ")"
+}
+
+// go_cxx_ret_name returns the Go type of the result of the C++ function,
+// if any
+func (f *cxxgo_function) go_cxx_ret_name() string {
+	fct := f.f
 	if fct.Ret != "" && fct.Ret != "void" {
+		if c := f.view_ret(); c != nil {
+			return c.goname()
+		}
+		if s := get_cxxgo_string(cxxtypes.IdByName(fct.Ret)); s != nil {
+			// returned strings are always copied
+			return s.value().goname()
+		}
+		if c, _, _ := strip_container(cxxtypes.IdByName(fct.Ret)); c != nil && c.class == "optional" {
+			return "(" + c.elt_goname(0) + ", bool)"
+		}
+		if sp, ref, _ := strip_smartptr(cxxtypes.IdByName(fct.Ret)); sp != nil && sp.kind == "unique" && ref {
+			// the object is still owned by the C++ unique_ptr
+			return sp.elt_goname()
+		}
 		ret_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.Ret))
-		s = append(s, " ", ret_id.goname)
+		return ret_id.goname
 	} else if fct.IsConstructor() || fct.IsCopyConstructor() {
 		scope_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
-		s = append(s, " ", scope_id.goname)
+		return scope_id.goname
 	}
-	return strings.Join(s, "")
+	return ""
 }
 
-type cxxgo_function struct {
-	f       cxxtypes.Function
-	pkg     string
-	idx     int // index in the overload fct set
-	ovfct   *cxxgo_overload_fct_set_t
-	goname  string
-	cgoname string
+// param_id returns the type of the i-th parameter
+func (f *cxxgo_function) param_id(i int) cxxtypes.Id {
+	return cxxtypes.IdByName(f.f.Param(i).Type)
 }
 
-func (f *cxxgo_function) go_prototype() string {
+// nreq returns the number of required parameters, ie: parameters without a
+// default value.
+func (f *cxxgo_function) nreq() int {
+	return f.f.NumParam() - f.f.NumDefaultParam()
+}
 
+// go_opts_name returns the prefix of the Go names dealing with the optional
+// arguments of this function. (e.g. FooSet for Foo::set)
+func (f *cxxgo_function) go_opts_name() string {
 	fct := f.f
-	s := []string{f.goname, "("}
+	n := strings.Replace(f.goname, "__GOCXX", "", 1)
+	if fct.IsMethod() && !fct.IsConstructor() && !fct.IsDestructor() {
+		cid_scope := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
+		n = cid_scope.goname + n
+	}
+	return n
+}
 
-	if fct.IsDestructor() {
-		scope_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
-		s = append(s,
-			"arg",
-			" ", scope_id.goname)
-	} else {
-		for i, _ := range fct.Params {
-			arg_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.Param(i).Type))
-			s = append(s,
-				fmt.Sprintf("arg_%d ", i),
-				arg_id.goname)
-			if i < len(fct.Params)-1 {
-				s = append(s, ", ")
-			}
-		}
+// go_opt_name returns the Go name of the function setting the i-th
+// (optional) argument of this function. (e.g. FooSetWithD)
+func (f *cxxgo_function) go_opt_name(i int) string {
+	n := f.f.Param(i).Name
+	if n == "" {
+		n = fmt.Sprintf("Arg%d", i)
 	}
-	s = append(s, ")")
-	if fct.Ret != "" && fct.Ret != "void" {
-		ret_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.Ret))
-		s = append(s, " ", ret_id.goname)
-	} else if fct.IsConstructor() || fct.IsCopyConstructor() {
-		scope_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.BaseId.Scope))
-		s = append(s, " ", scope_id.goname)
+	return f.go_opts_name() + "With" + strings.ToUpper(n[:1]) + n[1:]
+}
+
+// go_opts_decl returns the Go declarations of the types and functions
+// handling the optional arguments of this function.
+func (f *cxxgo_function) go_opts_decl() string {
+	fct := f.f
+	n := f.go_opts_name()
+	s := []string{}
+	s = append(s,
+		fmt.Sprintf("\n// %sOpt sets an optional argument of [%s]\n",
+			n, f.cxx_prototype()),
+		fmt.Sprintf("type %sOpt func(*%sOpts)\n", n, n),
+		fmt.Sprintf("\n// %sOpts holds the optional arguments of [%s]\n",
+			n, f.cxx_prototype()),
+		"// Arguments which are not set take their C++ default value.\n",
+		fmt.Sprintf("type %sOpts struct {\n", n),
+	)
+	for i := f.nreq(); i < len(fct.Params); i++ {
+		arg_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(fct.Param(i).Type))
+		s = append(s, fmt.Sprintf("\targ_%d *%s\n", i, arg_id.goname))
+	}
+	s = append(s, "}\n")
+	for i := f.nreq(); i < len(fct.Params); i++ {
+		arg := fct.Param(i)
+		arg_id := get_cxxgo_id(f.pkg, cxxtypes.IdByName(arg.Type))
+		s = append(s,
+			fmt.Sprintf("\n// %s sets the argument [%s] (C++ default: %s)\n",
+				f.go_opt_name(i),
+				arg.Name,
+				arg.DefaultValue()),
+			fmt.Sprintf("func %s(v %s) %sOpt {\n",
+				f.go_opt_name(i), arg_id.goname, n),
+			fmt.Sprintf("\treturn func(o *%sOpts) { o.arg_%d = &v }\n}\n",
+				n, i),
+		)
 	}
 	return strings.Join(s, "")
 }
@@ -1680,6 +2801,9 @@ func (f *cxxgo_function) cxx_prototype() string {
 				strings.TrimSpace(fct.Param(i).Type),
 				" ",
 				strings.TrimSpace(fct.Param(i).Name))
+			if fct.Param(i).HasDefaultValue() {
+				s = append(s, "=", fct.Param(i).DefaultValue())
+			}
 			if i < len(fct.Params)-1 {
 				s = append(s, ", ")
 			}
@@ -1738,6 +2862,17 @@ func cxx2cgo_typename(t string) string {
 }
 
 func gen_go_name_from_id(id cxxtypes.Id) string {
+	if td, ok := id.(*cxxtypes.TypedefType); ok {
+		if _, ok := g_typedefs[td.IdScopedName()]; ok {
+			return typedef_goname(td)
+		}
+	}
+	if s := get_cxxgo_string(id); s != nil {
+		return s.goname()
+	}
+	if st := strip_stream(id); st != nil {
+		return st.goname()
+	}
 	n := id.IdScopedName()
 
 	// special cases
@@ -1745,7 +2880,7 @@ func gen_go_name_from_id(id cxxtypes.Id) string {
 		return cxx2go_typename(n)
 	}
 
-	n = g_cxxgo_trans.Replace(n)
+	n = g_cxxgo_trans.Replace(strip_root_ns(n))
 
 	switch id := id.(type) {
 
@@ -1755,47 +2890,26 @@ func gen_go_name_from_id(id cxxtypes.Id) string {
 		if id.IsDestructor() {
 			n = "Delete" + cls_name //strings.Title(cls_id.IdName())[1:]
 		} else if id.IsOperator() {
-			switch id.IdName() {
-			case "operator+":
-				n = "Add_op"
-			case "operator+=":
-				n = "IAdd_op"
-			case "operator-":
-				n = "Sub_op"
-			case "operator-=":
-				n = "ISub_op"
-			case "operator*":
-				n = "Mul_op"
-			case "operator*=":
-				n = "IMul_op"
-			case "operator/":
-				n = "Div_op"
-			case "operator/=":
-				n = "IDiv_op"
-			case "operator=":
-				n = "Assign_op"
-			case "operator==":
-				n = "Eq_op"
-			case "operator()":
-				n = "Call_op"
-			case "operator->":
-				n = "Arrow_op"
-			case "operator[]":
-				n = "At_op"
-			case "operator++":
-				n = "Inc_op"
-			case "operator--":
-				n = "Dec_op"
-			default:
-				panic(fmt.Sprintf("unknown operator [%s] [scoped=%s]",
-					id.IdName(),
-					id.IdScopedName()))
+			n = gen_go_operator_name(id)
+			if n == "" {
+				// unsupported operator
+				return n
+			}
+			if scope_has_go_name(id, n) {
+				n += "_op"
 			}
 		} else if id.IsConverter() {
 
 			n = "CnvTo_" + id.IdName()[len("operator "):]
 		} else if id.IsConstructor() || id.IsCopyConstructor() {
 			n = "New" + cls_name //strings.Title(iid.IdName())
+		} else if o := tmpl_goname(id); o != "" {
+			n = o
+		} else if o := tmpl_default_goname(id, gen_go_tmpl_name(id)); o != "" {
+			n = o
+		} else if !id.IsMethod() {
+			// free functions are prefixed with their namespaces
+			n = strings.Title(g_cxxgo_trans.Replace(strip_root_ns(id.IdScopedName())))
 		} else {
 			n = strings.Title(id.IdName())
 		}
@@ -1809,24 +2923,74 @@ func gen_go_name_from_id(id cxxtypes.Id) string {
 		return gen_go_name_from_id(iid)
 
 	case *cxxtypes.ClassType:
+		if c := get_cxxgo_container(id); c != nil {
+			return c.goname()
+		}
+		if sp := get_cxxgo_smartptr(id); sp != nil {
+			return "*" + sp.goname()
+		}
+		if cb := get_cxxgo_callback(id); cb != nil {
+			return cb.goname()
+		}
+		if o := tmpl_goname(id); o != "" {
+			return o
+		}
+		if o := nested_goname(id); o != "" {
+			return o
+		}
+		if o := tmpl_default_goname(id, gen_go_tmpl_name(id)); o != "" {
+			return o
+		}
 		n = strings.Title(n)
 
+	case *cxxtypes.EnumType:
+		if o := nested_goname(id); o != "" {
+			return o
+		}
+
 	case *cxxtypes.PtrType:
 		ptr := "*"
 		ptee_id := id.UnderlyingType().(cxxtypes.Id)
+		if c, _, _ := strip_container(ptee_id); c != nil && c.class == "optional" {
+			// already a Go pointer
+			return c.goname()
+		}
+		if ft, ok := cxxtypes.UnqualifiedType(id.UnderlyingType()).(*cxxtypes.FundamentalType); ok &&
+			ft.TypeKind() == cxxtypes.TK_Void {
+			return "unsafe.Pointer"
+		}
 		switch ptee_id.(type) {
 		case *cxxtypes.ClassType:
 			// for a class, the go-type is an interface...
 			// having a pointer to an interface isn't really go-ish
-			ptr = ""
+			if c := get_cxxgo_container(ptee_id); c == nil || !c.cvt {
+				ptr = ""
+			}
 		}
 		return ptr + gen_go_name_from_id(id.UnderlyingType().(cxxtypes.Id))
 
 	case *cxxtypes.RefType:
+		if c, indirect, _ := strip_container(id); c != nil && c.cvt && indirect {
+			// the content of the Go slice/map is updated after the call
+			return "*" + c.goname()
+		}
 		return gen_go_name_from_id(id.UnderlyingType().(cxxtypes.Id))
 
 	case *cxxtypes.CvrQualType:
 		return gen_go_name_from_id(cxxtypes.IdByName(id.Type))
+
+	case *cxxtypes.FundamentalType:
+		if id.TypeKind() == cxxtypes.TK_Void {
+			return ""
+		}
+		if o := gen_go_fundamental_name(id); o != "" {
+			return o
+		}
+		return cxx2go_typename(id.IdScopedName())
+
+	case *cxxtypes.TypedefType:
+		// not wrapped (see g_typedefs): the Go type of the underlying type
+		return gen_go_name_from_id(id.UnderlyingType().(cxxtypes.Id))
 	}
 
 	// sanitize
@@ -1838,6 +3002,346 @@ func gen_go_name_from_id(id cxxtypes.Id) string {
 	return o
 }
 
+// gen_go_tmpl_name returns the Go name of the template of the class or
+// function template instance id, as the template would be named if it
+// were a class or a function.
+func gen_go_tmpl_name(id cxxtypes.Id) string {
+	ti := template_instance(id)
+	if ti == nil {
+		return ""
+	}
+	n := ti.TemplateName()
+	if fct, ok := id.(*cxxtypes.Function); ok && fct.IsMethod() {
+		return strings.Title(n[strings.LastIndex(n, ":")+1:])
+	}
+	return strings.Title(g_cxxgo_trans.Replace(strip_root_ns(n)))
+}
+
+// gen_go_operator_name returns the Go name of a C++ operator function,
+// or the empty string if that operator can not be wrapped.
+func gen_go_operator_name(fct *cxxtypes.Function) string {
+	// number of operands, including the implicit 'this' of methods
+	nops := len(fct.Params)
+	if fct.IsMethod() && !fct.IsStatic() {
+		nops += 1
+	}
+	unary := nops == 1
+	// postfix inc/dec operators take an additional dummy int
+	postfix := nops == 2
+
+	op := strings.TrimSpace(strings.TrimPrefix(fct.IdName(), "operator"))
+	op = strings.Replace(op, " ", "", -1)
+	switch op {
+	// arithmetic
+	case "+":
+		if unary {
+			return "Plus"
+		}
+		return "Add"
+	case "-":
+		if unary {
+			return "Neg"
+		}
+		return "Sub"
+	case "*":
+		if unary {
+			return "Deref"
+		}
+		return "Mul"
+	case "/":
+		return "Div"
+	case "%":
+		return "Mod"
+	case "++":
+		if postfix {
+			return "PostInc"
+		}
+		return "Inc"
+	case "--":
+		if postfix {
+			return "PostDec"
+		}
+		return "Dec"
+
+	// comparison
+	case "==":
+		return "Equal"
+	case "!=":
+		return "NotEqual"
+	case "<":
+		return "Less"
+	case "<=":
+		return "LessEqual"
+	case ">":
+		return "Greater"
+	case ">=":
+		return "GreaterEqual"
+	case "<=>":
+		return "Compare"
+
+	// logical
+	case "!":
+		return "Not"
+	case "&&":
+		return "LogicalAnd"
+	case "||":
+		return "LogicalOr"
+
+	// bitwise
+	case "~":
+		return "Compl"
+	case "&":
+		if unary {
+			return "Addr"
+		}
+		return "And"
+	case "|":
+		return "Or"
+	case "^":
+		return "Xor"
+	case "<<":
+		return "Shl"
+	case ">>":
+		return "Shr"
+
+	// assignment
+	case "=":
+		return "Assign"
+	case "+=":
+		return "AddAssign"
+	case "-=":
+		return "SubAssign"
+	case "*=":
+		return "MulAssign"
+	case "/=":
+		return "DivAssign"
+	case "%=":
+		return "ModAssign"
+	case "&=":
+		return "AndAssign"
+	case "|=":
+		return "OrAssign"
+	case "^=":
+		return "XorAssign"
+	case "<<=":
+		return "ShlAssign"
+	case ">>=":
+		return "ShrAssign"
+
+	// member access, call, subscript, comma
+	case "[]":
+		return "Index"
+	case "()":
+		return "Call"
+	case "->":
+		return "Arrow"
+	case "->*":
+		return "ArrowStar"
+	case ",":
+		return "Comma"
+
+	// allocation: the class-specific allocation functions are called by
+	// new-expressions, not on objects
+	case "new", "new[]", "delete", "delete[]":
+		return ""
+	}
+	// co_await, user-defined literals, ...
+	return ""
+}
+
+// gen_go_fundamental_name returns the Go type of a C++ fundamental type,
+// derived from its size and kind as recorded in the registry.
+// Integers always map to exact-width Go types.
+// It returns "" if there is no Go equivalent, as for void. (see
+// gen_go_name_from_id for void*)
+func gen_go_fundamental_name(id *cxxtypes.FundamentalType) string {
+	sz := id.TypeSize()
+	switch id.TypeKind() {
+	case cxxtypes.TK_Bool:
+		return "bool"
+
+	case cxxtypes.TK_Char_S, cxxtypes.TK_Char_U:
+		if sz == 1 {
+			return "byte"
+		}
+
+	case cxxtypes.TK_WChar:
+		switch sz {
+		case 1, 2, 4, 8:
+			if cxxtypes.CurrentTarget().WCharSigned {
+				return fmt.Sprintf("int%d", 8*sz)
+			}
+			return fmt.Sprintf("uint%d", 8*sz)
+		}
+
+	case cxxtypes.TK_SChar, cxxtypes.TK_Short, cxxtypes.TK_Int,
+		cxxtypes.TK_Long, cxxtypes.TK_LongLong, cxxtypes.TK_Int128:
+		switch sz {
+		case 1, 2, 4, 8:
+			return fmt.Sprintf("int%d", 8*sz)
+		}
+
+	case cxxtypes.TK_UChar, cxxtypes.TK_UShort, cxxtypes.TK_UInt,
+		cxxtypes.TK_ULong, cxxtypes.TK_ULongLong, cxxtypes.TK_UInt128,
+		cxxtypes.TK_Char16, cxxtypes.TK_Char32:
+		switch sz {
+		case 1, 2, 4, 8:
+			return fmt.Sprintf("uint%d", 8*sz)
+		}
+
+	case cxxtypes.TK_Float, cxxtypes.TK_Double, cxxtypes.TK_LongDouble:
+		switch sz {
+		case 4, 8:
+			return fmt.Sprintf("float%d", 8*sz)
+		}
+
+	case cxxtypes.TK_Complex:
+		switch sz {
+		case 8, 16:
+			return fmt.Sprintf("complex%d", 8*sz)
+		}
+	}
+	return ""
+}
+
+// gen_cgo_fundamental_name returns the name cgo gives to a C++ fundamental
+// type (w/o the "C." prefix)
+func gen_cgo_fundamental_name(id *cxxtypes.FundamentalType) string {
+	switch id.TypeKind() {
+	case cxxtypes.TK_Char_S, cxxtypes.TK_Char_U:
+		return "char"
+	case cxxtypes.TK_SChar:
+		return "schar"
+	case cxxtypes.TK_UChar:
+		return "uchar"
+	case cxxtypes.TK_Short:
+		return "short"
+	case cxxtypes.TK_UShort:
+		return "ushort"
+	case cxxtypes.TK_Int:
+		return "int"
+	case cxxtypes.TK_UInt:
+		return "uint"
+	case cxxtypes.TK_Long:
+		return "long"
+	case cxxtypes.TK_ULong:
+		return "ulong"
+	case cxxtypes.TK_LongLong:
+		return "longlong"
+	case cxxtypes.TK_ULongLong:
+		return "ulonglong"
+	case cxxtypes.TK_Float:
+		return "float"
+	case cxxtypes.TK_Double:
+		return "double"
+	}
+	return g_strtrans.Replace(id.IdName())
+}
+
+// gen_go_build_constraint returns the build constraint restricting the
+// generated Go code to the platform the registry was built for.
+func gen_go_build_constraint(t cxxtypes.Target) string {
+	if t.GOOS == "" || t.GOARCH == "" {
+		return ""
+	}
+	return fmt.Sprintf("//go:build %s && %s\n// +build %s,%s\n",
+		t.GOOS, t.GOARCH,
+		t.GOOS, t.GOARCH,
+	)
+}
+
+// is_go_sized_int returns whether n is the name of an exact-width Go integer
+func is_go_sized_int(n string) bool {
+	switch n {
+	case "int8", "int16", "int32", "int64",
+		"uint8", "uint16", "uint32", "uint64", "byte":
+		return true
+	}
+	return false
+}
+
+// resolve_typedef returns the type a (chain of) typedef(s) ultimately
+// refers to, stripped from its cv-qualifiers.
+func resolve_typedef(t cxxtypes.Type) cxxtypes.Type {
+	for {
+		switch tt := cxxtypes.UnqualifiedType(t).(type) {
+		case *cxxtypes.TypedefType:
+			t = tt.UnderlyingType()
+		default:
+			return tt
+		}
+	}
+	panic("unreachable")
+}
+
+// gen_go_overload_suffix returns a readable suffix disambiguating an
+// overload, derived from the Go types of its required parameters
+// (e.g. "IntFloat64")
+func gen_go_overload_suffix(pkg string, fct *cxxtypes.Function) string {
+	nreq := fct.NumParam() - fct.NumDefaultParam()
+	s := make([]string, 0, nreq)
+	for i, _ := range fct.Params[:nreq] {
+		cid := get_cxxgo_id(pkg, cxxtypes.IdByName(fct.Param(i).Type))
+		n := cid.goname
+		if is_move_param(cid.id) {
+			n = "Move_" + n
+		}
+		// drop package qualifier
+		if idx := strings.LastIndex(n, "."); idx >= 0 {
+			n = n[idx+1:]
+		}
+		n = strings.Replace(n, "*", "Ptr_", -1)
+		n = strings.Replace(n, "[]", "Slice_", -1)
+		for _, word := range strings.FieldsFunc(n, is_not_alnum) {
+			s = append(s, strings.ToUpper(word[:1])+word[1:])
+		}
+	}
+	return strings.Join(s, "")
+}
+
+func is_not_alnum(r rune) bool {
+	return !(('a' <= r && r <= 'z') ||
+		('A' <= r && r <= 'Z') ||
+		('0' <= r && r <= '9'))
+}
+
+// scope_has_go_name returns whether the declaring scope of fct holds a
+// non-operator function which would be wrapped under the Go name n.
+func scope_has_go_name(fct *cxxtypes.Function, n string) bool {
+	names := []string{}
+	switch scope := cxxtypes.IdByName(fct.BaseId.Scope).(type) {
+	case *cxxtypes.ClassType:
+		for _, mbr := range scope.Members {
+			if mbr.IsFunctionMember() {
+				names = append(names, mbr.Name)
+			}
+		}
+	case *cxxtypes.StructType:
+		for _, mbr := range scope.Members {
+			if mbr.IsFunctionMember() {
+				names = append(names, mbr.Name)
+			}
+		}
+	case *cxxtypes.Namespace:
+		names = append(names, scope.Members...)
+	}
+	for _, name := range names {
+		ovfct, ok := cxxtypes.IdByName(name).(*cxxtypes.OverloadFunctionSet)
+		if !ok || ovfct.NumFunction() <= 0 {
+			continue
+		}
+		f := ovfct.Function(0)
+		if f.IsOperator() || f.IsConverter() ||
+			f.IsConstructor() || f.IsDestructor() {
+			continue
+		}
+		if strings.Title(f.IdName()) == n {
+			return true
+		}
+	}
+	return false
+}
+
 func gen_go_name(cxxname string) string {
 	o := g_cxxgo_trans.Replace(cxxname)
 	if _, ok := _cxx2go_typemap[o]; ok {
@@ -1857,7 +3361,7 @@ func gen_cgo_name_from_id(pkgname string, id cxxtypes.Id) string {
 	switch id := id.(type) {
 
 	case *cxxtypes.FundamentalType:
-		n = "C."+id.IdName()
+		n = "C." + gen_cgo_fundamental_name(id)
 
 	case *cxxtypes.Function:
 		n = fmt.Sprintf("C._gocxx_fct_%s_%s", pkgname, get_iid_str(id))
@@ -1901,6 +3405,14 @@ func gen_cgo_name_from_id(pkgname string, id cxxtypes.Id) string {
 	case *cxxtypes.TypedefType:
 		n = fmt.Sprintf("C._gocxx_typedef_%s_%s", pkgname, get_iid_str(id))
 
+	case *cxxtypes.EnumType:
+		// enums are passed as C ints
+		n = "C.int"
+
+	case *cxxtypes.Namespace, *cxxtypes.NamespaceAlias:
+		// no cgo counterpart
+		n = ""
+
 	default:
 		err := fmt.Errorf("unhandled identifier [%v]", id)
 		panic(err)
@@ -1923,9 +3435,9 @@ func (p *plugin) mbr_filter(mbr *cxxtypes.Member) bool {
 		return false
 	}
 
-	// filter any copy constructor with a private copy constructor in
-	// any base class
-	// TODO
+	// copy constructors of classes with a private or deleted copy
+	// constructor in any base class are filtered out with the other
+	// overloads (see new_cxxgo_ovfcts_access and can_copy)
 
 	// filter any constructor for pure abstract classes
 	// TODO
@@ -1939,6 +3451,19 @@ func (p *plugin) mbr_filter(mbr *cxxtypes.Member) bool {
 	return true
 }
 
+// use_view returns whether fct has been selected to exchange zero-copy
+// slice views with Go (see the 'views' argument)
+func (p *plugin) use_view(fct *cxxtypes.Function) bool {
+	n := fct.IdScopedName()
+	for _, pat := range p.views {
+		matched, err := path.Match(pat, n)
+		if err != path.ErrBadPattern && matched {
+			return true
+		}
+	}
+	return false
+}
+
 // get_container_id returns the container-type and stl-class of id
 // (if id is actually a container.)
 func get_container_id(id cxxtypes.Id) (string, string) {
@@ -1992,8 +3517,18 @@ func get_container_id(id cxxtypes.Id) (string, string) {
 		return "STACK", "stack"
 	} else if strings.HasPrefix(n, "std::vector") {
 		return "VECTOR", "vector"
+	} else if strings.HasPrefix(n, "std::array") {
+		return "ARRAY", "array"
 	} else if strings.HasPrefix(n, "std::bitset") {
 		return "BITSET", "bitset"
+	} else if strings.HasPrefix(n, "std::pair") {
+		return "PAIR", "pair"
+	} else if strings.HasPrefix(n, "std::tuple") {
+		return "TUPLE", "tuple"
+	} else if strings.HasPrefix(n, "std::optional") {
+		return "OPTIONAL", "optional"
+	} else if strings.HasPrefix(n, "std::variant") {
+		return "VARIANT", "variant"
 	} else {
 		return "NOCONTAINER", ""
 	}
@@ -2019,40 +3554,10 @@ func str_is_in_slice(s string, slice []string) bool {
 	return false
 }
 
-func fctset_need_dispatch(ovfct *cxxtypes.OverloadFunctionSet) bool {
-	noverloads := 0
-	for i, _ := range ovfct.Fcts {
-		f := ovfct.Function(i)
-		if f.IsPrivate() {
-			continue
-		}
-		if f.IsMethod() && f.IsConstructor() {
-			// discard if class is abstract...
-			scope_id, ok := cxxtypes.IdByName(f.BaseId.Scope).(cxxtypes.Type)
-			if ok && cxxtypes.IsAbstractType(scope_id) {
-				continue
-			}
-		}
-		if f.IsMethod() && f.IsConst() {
-			// discard if there is the non-const version
-			discard := false
-			for ii, _ := range ovfct.Fcts {
-				ff := ovfct.Function(ii)
-				if !ff.IsPrivate() && !ff.IsConst() &&
-					ff.BaseId.Name == f.BaseId.Name {
-					discard = true
-					break
-				}
-			}
-			if discard {
-				continue
-			}
-		}
-		noverloads += 1 + f.NumDefaultParam()
-	}
-	return noverloads > 1
-}
-
+// get_dependent_ids returns the names of the identifiers needed to wrap id.
+// The classes reachable from the signatures of the functions are not
+// followed: they are wrapped as opaque handles, if not selected.
+// (see g_opaques)
 func get_dependent_ids(in_ids []string, id cxxtypes.Id) []string {
 	return get_dependent_ids_rec(in_ids, id, true, 0)
 }
@@ -2067,10 +3572,18 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 		dep_ids = append(dep_ids, dep_id)
 	}
 
-	if str_is_in_slice(id.IdScopedName(), dep_ids) {
+	if reclvl > 0 && str_is_in_slice(id.IdScopedName(), dep_ids) {
 		//println("...", reclvl, id.IdScopedName(), "ALREADY DONE")
 		return dep_ids
 	}
+	if get_cxxgo_string(id) != nil && !is_go_typedef(id) {
+		// strings are converted to Go strings
+		return dep_ids
+	}
+	if strip_stream(id) != nil && !is_go_typedef(id) {
+		// streams are bridged to Go writers and readers
+		return dep_ids
+	}
 	dep_ids = append(dep_ids, id.IdScopedName())
 
 	switch id := id.(type) {
@@ -2080,6 +3593,34 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 
 	case *cxxtypes.ClassType:
 		//println("**cls",id.IdScopedName())
+		if c := get_cxxgo_container(id); c != nil {
+			// only the element types are needed, not the STL internals
+			for i, elt := range c.elts {
+				if c.ekind[i] != "handle" ||
+					str_is_in_slice(elt.IdScopedName(), dep_ids) {
+					continue
+				}
+				dep_ids = append(dep_ids,
+					get_dependent_ids_rec(dep_ids, elt, false, reclvl+1)...)
+			}
+			break
+		}
+		if sp := get_cxxgo_smartptr(id); sp != nil {
+			// only the class pointed at is needed
+			if !str_is_in_slice(sp.elt.IdScopedName(), dep_ids) {
+				dep_ids = append(dep_ids,
+					get_dependent_ids_rec(dep_ids, sp.elt, false, reclvl+1)...)
+			}
+			break
+		}
+		if get_cxxgo_callback(id) != nil {
+			// only fundamental types and strings are exchanged
+			break
+		}
+		if !rec {
+			// an opaque handle, unless selected
+			break
+		}
 		for _, mbr := range id.Members {
 			mbr_id := cxxtypes.IdByName(mbr.Name)
 			if str_is_in_slice(mbr_id.IdScopedName(), dep_ids) {
@@ -2103,6 +3644,10 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 
 	case *cxxtypes.StructType:
 		//println("**str",id.IdScopedName())
+		if !rec {
+			// an opaque handle, unless selected
+			break
+		}
 		for _, mbr := range id.Members {
 			mbr_id := cxxtypes.IdByName(mbr.Name)
 			if str_is_in_slice(mbr_id.IdScopedName(), dep_ids) {
@@ -2126,13 +3671,17 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 
 	case *cxxtypes.OverloadFunctionSet:
 		for _, fct := range id.Fcts {
+			if unsupported_fct(fct) != "" {
+				// discarded (see new_cxxgo_ovfcts)
+				continue
+			}
 			for i, _ := range fct.Params {
 				arg_id := cxxtypes.IdByName(fct.Params[i].Type)
 				if str_is_in_slice(arg_id.IdScopedName(), dep_ids) {
 					continue
 				}
 				dep_ids = append(dep_ids,
-					get_dependent_ids_rec(dep_ids, arg_id, true, reclvl+1)...)
+					get_dependent_ids_rec(dep_ids, arg_id, false, reclvl+1)...)
 				dep_ids = append(dep_ids, arg_id.IdScopedName())
 			}
 			if fct.Ret != "" && fct.Ret != "void" {
@@ -2141,16 +3690,33 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 					continue
 				}
 				dep_ids = append(dep_ids,
-					get_dependent_ids_rec(dep_ids, ret_id, true, reclvl+1)...)
+					get_dependent_ids_rec(dep_ids, ret_id, false, reclvl+1)...)
 				dep_ids = append(dep_ids, ret_id.IdScopedName())
 			}
 		}
+	case *cxxtypes.TypedefType:
+		tt := id.UnderlyingType().(cxxtypes.Id)
+		if !str_is_in_slice(tt.IdScopedName(), dep_ids) {
+			dep_ids = append(dep_ids,
+				get_dependent_ids_rec(dep_ids, tt, rec, reclvl+1)...)
+		}
+
+	case *cxxtypes.Member:
+		if !id.IsDataMember() || unsupported_type(cxxtypes.IdByName(id.Type)) != "" {
+			break
+		}
+		tt := cxxtypes.IdByName(id.Type)
+		if !str_is_in_slice(tt.IdScopedName(), dep_ids) {
+			dep_ids = append(dep_ids,
+				get_dependent_ids_rec(dep_ids, tt, false, reclvl+1)...)
+		}
+
 	case *cxxtypes.CvrQualType:
 		//println("**cvr",id.IdScopedName(),"-->",id.Type)
 		tt := cxxtypes.IdByName(id.Type)
 		if !str_is_in_slice(tt.IdScopedName(), dep_ids) {
 			dep_ids = append(dep_ids,
-				get_dependent_ids_rec(dep_ids, tt, true, reclvl+1)...)
+				get_dependent_ids_rec(dep_ids, tt, rec, reclvl+1)...)
 			
 		}
 
@@ -2159,7 +3725,7 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 		tt := cxxtypes.IdByName(id.Type)
 		if !str_is_in_slice(tt.IdScopedName(), dep_ids) {
 			dep_ids = append(dep_ids,
-				get_dependent_ids_rec(dep_ids, tt, true, reclvl+1)...)
+				get_dependent_ids_rec(dep_ids, tt, rec, reclvl+1)...)
 		}
 
 	case *cxxtypes.PtrType:
@@ -2167,7 +3733,7 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 		tt := cxxtypes.IdByName(id.Type)
 		if !str_is_in_slice(tt.IdScopedName(), dep_ids) {
 			dep_ids = append(dep_ids,
-				get_dependent_ids_rec(dep_ids, tt, true, reclvl+1)...)
+				get_dependent_ids_rec(dep_ids, tt, rec, reclvl+1)...)
 		}
 	}
 	// // recurse...
@@ -2189,32 +3755,47 @@ func get_dependent_ids_rec(in_ids []string, id cxxtypes.Id, rec bool, reclvl int
 	for k, _ := range set_ids {
 		dep_ids = append(dep_ids, k)
 	}
+	sort.Strings(dep_ids)
 	return dep_ids
 }
 
-var g_id_counter uint64 = 0
-
+// get_iid returns a numeric identifier for id.
+// The identifier is derived from the kind and scoped name of id, so C
+// symbols stay the same from one generation to the next.
 func get_iid(id cxxtypes.Id) uint64 {
-	c, ok := g_idmap[id]
+	key := fmt.Sprintf("%T:%s", id, id.IdScopedName())
+	c, ok := g_idmap[key]
 	if ok {
 		return c
 	}
-	c = g_id_counter
-	g_idmap[id] = c
-	g_id_counter += 1
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	c = h.Sum64()
+	// resolve (unlikely) collisions
+	for {
+		if _, dup := g_iids[c]; !dup {
+			break
+		}
+		c += 1
+	}
+	g_idmap[key] = c
+	g_iids[c] = key
 	return c
 }
 
 func get_iid_str(id cxxtypes.Id) string {
-	return fmt.Sprintf("%v", get_iid(id))
+	return fmt.Sprintf("%x", get_iid(id))
 }
 
 // globals ----------------------
 
-// g_idmap is a global map of Id to some integer to uniquely identify
-// identifiers
+// g_idmap is a global map of Id (kind and scoped name) to some integer to
+// uniquely identify identifiers
 var g_idmap idmap_t
 
+// g_iids is the reverse of g_idmap
+var g_iids map[uint64]string
+
 type cxxgo_idmap_t map[cxxtypes.Id]*cxxgo_id
 
 // g_cxxgo_idmap is a global map of all cxxgo_ids
@@ -2247,7 +3828,7 @@ var g_cxxgo_trans *strings.Replacer = strings.NewReplacer(
 	"*", "_Sp_",
 )
 
-var _go_hdr string = `
+var _go_hdr string = `%s
 package %s
 
 // #include <stdlib.h>
@@ -2255,6 +3836,9 @@ package %s
 // #include "%s"
 // #cgo LDFLAGS: -l%s -l%s
 import "C"
+import "io"
+%[6]simport "runtime"
+import "sync"
 import "unsafe"
 
 // dummy function which uses unsafe
@@ -2262,6 +3846,25 @@ func _gocxx_free_ptr(ptr unsafe.Pointer) {
  C.free(ptr)
 }
 
+// _gocxx_strview_from_go returns a C copy of the Go string s.
+// the copy has to be released with C.free.
+func _gocxx_strview_from_go(s string) C._gocxx_strview {
+  return C._gocxx_strview{p: C.CString(s), n: C.size_t(len(s))}
+}
+
+// _gocxx_strview_to_go converts a C copy of a C++ string into a Go string
+// and releases the C copy.
+func _gocxx_strview_to_go(v C._gocxx_strview) string {
+  s := C.GoStringN(v.p, C.int(v.n))
+  C.free(unsafe.Pointer(v.p))
+  return s
+}
+
+// _gocxx_set_finalizer sets the finalizer of the Go handle obj
+func _gocxx_set_finalizer(obj interface{}, f interface{}) {
+  runtime.SetFinalizer(obj, f)
+}
+
 // _gocxx_int2bool converts a C.int into a Go bool
 func _gocxx_int2bool(i C.int) bool {
   if i != 0 {
@@ -2269,6 +3872,79 @@ func _gocxx_int2bool(i C.int) bool {
   }
   return false
 }
+
+// _gocxx_gofuncs holds the Go funcs called back from C++ (through a
+// std::function), until C++ releases them.
+var _gocxx_gofuncs = struct {
+  sync.Mutex
+  next C.size_t
+  fcts map[C.size_t]func(args, ret unsafe.Pointer)
+}{fcts: make(map[C.size_t]func(args, ret unsafe.Pointer))}
+
+// _gocxx_gofunc_new registers f to be called back from C++, with pointers
+// to the array of pointers to the arguments, and to the result.
+func _gocxx_gofunc_new(f func(args, ret unsafe.Pointer)) C._gocxx_gofunc {
+  _gocxx_gofuncs.Lock()
+  defer _gocxx_gofuncs.Unlock()
+  _gocxx_gofuncs.next++
+  id := _gocxx_gofuncs.next
+  _gocxx_gofuncs.fcts[id] = f
+  return C._gocxx_gofunc{
+    id:      id,
+    call:    C._gocxx_gofunc_call_t(C._gocxx_%[2]s_gofunc_call),
+    release: C._gocxx_gofunc_release_t(C._gocxx_%[2]s_gofunc_release),
+  }
+}
+
+//export _gocxx_%[2]s_gofunc_call
+func _gocxx_%[2]s_gofunc_call(id C.size_t, args, ret unsafe.Pointer) {
+  _gocxx_gofuncs.Lock()
+  f := _gocxx_gofuncs.fcts[id]
+  _gocxx_gofuncs.Unlock()
+  f(args, ret)
+}
+
+//export _gocxx_%[2]s_gofunc_release
+func _gocxx_%[2]s_gofunc_release(id C.size_t) {
+  _gocxx_gofuncs.Lock()
+  delete(_gocxx_gofuncs.fcts, id)
+  _gocxx_gofuncs.Unlock()
+}
+
+// _gocxx_writer_from_go registers w to be written to by a C++ std::ostream.
+// the C++ side gets the number of bytes written, or -1 on error.
+func _gocxx_writer_from_go(w io.Writer) C._gocxx_gofunc {
+  if w == nil {
+    return C._gocxx_gofunc{}
+  }
+  return _gocxx_gofunc_new(func(args, ret unsafe.Pointer) {
+    c_args := unsafe.Slice((*unsafe.Pointer)(args), 2)
+    buf := unsafe.Slice((*byte)(c_args[0]), *(*C.size_t)(c_args[1]))
+    n, err := w.Write(buf)
+    if err != nil {
+      n = -1
+    }
+    *(*C.long)(ret) = C.long(n)
+  })
+}
+
+// _gocxx_reader_from_go registers r to be read from by a C++ std::istream.
+// the C++ side gets the number of bytes read, 0 at the end of the input or
+// on error.
+func _gocxx_reader_from_go(r io.Reader) C._gocxx_gofunc {
+  if r == nil {
+    return C._gocxx_gofunc{}
+  }
+  return _gocxx_gofunc_new(func(args, ret unsafe.Pointer) {
+    c_args := unsafe.Slice((*unsafe.Pointer)(args), 2)
+    buf := unsafe.Slice((*byte)(c_args[0]), *(*C.size_t)(c_args[1]))
+    n, err := 0, error(nil)
+    for n == 0 && err == nil {
+      n, err = r.Read(buf)
+    }
+    *(*C.long)(ret) = C.long(n)
+  })
+}
 `
 
 var _cxx_hdr string = `
@@ -2278,9 +3954,19 @@ var _cxx_hdr string = `
 #include <stdio.h>
 
 // C++ includes
+#include <functional>
+#include <istream>
+#include <iterator>
+#include <memory>
+#include <ostream>
+#include <sstream>
+#include <streambuf>
 #include <string>
+#include <tuple>
+#include <typeinfo>
+#include <utility>
 #include <vector>
-
+%s
 #include "%s"
 
 #include "%s"
@@ -2291,24 +3977,12 @@ extern "C" {
 
 // helpers for CGo runtime
 
-typedef struct { char *p; int n; } _gostring_;
 typedef struct { void* array; unsigned int len; unsigned int cap; } _goslice_;
 
 
 extern void crosscall2(void (*fn)(void *, int), void *, int);
-extern void _cgo_allocate(void *, int);
 extern void _cgo_panic(void *, int);
 
-static void *_gocxx_goallocate(size_t len) {
-  struct {
-    size_t len;
-    void *ret;
-  } a;
-  a.len = len;
-  crosscall2(_cgo_allocate, &a, (int) sizeof a);
-  return a.ret;
-}
-
 static void _gocxx_gopanic(const char *p) {
   struct {
     const char *p;
@@ -2317,20 +3991,84 @@ static void _gocxx_gopanic(const char *p) {
   crosscall2(_cgo_panic, &a, (int) sizeof a);
 }
 
-static _gostring_ _gocxx_makegostring(const char *p, size_t l) {
-  _gostring_ ret;
-  ret.p = (char*)_gocxx_goallocate(l + 1);
-  memcpy(ret.p, p, l);
-  ret.n = l;
-  return ret;
+// stores into v a C copy of the l characters at p, released by the Go side.
+static void _gocxx_strview_set(_gocxx_strview *v, const char *p, size_t l) {
+  char* cstr = (char*)malloc((l+1) * sizeof(char));
+  if (l) { memcpy(cstr, p, l); }
+  cstr[l] = '\0';
+  v->p = cstr;
+  v->n = l;
 }
 
-static char* _gocxx_makecstring(const _gostring_ *s) {
-  char* cstr = (char*)malloc((s->n+1) * sizeof(char));
-  memcpy(cstr, s->p, s->n);
-  cstr[s->n] = '\0';
-  return cstr;
-}
+// holds a Go func called back from C++. the Go func is released along with
+// the last std::function referring to it.
+struct _gocxx_gofunc_ref {
+  _gocxx_gofunc f;
+  _gocxx_gofunc_ref(const _gocxx_gofunc &f) : f(f) {}
+  ~_gocxx_gofunc_ref() { if (f.release) { f.release(f.id); } }
+  void call(void **args, void *ret) const { f.call(f.id, (void*)args, ret); }
+};
+
+// a std::streambuf writing to a Go io.Writer (see _gocxx_writer_from_go)
+class _gocxx_gowriter_buf : public std::streambuf {
+  _gocxx_gofunc_ref m_w;
+  char m_buf[1024];
+public:
+  _gocxx_gowriter_buf(void *c_fct) : m_w(*(_gocxx_gofunc*)c_fct) {
+    setp(m_buf, m_buf + sizeof(m_buf));
+  }
+  ~_gocxx_gowriter_buf() { sync(); }
+  // returns the buffer, or NULL if there is no Go io.Writer
+  std::streambuf* get() { return m_w.f.call ? this : NULL; }
+protected:
+  int overflow(int c) {
+    if (sync() != 0) {
+      return traits_type::eof();
+    }
+    if (!traits_type::eq_int_type(c, traits_type::eof())) {
+      *pptr() = traits_type::to_char_type(c);
+      pbump(1);
+    }
+    return traits_type::not_eof(c);
+  }
+  int sync() {
+    size_t n = pptr() - pbase();
+    if (n == 0 || !m_w.f.call) {
+      return 0;
+    }
+    void *args[] = { (void*)pbase(), (void*)&n };
+    long nw = -1;
+    m_w.call(args, (void*)&nw);
+    setp(m_buf, m_buf + sizeof(m_buf));
+    return nw == (long)n ? 0 : -1;
+  }
+};
+
+// a std::streambuf reading from a Go io.Reader (see _gocxx_reader_from_go).
+// the bytes read from Go but not consumed by C++ are lost.
+class _gocxx_goreader_buf : public std::streambuf {
+  _gocxx_gofunc_ref m_r;
+  char m_buf[1024];
+public:
+  _gocxx_goreader_buf(void *c_fct) : m_r(*(_gocxx_gofunc*)c_fct) {}
+  // returns the buffer, or NULL if there is no Go io.Reader
+  std::streambuf* get() { return m_r.f.call ? this : NULL; }
+protected:
+  int underflow() {
+    if (gptr() < egptr()) {
+      return traits_type::to_int_type(*gptr());
+    }
+    size_t n = sizeof(m_buf);
+    void *args[] = { (void*)m_buf, (void*)&n };
+    long nr = 0;
+    m_r.call(args, (void*)&nr);
+    if (nr <= 0) {
+      return traits_type::eof();
+    }
+    setg(m_buf, m_buf, m_buf + nr);
+    return traits_type::to_int_type(*gptr());
+  }
+};
 
 #define GOCXX_contract_assert(expr, msg) \
   if (!(expr)) { _gocxx_gopanic(msg); } else
@@ -2356,6 +4094,21 @@ typedef bool _gocxx_bool_t;
 #else
 typedef int _gocxx_bool_t;
 #endif
+
+/* a view on the characters of a string, to convert strings (and STL
+ * containers of strings) from/to Go */
+typedef struct { const char *p; size_t n; } _gocxx_strview;
+
+/* a Go func called back from C++ through a std::function */
+typedef void (*_gocxx_gofunc_call_t)(size_t id, void *args, void *ret);
+typedef void (*_gocxx_gofunc_release_t)(size_t id);
+typedef struct {
+  size_t id;
+  _gocxx_gofunc_call_t call;
+  _gocxx_gofunc_release_t release;
+} _gocxx_gofunc;
+void _gocxx_%[1]s_gofunc_call(size_t id, void *args, void *ret);
+void _gocxx_%[1]s_gofunc_release(size_t id);
 `
 
 var _go_footer string = `
@@ -2391,7 +4144,6 @@ var _cxx2cgo_typemap = map[string]string{
 }
 
 var _cxx2go_typemap = map[string]string{
-	"void":     "byte", //FIXME
 	"uint64_t": "uint64",
 	"uint32_t": "uint32",
 	"uint16_t": "uint16",
@@ -2402,86 +4154,28 @@ var _cxx2go_typemap = map[string]string{
 	"int16_t":  "int16",
 	"int8_t":   "int8",
 
-	"bool":           "bool",
-	"char":           "byte",
-	"signed char":    "int8",
-	"unsigned char":  "byte",
-	"short":          "int16",
-	"unsigned short": "uint16",
-	"int":            "int",
-	"unsigned int":   "uint",
-
-	"char*":       "string",
-	"const char*": "string",
-	"char const*": "string",
-
-	// FIXME: 32/64 platforms... (and cross-compilation)
-	//"long":           "int32",
-	//"unsigned long":  "uint32",
-	"long":          "int64",
-	"unsigned long": "uint64",
-
-	"long long":          "int64",
-	"unsigned long long": "uint64",
-
-	"float":  "float32",
-	"double": "float64",
-
-	"float complex":  "complex64",
-	"double complex": "complex128",
-
-	// FIXME: 32/64 platforms
-	//"size_t": "int",
-	"size_t":      "int64",
-	"std::size_t": "int64",
-
-	// stl
-	"std::string": "string",
-
-	"std::ptrdiff_t": "int64",   //FIXME !!
-	"std::ostream":   "uintptr", //FIXME !!
-
-	// ROOT types
-	"Char_t":   "byte",
-	"UChar_t":  "byte",
-	"Short_t":  "int16",
-	"UShort_t": "uint16",
-	"Int_t":    "int",
-	"UInt_t":   "uint",
-
-	"Seek_t":     "int",
-	"Long_t":     "int64",
-	"ULong_t":    "uint64",
-	"Float_t":    "float32",
-	"Float16_t":  "float32", //FIXME
-	"Double_t":   "float64",
-	"Double32_t": "float64",
-
-	"Bool_t":    "bool",
-	"Text_t":    "byte",
-	"Byte_t":    "byte",
-	"Version_t": "int16",
-	"Option_t":  "byte",
-	"Ssiz_t":    "int",
-	"Real_t":    "float32",
-	"Long64_t":  "int64",
-	"ULong64_t": "uint64",
-	"Axis_t":    "float64",
-	"Stat_t":    "float64",
-	"Font_t":    "int16",
-	"Style_t":   "int16",
-	"Marker_t":  "int16",
-	"Width_t":   "int16",
-	"Color_t":   "int16",
-	"SCoord_t":  "int16",
-	"Coord_t":   "float64",
-	"Angle_t":   "float32",
-	"Size_t":    "float32",
+	// fundamental types are mapped from their size and kind.
+	// (see gen_go_fundamental_name)
+	// typedefs to fundamental types (size_t, ptrdiff_t, ROOT's Int_t, ...)
+	// are resolved through their underlying type.
+
+	"bool": "bool",
+
+	// strings are mapped from their kind (see get_cxxgo_string)
+	// streams are bridged to Go writers and readers (see strip_stream)
 }
 
+// _cxx2go_builtins holds the initial content of _cxx2go_typemap, which
+// gets the Go names of the selected identifiers during a generation
+var _cxx2go_builtins = make(map[string]string)
+
 func init() {
+	for k, v := range _cxx2go_typemap {
+		_cxx2go_builtins[k] = v
+	}
 	wrapper.RegisterPlugin(&plugin{})
 	g_idmap = make(idmap_t)
+	g_iids = make(map[uint64]string)
 	g_cxxgo_idmap = make(cxxgo_idmap_t)
 }
 
@@ -0,0 +1,2689 @@
+package cxxgo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+	"github.com/sbinet/go-cxxdict/pkg/wrapper"
+)
+
+// new_test_registry resets the global registry and fills it with the base
+// fixture, completed with the fixtures of the tested features.
+func new_test_registry(fixtures ...func()) {
+	cxxtypes.ResetIds()
+	fill_base_registry()
+	for _, fill := range fixtures {
+		fill()
+	}
+}
+
+// test_fixtures are the fixtures of all the tested features.
+var test_fixtures = []func(){
+	fill_overloads_registry,
+	fill_defaults_registry,
+	fill_containers_registry,
+	fill_views_registry,
+	fill_strings_registry,
+	fill_smartptr_registry,
+	fill_compound_registry,
+	fill_streams_registry,
+	fill_templates_registry,
+	fill_namespaces_registry,
+	fill_nested_registry,
+	fill_typedefs_registry,
+	fill_opaque_registry,
+	fill_diamond_registry,
+	fill_inherited_registry,
+	fill_protected_registry,
+	fill_copy_registry,
+	fill_values_registry,
+	fill_outparams_registry,
+	fill_status_registry,
+}
+
+// fill_base_registry populates the global registry with a few classes, with
+// overloads of various arities.
+func fill_base_registry() {
+	cxxtypes.NewNamespace("", "")
+	cxxtypes.NewFundamentalType("int", 4, cxxtypes.TK_Int, "::")
+	cxxtypes.NewFundamentalType("double", 8, cxxtypes.TK_Double, "::")
+	cxxtypes.NewFundamentalType("bool", 1, cxxtypes.TK_Bool, "::")
+	cxxtypes.NewFundamentalType("void", 0, cxxtypes.TK_Void, "::")
+
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	op := m | cxxtypes.TS_Operator
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+	d := cxxtypes.Parameter{Name: "d", Type: "double"}
+
+	for _, n := range []string{"Foo", "Base", "Class"} {
+		cls := cxxtypes.NewClassType(n, 8, "::")
+		cxxtypes.NewQualType(n+" const", n, "::", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "::")
+		cxxtypes.NewRefType(n+"&", n, "::")
+		rhs := cxxtypes.Parameter{Name: "rhs", Type: n + " const&"}
+		fcts := []*cxxtypes.Function{
+			cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, nil, "void", n),
+			cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, []cxxtypes.Parameter{i}, "void", n),
+			cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, []cxxtypes.Parameter{i, d}, "void", n),
+			cxxtypes.NewFunction(n+"::~"+n, 0, m|cxxtypes.TS_Destructor, pub, false, nil, "void", n),
+			cxxtypes.NewFunction(n+"::operator<", cxxtypes.TQ_Const, op, pub, false, []cxxtypes.Parameter{rhs}, "bool", n),
+			cxxtypes.NewFunction(n+"::operator-", cxxtypes.TQ_Const, op, pub, false, []cxxtypes.Parameter{rhs}, n, n),
+			cxxtypes.NewFunction(n+"::operator-", cxxtypes.TQ_Const, op, pub, false, nil, n, n),
+			cxxtypes.NewFunction(n+"::set", 0, m, pub, false, nil, "void", n),
+			cxxtypes.NewFunction(n+"::set", 0, m, pub, false, []cxxtypes.Parameter{i}, "void", n),
+			cxxtypes.NewFunction(n+"::set", 0, m, pub, false, []cxxtypes.Parameter{i, d}, "void", n),
+			cxxtypes.NewFunction(n+"::reset", 0, m, pub, false, []cxxtypes.Parameter{*cxxtypes.NewParameter("i", "int", "42")}, "void", n),
+		}
+		mbrs := []cxxtypes.Member{}
+		seen := map[string]bool{}
+		for _, f := range fcts {
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+		}
+		cls.SetMembers(mbrs)
+	}
+}
+
+// generate runs the generator in dir and returns the content of the
+// generated files, indexed by file name.
+func generate(t *testing.T, dir string, args map[string]interface{}) map[string][]byte {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("could not get working directory: %v", err)
+	}
+	defer os.Chdir(pwd)
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatalf("could not change directory: %v", err)
+	}
+
+	gen := wrapper.NewGenerator()
+	gen.Fd.Name = "mylib"
+	gen.Fd.Package = "mylib"
+	gen.Fd.Header = "mylib.hh"
+	for k, v := range args {
+		gen.Args[k] = v
+	}
+	err = gen.GenerateAllFiles()
+	if err != nil {
+		t.Fatalf("could not generate files: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, ext := range []string{".go", ".cxx", ".h"} {
+		fname := "mylib_cxxgo.plugin" + ext
+		buf, err := ioutil.ReadFile(filepath.Join(dir, fname))
+		if err != nil {
+			t.Fatalf("could not read [%s]: %v", fname, err)
+		}
+		files[fname] = buf
+	}
+	return files
+}
+
+// gen_files returns the files generated from the test registry with the
+// arguments args, in a temporary directory.
+func gen_files(t *testing.T, args map[string]interface{}) map[string][]byte {
+	dir, err := ioutil.TempDir("", "go-cxxdict-")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	return generate(t, dir, args)
+}
+
+// check_code checks that the generated file fname contains all the strings
+// of expected and none of absent. The error messages are prefixed with
+// label, if any.
+func check_code(t *testing.T, label string, files map[string][]byte, fname string, expected, absent []string) {
+	t.Helper()
+	if label != "" {
+		label += ": "
+	}
+	code := string(files[fname])
+	for _, str := range expected {
+		if !strings.Contains(code, str) {
+			t.Errorf("%sexpected [%s] in [%s]", label, str, fname)
+		}
+	}
+	for _, str := range absent {
+		if strings.Contains(code, str) {
+			t.Errorf("%sexpected no [%s] in [%s]", label, str, fname)
+		}
+	}
+}
+
+// typecheck type-checks the generated Go code, completed with the Go
+// declarations of probes (e.g. "var _ Base = GocxxcptrD2(0)"), and returns
+// the type errors, as well as the impossible interface-to-interface type
+// assertions (as reported by go vet).
+// The "C" pseudo-package is faked: the errors stemming from the (unknown)
+// types of its declarations are ignored.
+func typecheck(t *testing.T, code []byte, probes ...string) []error {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "mylib_cxxgo.plugin.go", code, 0)
+	if err != nil {
+		t.Fatalf("could not parse [mylib_cxxgo.plugin.go]: %v", err)
+	}
+	probe, err := parser.ParseFile(fset, "probes.go",
+		"package mylib\n"+strings.Join(probes, "\n")+"\n", 0)
+	if err != nil {
+		t.Fatalf("could not parse probes: %v", err)
+	}
+	errs := []error{}
+	conf := types.Config{
+		FakeImportC: true,
+		Importer:    importer.Default(),
+		Error: func(err error) {
+			if !strings.Contains(err.Error(), "invalid type") {
+				errs = append(errs, err)
+			}
+		},
+	}
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf.Check("mylib", fset, []*ast.File{f, probe}, info)
+
+	// x.(T) and the cases of the type switches on x
+	assert := func(x ast.Expr, typ ast.Expr) {
+		v, ok := info.TypeOf(x).Underlying().(*types.Interface)
+		if !ok || info.TypeOf(typ) == nil {
+			return
+		}
+		t, ok := info.TypeOf(typ).Underlying().(*types.Interface)
+		if !ok {
+			return
+		}
+		if m, wrong := types.MissingMethod(v, t, false); wrong {
+			errs = append(errs, fmt.Errorf("%v: impossible type assertion: %v and %v have conflicting methods (%s)",
+				fset.Position(typ.Pos()), info.TypeOf(x), info.TypeOf(typ), m.Name()))
+		}
+	}
+	for _, f := range []*ast.File{f, probe} {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.TypeAssertExpr:
+				if n.Type != nil {
+					assert(n.X, n.Type)
+				}
+			case *ast.TypeSwitchStmt:
+				var x ast.Expr
+				switch stmt := n.Assign.(type) {
+				case *ast.AssignStmt:
+					x = stmt.Rhs[0].(*ast.TypeAssertExpr).X
+				case *ast.ExprStmt:
+					x = stmt.X.(*ast.TypeAssertExpr).X
+				}
+				for _, c := range n.Body.List {
+					for _, typ := range c.(*ast.CaseClause).List {
+						assert(x, typ)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return errs
+}
+
+func TestReproducibleOutput(t *testing.T) {
+	new_test_registry(test_fixtures...)
+
+	top, err := ioutil.TempDir("", "go-cxxdict-")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(top)
+
+	var ref map[string][]byte
+	for i := 0; i < 2; i++ {
+		dir := filepath.Join(top, fmt.Sprintf("gen-%d", i))
+		err = os.Mkdir(dir, 0755)
+		if err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		files := generate(t, dir, nil)
+		if ref == nil {
+			ref = files
+			continue
+		}
+		for fname, buf := range files {
+			if !bytes.Equal(ref[fname], buf) {
+				t.Errorf("expected identical [%s] across generations", fname)
+			}
+		}
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	new_test_registry(test_fixtures...)
+
+	// the whole generated package type-checks, whatever the arguments
+	for _, args := range []map[string]interface{}{
+		nil,
+		{"overloads": "typed"},
+		{"overloads": "both"},
+		{"views": "TSamples,TCoords,TNorm,TScaleAll"},
+		{"select": "Copy*"},
+		{"nested-sep": "_"},
+		{"typedefs": "alias"},
+		{"namespaces": "strip", "ns-strip": "Math"},
+		{"templates": "Box, Cell, twice", "generics": true},
+		{"using": "D1::set", "overloads": "typed"},
+		{"out-refs": "out", "errors": "TStatus>=0,int==0@TInit,TCode==TOk,int==0@TAlg::*"},
+		{"out-refs": "inout", "params": "TGetRange(lo)=out,TGetRange(hi)=out"},
+	} {
+		files := gen_files(t, args)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"]) {
+			t.Errorf("args=%v: type error: %v", args, err)
+		}
+	}
+}
+
+// fill_overloads_registry populates the global registry with an overloaded
+// free function.
+func fill_overloads_registry() {
+	if cxxtypes.IdByName("TCompute") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+	d := cxxtypes.Parameter{Name: "d", Type: "double"}
+
+	cxxtypes.NewFunction("TCompute", 0, 0, pub, false, nil, "double", "::")
+	cxxtypes.NewFunction("TCompute", 0, 0, pub, false, []cxxtypes.Parameter{i}, "double", "::")
+	cxxtypes.NewFunction("TCompute", 0, 0, pub, false, []cxxtypes.Parameter{i, d}, "double", "::")
+}
+
+func TestTypedOverloads(t *testing.T) {
+	new_test_registry(fill_overloads_registry)
+
+	for _, table := range []struct {
+		mode     string
+		expected []string
+		absent   []string
+		probes   []string
+	}{
+		{
+			mode: "dispatch",
+			expected: []string{
+				"func (p GocxxcptrFoo)Set(args ...interface{})",
+				"func TCompute(args ...interface{}) float64",
+			},
+			absent: []string{
+				"func (p GocxxcptrFoo)SetInt32(arg_0 int32)",
+			},
+			probes: []string{
+				"var _ float64 = TCompute(int32(1), 2.0)",
+				"func setFoo(f Foo) { f.Set(int32(1)) }",
+			},
+		},
+		{
+			mode: "typed",
+			expected: []string{
+				"func (p GocxxcptrFoo)Set()",
+				"func (p GocxxcptrFoo)SetInt32(arg_0 int32)",
+				"func (p GocxxcptrFoo)SetInt32Float64(arg_0 int32, arg_1 float64)",
+				"\tSetInt32Float64(arg_0 int32, arg_1 float64)\n",
+				"func NewFooInt32(arg_0 int32) Foo",
+				"func TComputeInt32Float64(arg_0 int32, arg_1 float64) float64",
+			},
+			absent: []string{
+				"...interface{}",
+				"__GOCXX_",
+			},
+			probes: []string{
+				"var _ float64 = TComputeInt32Float64(1, 2) + TComputeInt32(1) + TCompute()",
+				"var _ Foo = NewFooInt32Float64(1, 2)",
+				"func setFoo(f Foo) { f.Set(); f.SetInt32(1); f.SetInt32Float64(1, 2) }",
+			},
+		},
+		{
+			mode: "both",
+			expected: []string{
+				"func (p GocxxcptrFoo)Set_0()",
+				"func (p GocxxcptrFoo)SetInt32(arg_0 int32)",
+				"\tSetInt32(arg_0 int32)\n",
+				"func (p GocxxcptrFoo)Set(args ...interface{})",
+				"\tSet(args ...interface{})\n",
+				"\t\tp.SetInt32(arg_0)\n",
+			},
+			probes: []string{
+				"func setFoo(f Foo) { f.Set_0(); f.SetInt32(1); f.Set(int32(1), 2.0) }",
+			},
+		},
+	} {
+		files := gen_files(t, map[string]interface{}{"overloads": table.mode})
+		check_code(t, table.mode, files, "mylib_cxxgo.plugin.go", table.expected, table.absent)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"], table.probes...) {
+			t.Errorf("%s: type error: %v", table.mode, err)
+		}
+	}
+}
+
+// fill_defaults_registry populates the global registry with functions
+// with default values of their parameters.
+func fill_defaults_registry() {
+	if cxxtypes.IdByName("TScale") != nil {
+		return
+	}
+	fill_namespaces_registry()
+
+	pub := cxxtypes.AS_Public
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	cxxtypes.NewFunction("TScale", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			*cxxtypes.NewParameter("x", "double", ""),
+			*cxxtypes.NewParameter("n", "int", "2"),
+			*cxxtypes.NewParameter("f", "double", "0.5"),
+		},
+		"double", "::")
+
+	// a default value flagged w/o its expression (older registry files)
+	cxxtypes.NewFunction("TEmptyDefault", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "n", Type: "int", DefVal: true}}, "int", "::")
+
+	cxxtypes.NewFunction("TShift", 0, 0, pub, false, []cxxtypes.Parameter{i}, "int", "::")
+	cxxtypes.NewFunction("TShift", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			*cxxtypes.NewParameter("x", "double", ""),
+			*cxxtypes.NewParameter("n", "int", "1"),
+		},
+		"int", "::")
+
+	// default values naming an enumerator of their namespace
+	cxxtypes.NewEnumType("Math::Mode", []cxxtypes.Member{
+		cxxtypes.NewMember("Math::Fast", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, pub, 0, "Math"),
+	}, "Math")
+	cxxtypes.NewFunction("Math::tune", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			*cxxtypes.NewParameter("n", "int", ""),
+			*cxxtypes.NewParameter("m", "int", "Fast"),
+			*cxxtypes.NewParameter("k", "int", "Math::Fast + 1"),
+		},
+		"int", "Math")
+	// kHidden is not in the registry
+	cxxtypes.NewFunction("Math::retune", 0, 0, pub, false,
+		[]cxxtypes.Parameter{*cxxtypes.NewParameter("n", "int", "kHidden")},
+		"int", "Math")
+}
+
+func TestDefaultArguments(t *testing.T) {
+	new_test_registry(fill_defaults_registry)
+
+	for _, table := range []struct {
+		mode     string
+		fname    string
+		expected []string
+		probes   []string
+	}{
+		{
+			mode:  "typed",
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"func TScale(arg_0 float64, opts ...TScaleOpt) float64",
+				"func TScaleWithN(v int32) TScaleOpt",
+				"func TScaleWithF(v float64) TScaleOpt",
+				"\tReset(opts ...FooResetOpt)\n",
+				"func (p GocxxcptrFoo)Reset(opts ...FooResetOpt)",
+				"func TShiftFloat64(arg_0 float64, opts ...TShiftFloat64Opt) int",
+				"func Math_tune(arg_0 int32, opts ...Math_tuneOpt) int32",
+				"func Math_retune(arg_0 int32) int32",
+				"func TEmptyDefault(arg_0 int32) int32",
+			},
+			probes: []string{
+				"var _ float64 = TScale(1) + TScale(1, TScaleWithF(0.1), TScaleWithN(3))",
+				"var _ int32 = Math_tune(1, Math_tuneWithK(2)) + Math_retune(1) + TEmptyDefault(1)",
+				"var _ int32 = TShiftInt32(1) + TShiftFloat64(1)",
+			},
+		},
+		{
+			mode:  "typed",
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"TScale(*cxx_arg_0, (c_arg_1 ? (*cxx_arg_1) : (2)), (c_arg_2 ? (*cxx_arg_2) : (0.5)));",
+				"cxx_this->reset((c_arg_0 ? (*cxx_arg_0) : (42)));",
+				// the default values are evaluated from the global scope
+				"Math::tune(*cxx_arg_0, (c_arg_1 ? (*cxx_arg_1) : (::Math::Fast)), (c_arg_2 ? (*cxx_arg_2) : (Math::Fast + 1)));",
+				// unresolved names: the parameter is required
+				"Math::retune(*cxx_arg_0);",
+				"TEmptyDefault(*cxx_arg_0);",
+			},
+		},
+		{
+			mode:  "dispatch",
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"func TScale(arg_0 float64, opts ...TScaleOpt) float64",
+				"\t\treturn TShift__GOCXX_1(arg_0, TShift_1WithN(arg_1))\n",
+			},
+			probes: []string{
+				"var _ int32 = TShift(1.0) + TShift(1.0, int32(2)) + TShift(int32(1))",
+			},
+		},
+	} {
+		files := gen_files(t, map[string]interface{}{"overloads": table.mode})
+		check_code(t, table.mode, files, table.fname, table.expected, nil)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"], table.probes...) {
+			t.Errorf("[%s]: type error: %v", table.mode, err)
+		}
+		// one C++ wrapper per C++ function, however many default values
+		if n := strings.Count(string(files[table.fname]), "wraps [double TScale("); n != 1 {
+			t.Errorf("[%s]: expected 1 wrapper for TScale in [%s], got %d",
+				table.mode, table.fname, n)
+		}
+	}
+}
+
+func TestFundamentalTypes(t *testing.T) {
+	for _, table := range []struct {
+		size     uintptr
+		kind     cxxtypes.TypeKind
+		expected string
+	}{
+		{1, cxxtypes.TK_Bool, "bool"},
+		{1, cxxtypes.TK_Char_S, "byte"},
+		{1, cxxtypes.TK_SChar, "int8"},
+		{1, cxxtypes.TK_UChar, "uint8"},
+		{2, cxxtypes.TK_Short, "int16"},
+		{2, cxxtypes.TK_UShort, "uint16"},
+		{4, cxxtypes.TK_Int, "int32"},
+		{4, cxxtypes.TK_UInt, "uint32"},
+		{4, cxxtypes.TK_Long, "int32"},
+		{8, cxxtypes.TK_Long, "int64"},
+		{4, cxxtypes.TK_ULong, "uint32"},
+		{8, cxxtypes.TK_ULong, "uint64"},
+		{8, cxxtypes.TK_LongLong, "int64"},
+		{8, cxxtypes.TK_ULongLong, "uint64"},
+		{4, cxxtypes.TK_Float, "float32"},
+		{8, cxxtypes.TK_Double, "float64"},
+		{8, cxxtypes.TK_LongDouble, "float64"},
+		{16, cxxtypes.TK_LongDouble, ""},
+		{16, cxxtypes.TK_Int128, ""},
+		{8, cxxtypes.TK_Complex, "complex64"},
+		{16, cxxtypes.TK_Complex, "complex128"},
+	} {
+		id := &cxxtypes.FundamentalType{
+			BaseType: cxxtypes.BaseType{
+				Name: "builtin",
+				Size: table.size,
+				Kind: table.kind,
+			},
+		}
+		n := gen_go_fundamental_name(id)
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] (size=%d, kind=%v)",
+				table.expected, n, table.size, table.kind)
+		}
+	}
+}
+
+func TestTypedefResolution(t *testing.T) {
+	new_test_registry()
+	cxxtypes.NewFundamentalType("long", 8, cxxtypes.TK_Long, "::")
+	cxxtypes.NewFundamentalType("unsigned long", 8, cxxtypes.TK_ULong, "::")
+	cxxtypes.NewTypedefType("size_t", "unsigned long", 8, "::")
+	cxxtypes.NewTypedefType("std::size_t", "size_t", 8, "std")
+	cxxtypes.NewTypedefType("std::ptrdiff_t", "long", 8, "std")
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"size_t", "uint64"},
+		{"std::size_t", "uint64"},
+		{"std::ptrdiff_t", "int64"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	host := cxxtypes.CurrentTarget()
+	defer cxxtypes.SetTarget(host)
+
+	for _, table := range []struct {
+		target     string
+		wchar_size uintptr
+		wchar      string
+		constraint string
+	}{
+		{"linux/amd64", 4, "int32", "//go:build linux && amd64\n// +build linux,amd64\n"},
+		{"windows/amd64", 2, "uint16", "//go:build windows && amd64\n// +build windows,amd64\n"},
+		{"linux/arm64", 4, "uint32", "//go:build linux && arm64\n// +build linux,arm64\n"},
+	} {
+		tgt, err := cxxtypes.TargetByName(table.target)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		cxxtypes.SetTarget(tgt)
+
+		id := &cxxtypes.FundamentalType{
+			BaseType: cxxtypes.BaseType{
+				Name: "wchar_t",
+				Size: table.wchar_size,
+				Kind: cxxtypes.TK_WChar,
+			},
+		}
+		if n := gen_go_fundamental_name(id); n != table.wchar {
+			t.Errorf("[%s]: expected [%s], got [%s] for wchar_t",
+				table.target, table.wchar, n)
+		}
+
+		if c := gen_go_build_constraint(tgt); c != table.constraint {
+			t.Errorf("[%s]: expected build constraint [%s], got [%s]",
+				table.target, table.constraint, c)
+		}
+	}
+}
+
+func TestOperatorNames(t *testing.T) {
+	new_test_registry()
+	m := cxxtypes.TS_Method
+	op := cxxtypes.TS_Operator
+	pub := cxxtypes.AS_Public
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+	p := cxxtypes.Parameter{Name: "p", Type: "void*"}
+	n := cxxtypes.Parameter{Name: "n", Type: "unsigned long"}
+	for _, table := range []struct {
+		name     string
+		spec     cxxtypes.TypeSpecifier
+		params   []cxxtypes.Parameter
+		expected string
+	}{
+		// unary vs binary, as member and free functions
+		{"operator-", m | op, nil, "Neg"},
+		{"operator-", m | op, []cxxtypes.Parameter{i}, "Sub"},
+		{"operator-", op, []cxxtypes.Parameter{i}, "Neg"},
+		{"operator-", op, []cxxtypes.Parameter{i, i}, "Sub"},
+		{"operator+", m | op, nil, "Plus"},
+		{"operator+", op, []cxxtypes.Parameter{i, i}, "Add"},
+		{"operator*", m | op, nil, "Deref"},
+		{"operator*", m | op, []cxxtypes.Parameter{i}, "Mul"},
+		{"operator&", op, []cxxtypes.Parameter{i}, "Addr"},
+		{"operator&", m | op, []cxxtypes.Parameter{i}, "And"},
+		// prefix vs postfix (w/ a dummy int)
+		{"operator++", m | op, nil, "Inc"},
+		{"operator++", m | op, []cxxtypes.Parameter{i}, "PostInc"},
+		{"operator--", op, []cxxtypes.Parameter{i}, "Dec"},
+		{"operator--", op, []cxxtypes.Parameter{i, i}, "PostDec"},
+		{"operator[]", m | op, []cxxtypes.Parameter{i}, "Index"},
+		{"operator()", m | op, []cxxtypes.Parameter{i, i}, "Call"},
+		// not wrapped: discarded with a diagnostic
+		{"operator new", m | op | cxxtypes.TS_Static, []cxxtypes.Parameter{n}, ""},
+		{"operator new []", m | op | cxxtypes.TS_Static, []cxxtypes.Parameter{n}, ""},
+		{"operator delete", m | op | cxxtypes.TS_Static, []cxxtypes.Parameter{p}, ""},
+		{"operator delete []", m | op | cxxtypes.TS_Static, []cxxtypes.Parameter{p}, ""},
+		{"operator int", m | cxxtypes.TS_Converter, nil, ""},
+		{"operator co_await", m | op, nil, ""},
+		{"operator\"\"_km", op, []cxxtypes.Parameter{n}, ""},
+	} {
+		// out of the selected scopes of the fixture
+		scope := "OpNames"
+		if (table.spec & m) != 0 {
+			scope = "OpNames::C"
+		}
+		name := scope + "::" + table.name
+		fct := cxxtypes.NewFunction(name, 0, table.spec, pub, false, table.params, "int", scope)
+		if o := gen_go_operator_name(fct); o != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s] (%d params)",
+				table.expected, o, fct.IdScopedName(), len(table.params))
+		}
+	}
+}
+
+func TestTemplateArgs(t *testing.T) {
+	for _, table := range []struct {
+		name     string
+		expected []string
+	}{
+		{"Foo", nil},
+		{"std::vector<int, std::allocator<int> >", []string{"int", "std::allocator<int>"}},
+		{"std::map<int, std::pair<int, double> >", []string{"int", "std::pair<int, double>"}},
+		{"std::vector<void (*)(int, int)>", []string{"void (*)(int, int)"}},
+	} {
+		args := template_args(table.name)
+		if strings.Join(args, "|") != strings.Join(table.expected, "|") {
+			t.Errorf("expected %q, got %q for [%s]", table.expected, args, table.name)
+		}
+	}
+}
+
+// fill_containers_registry populates the global registry with STL
+// containers of fundamental types, strings and classes.
+func fill_containers_registry() {
+	if cxxtypes.IdByName("TRange") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	if cxxtypes.IdByName("std::basic_string<char>") == nil {
+		cxxtypes.NewClassType("std::basic_string<char>", 8, "::")
+	}
+	vi := "std::vector<int, std::allocator<int> >"
+	vs := "std::vector<std::basic_string<char> >"
+	msd := "std::map<std::basic_string<char>, double>"
+	si := "std::set<int>"
+	vfoo := "std::vector<Foo>"
+	mfoo := "std::map<int, Foo>"
+	for _, n := range []string{vi, vs, msd, si, vfoo, mfoo} {
+		cxxtypes.NewClassType(n, 24, "std")
+		cxxtypes.NewQualType(n+" const", n, "std", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "std")
+		cxxtypes.NewRefType(n+"&", n, "std")
+	}
+	n := cxxtypes.Parameter{Name: "n", Type: "int"}
+	cxxtypes.NewFunction("TRange", 0, 0, pub, false, []cxxtypes.Parameter{n}, vi, "::")
+	cxxtypes.NewFunction("TSum", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: vi + " const&"}}, "double", "::")
+	cxxtypes.NewFunction("TFill", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: vi + "&"}, n}, "void", "::")
+	cxxtypes.NewFunction("TTable", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "names", Type: vs + " const&"}}, msd, "::")
+	cxxtypes.NewFunction("TUniq", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: vi}}, si, "::")
+	cxxtypes.NewFunction("TFoos", 0, 0, pub, false, []cxxtypes.Parameter{n}, vfoo, "::")
+	cxxtypes.NewFunction("TCount", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "m", Type: mfoo + " const&"}}, "int", "::")
+}
+
+func TestContainers(t *testing.T) {
+	new_test_registry(fill_containers_registry)
+
+	files := gen_files(t, nil)
+	for _, table := range []struct {
+		fname    string
+		expected []string
+		absent   []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"func TRange(arg_0 int32) []int32",
+				"func TSum(arg_0 []int32) float64",
+				"func TFill(arg_0 *[]int32, arg_1 int32)",
+				"func TTable(arg_0 []string) map[string]float64",
+				"func TUniq(arg_0 []int32) []int32",
+				"func TFoos(arg_0 int32) VectorFoo",
+				"func TCount(arg_0 MapInt32Foo) int32",
+				"func NewVectorFoo() VectorFoo",
+				"\tAt(i int) Foo\n",
+				"\tPush(v Foo)\n",
+				"\tRange(f func(i int, v Foo) bool)\n",
+				"\tAt(k int32) Foo\n",
+				"\tRange(f func(k int32, v Foo) bool)\n",
+			},
+			absent: []string{
+				// the STL internals are not wrapped
+				"type Std_vector",
+				"type Std_map",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  *(void**)c_ret = (void*)new std::vector<int, std::allocator<int> >(TRange(*cxx_arg_0));\n",
+				"    cxx_self->insert(cxx_self->end(), ((int*)c_elts)[i]);\n",
+				"    (*cxx_self)[std::string(((_gocxx_strview*)c_keys)[i].p, ((_gocxx_strview*)c_keys)[i].n)] = ((double*)c_vals)[i];\n",
+				"    ((_gocxx_strview*)c_elts)[i].p = (*it).data(); ((_gocxx_strview*)c_elts)[i].n = (*it).size();\n",
+				"  cxx_self->insert(std::map<int, Foo>::value_type(((int*)c_key)[0], *(Foo*)c_elt));\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, table.absent)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ float64 = TSum(TRange(3))",
+		"var _ map[string]float64 = TTable([]string{\"a\", \"b\"})",
+		"func fill(v []int32) []int32 { TFill(&v, 2); return TUniq(v) }",
+		"func foos() Foo { v := NewVectorFoo(); v.Push(TFoos(1).At(0)); return v.At(0) }",
+		"func count(m MapInt32Foo) int32 { m.Range(func(k int32, v Foo) bool { return true }); return TCount(m) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_views_registry populates the global registry with functions
+// exchanging contiguous storage: vectors, arrays and pointer+length pairs.
+func fill_views_registry() {
+	if cxxtypes.IdByName("TSamples") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	d := cxxtypes.Parameter{Name: "d", Type: "double"}
+
+	cxxtypes.NewFundamentalType("unsigned long", 8, cxxtypes.TK_ULong, "::")
+	cxxtypes.NewQualType("double const", "double", "::", cxxtypes.TQ_Const)
+	cxxtypes.NewPtrType("double const*", "double const", "::")
+	cxxtypes.NewPtrType("double*", "double", "::")
+	vd := "std::vector<double, std::allocator<double> >"
+	ai := "std::array<int, 3ul>"
+	for _, n := range []string{vd, ai} {
+		cxxtypes.NewClassType(n, 24, "std")
+		cxxtypes.NewQualType(n+" const", n, "std", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "std")
+		cxxtypes.NewRefType(n+"&", n, "std")
+	}
+	cxxtypes.NewFunction("TSamples", 0, 0, pub, false, nil, vd+" const&", "::")
+	cxxtypes.NewFunction("TCoords", 0, 0, pub, false, nil, ai+"&", "::")
+	cxxtypes.NewFunction("TNorm", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			{Name: "data", Type: "double const*"},
+			{Name: "n", Type: "unsigned long"},
+		},
+		"double", "::")
+	cxxtypes.NewFunction("TScaleAll", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			{Name: "data", Type: "double*"},
+			{Name: "n", Type: "unsigned long"},
+			d,
+		},
+		"void", "::")
+}
+
+func TestViews(t *testing.T) {
+	new_test_registry(fill_views_registry)
+
+	for _, table := range []struct {
+		views    interface{}
+		expected []string
+		absent   []string
+		probes   []string
+	}{
+		{
+			views: nil,
+			expected: []string{
+				"func TSamples() []float64",
+				"func TCoords() *[]int32",
+				"func TNorm(arg_0 *float64, arg_1 uint64) float64",
+			},
+			absent: []string{
+				"_view(c_ret)",
+			},
+			probes: []string{
+				"func norm(p *float64) float64 { return TNorm(p, 3) }",
+				"var _ *[]int32 = TCoords()",
+			},
+		},
+		{
+			views: "TSamples,TCoords,TNorm,TScaleAll",
+			expected: []string{
+				"func TSamples() []float64",
+				"func TCoords() []int32",
+				"func TNorm(arg_0 []float64) float64",
+				"func TScaleAll(arg_0 []float64, arg_2 float64)",
+				"unsafe.Slice((*float64)(C._gocxx_cnt_mylib_",
+				"unsafe.Slice((*int32)(C._gocxx_cnt_mylib_",
+				"\tc_arg_1 := C.ulong(len(arg_0))\n",
+			},
+			probes: []string{
+				"func norm() float64 { v := TSamples(); TScaleAll(v, 2); return TNorm(v) }",
+				"var _ []int32 = TCoords()",
+			},
+		},
+		{
+			views: []string{"TNorm"},
+			expected: []string{
+				"func TSamples() []float64",
+				"func TCoords() *[]int32",
+				"func TNorm(arg_0 []float64) float64",
+				"func TScaleAll(arg_0 *float64, arg_1 uint64, arg_2 float64)",
+			},
+		},
+	} {
+		args := map[string]interface{}{}
+		if table.views != nil {
+			args["views"] = table.views
+		}
+		files := gen_files(t, args)
+		check_code(t, fmt.Sprintf("views=%v", table.views), files, "mylib_cxxgo.plugin.go", table.expected, table.absent)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"], table.probes...) {
+			t.Errorf("views=%v: type error: %v", table.views, err)
+		}
+	}
+}
+
+// fill_strings_registry populates the global registry with string-like
+// types, and functions exchanging them.
+func fill_strings_registry() {
+	if cxxtypes.IdByName("TString") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	if cxxtypes.IdByName("std::basic_string<char>") == nil {
+		cxxtypes.NewClassType("std::basic_string<char>", 8, "::")
+	}
+	cxxtypes.NewFundamentalType("char", 1, cxxtypes.TK_Char_S, "::")
+	cxxtypes.NewQualType("char const", "char", "::", cxxtypes.TQ_Const)
+	cxxtypes.NewPtrType("char*", "char", "::")
+	cxxtypes.NewPtrType("char const*", "char const", "::")
+	cxxtypes.NewTypedefType("std::string", "std::basic_string<char>", 8, "std")
+	cxxtypes.NewClassType("std::basic_string_view<char>", 16, "std")
+	cxxtypes.NewClassType("TString", 16, "::")
+	for _, n := range []string{"std::string", "std::basic_string_view<char>", "TString"} {
+		cxxtypes.NewQualType(n+" const", n, "::", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "::")
+		cxxtypes.NewRefType(n+"&", n, "::")
+		cxxtypes.NewPtrType(n+"*", n, "::")
+		cxxtypes.NewPtrType(n+" const*", n+" const", "::")
+	}
+	for _, n := range []string{
+		"std::string", "std::string const&", "std::string&", "std::string*",
+		"std::basic_string_view<char>", "char*", "char const*", "TString",
+	} {
+		cxxtypes.NewFunction("TStr", 0, 0, pub, false,
+			[]cxxtypes.Parameter{{Name: "s", Type: n}}, n, "::")
+	}
+	cxxtypes.NewFunction("TStrCat", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			{Name: "s", Type: "TString&"},
+			*cxxtypes.NewParameter("sfx", "std::string const*", ""),
+		},
+		"void", "::")
+}
+
+func TestStrings(t *testing.T) {
+	new_test_registry(fill_strings_registry)
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"std::string", "string"},
+		{"std::string const&", "string"},
+		{"std::string&", "*string"},
+		{"std::string*", "*string"},
+		{"std::string const*", "string"},
+		{"std::basic_string<char>", "string"},
+		{"std::basic_string_view<char>", "string"},
+		{"std::basic_string_view<char>&", "*string"},
+		{"char*", "string"},
+		{"char const*", "string"},
+		{"TString const&", "string"},
+		{"TString&", "*string"},
+		{"char", "byte"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+
+	files := gen_files(t, map[string]interface{}{"overloads": "typed"})
+	for _, table := range []struct {
+		fname    string
+		expected []string
+		absent   []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"func TStrString(arg_0 string) string",
+				"func TStrPtrString(arg_0 *string) string",
+				"func TStrCat(arg_0 *string, arg_1 string)",
+				"\tc_arg_0 := _gocxx_strview_from_go(arg_0)\n\tdefer C.free(unsafe.Pointer(c_arg_0.p))\n",
+				"\t\tdefer func() { *arg_0 = _gocxx_strview_to_go(c_arg_0) }()\n",
+				"\treturn _gocxx_strview_to_go(c_ret)\n",
+			},
+			absent: []string{
+				// strings are converted, not wrapped
+				"type string interface",
+				"type TString interface",
+				"_gostring_",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  const std::basic_string<char>& cxx_ret = TStr(cxx_arg_0);\n",
+				"  const std::basic_string<char>* cxx_ret = TStr((c_arg_0 ? &cxx_arg_0 : NULL));\n",
+				"  const std::basic_string_view<char>& cxx_ret = TStr(cxx_arg_0);\n",
+				"  const char* cxx_ret = TStr(cxx_arg_0);\n",
+				"  _gocxx_strview_set((_gocxx_strview*)c_ret, cxx_ret.Data(), cxx_ret.Length());\n",
+				"  _gocxx_strview_set((_gocxx_strview*)c_ret, (cxx_ret ? cxx_ret->data() : NULL), (cxx_ret ? cxx_ret->size() : 0));\n",
+				"  _gocxx_strview_set((_gocxx_strview*)c_ret, cxx_ret, (cxx_ret ? strlen(cxx_ret) : 0));\n",
+				"  char* cxx_arg_0 = (char*)(*(const _gocxx_strview*)c_arg_0).p;\n",
+				"  TString cxx_arg_0 = TString(); if (c_arg_0) { cxx_arg_0 = TString((*(const _gocxx_strview*)c_arg_0).p, (*(const _gocxx_strview*)c_arg_0).n); }\n",
+				"  if (c_arg_0) { _gocxx_strview_set((_gocxx_strview*)c_arg_0, cxx_arg_0.Data(), cxx_arg_0.Length()); }\n",
+				"  TStrCat(cxx_arg_0, &cxx_arg_1);\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, table.absent)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ string = TStrString(TStrPtrString(nil))",
+		"func cat(s string) string { TStrCat(&s, \"!\"); return s }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_smartptr_registry populates the global registry with shared and
+// unique pointers to a class, and functions exchanging them.
+func fill_smartptr_registry() {
+	if cxxtypes.IdByName("TShared") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	n := cxxtypes.Parameter{Name: "n", Type: "int"}
+	sp := "std::shared_ptr<Foo>"
+	up := "std::unique_ptr<Foo, std::default_delete<Foo> >"
+	for _, n := range []string{sp, up} {
+		cxxtypes.NewClassType(n, 16, "std")
+		cxxtypes.NewQualType(n+" const", n, "std", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "std")
+		cxxtypes.NewRefType(n+"&", n, "std")
+	}
+	cxxtypes.NewFunction("TShared", 0, 0, pub, false, []cxxtypes.Parameter{n}, sp, "::")
+	cxxtypes.NewFunction("TReset", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "p", Type: sp + "&"}}, "void", "::")
+	cxxtypes.NewFunction("TSink", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "p", Type: up}}, "int", "::")
+	cxxtypes.NewFunction("TPeek", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "p", Type: up + " const&"}}, "int", "::")
+	cxxtypes.NewFunction("TOwner", 0, 0, pub, false, nil, up+"&", "::")
+}
+
+func TestSmartPointers(t *testing.T) {
+	new_test_registry(fill_smartptr_registry)
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"std::shared_ptr<Foo>", "*SharedPtrFoo"},
+		{"std::shared_ptr<Foo> const&", "*SharedPtrFoo"},
+		{"std::unique_ptr<Foo, std::default_delete<Foo> >", "*UniquePtrFoo"},
+		{"std::unique_ptr<Foo, std::default_delete<Foo> >&", "*UniquePtrFoo"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+
+	files := gen_files(t, nil)
+	for _, table := range []struct {
+		fname    string
+		expected []string
+		absent   []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"type SharedPtrFoo struct {\n\tFoo\n",
+				"type UniquePtrFoo struct {\n\tFoo\n",
+				"func NewSharedPtrFoo(obj Foo) *SharedPtrFoo",
+				"func (p *SharedPtrFoo) Close() error",
+				"func (p *SharedPtrFoo) UseCount() int",
+				"func TShared(arg_0 int32) *SharedPtrFoo",
+				"func TReset(arg_0 *SharedPtrFoo)",
+				"func TSink(arg_0 *UniquePtrFoo) int32",
+				"func TPeek(arg_0 *UniquePtrFoo) int32",
+				"func TOwner() Foo",
+				"\t\t// the ownership of the object is transferred to C++\n\t\tdefer arg_0.Close()\n",
+				"\t\tdefer func() { arg_0.Foo = GocxxcptrFoo(C._gocxx_sptr_mylib_",
+			},
+			absent: []string{
+				"func (p *UniquePtrFoo) UseCount() int",
+				"type Std_shared_ptr",
+				"type Std_unique_ptr",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  return new std::shared_ptr<Foo>(*cxx_self);\n",
+				"  return new std::unique_ptr<Foo, std::default_delete<Foo> >(std::move(*cxx_self));\n",
+				"  std::shared_ptr<Foo> cxx_ret = TShared(*cxx_arg_0); *(void**)c_ret = _gocxx_sptr_mylib_",
+				"TSink(std::move(cxx_arg_0));\n",
+				"TPeek(cxx_arg_0);\n",
+				"  *(void**)c_ret = (void*)(TOwner()).get();\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, table.absent)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"func share() int { p := NewSharedPtrFoo(TOwner()); defer p.Close(); TReset(p); return p.UseCount() }",
+		"func sink(p *UniquePtrFoo) int32 { p.Foo = TOwner(); return TPeek(p) + TSink(p) }",
+		"var _ *SharedPtrFoo = TShared(1)",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_compound_registry populates the global registry with pairs, tuples,
+// optional values, variants and callbacks, and functions exchanging them.
+func fill_compound_registry() {
+	if cxxtypes.IdByName("TPair") != nil {
+		return
+	}
+	fill_strings_registry()
+
+	pub := cxxtypes.AS_Public
+	d := cxxtypes.Parameter{Name: "d", Type: "double"}
+	n := cxxtypes.Parameter{Name: "n", Type: "int"}
+	pi := "std::pair<int, std::string>"
+	tu := "std::tuple<int, double, std::string>"
+	od := "std::optional<double>"
+	va := "std::variant<int, std::string>"
+	fn := "std::function<double (int, std::string const&)>"
+	fv := "std::function<void ()>"
+	for _, n := range []string{pi, tu, od, va, fn, fv} {
+		cxxtypes.NewClassType(n, 32, "std")
+		cxxtypes.NewQualType(n+" const", n, "std", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "std")
+		cxxtypes.NewRefType(n+"&", n, "std")
+	}
+	cxxtypes.NewFunction("TPair", 0, 0, pub, false, []cxxtypes.Parameter{n}, pi, "::")
+	cxxtypes.NewFunction("TTuple", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "t", Type: tu + " const&"}}, "int", "::")
+	cxxtypes.NewFunction("TLookup", 0, 0, pub, false, []cxxtypes.Parameter{n}, od, "::")
+	cxxtypes.NewFunction("TOr", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "o", Type: od}, d}, "double", "::")
+	cxxtypes.NewFunction("TVariant", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: va + "&"}}, va, "::")
+	cxxtypes.NewFunction("TApply", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "f", Type: fn + " const&"}, n}, "double", "::")
+	cxxtypes.NewFunction("TOnDone", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "f", Type: fv}}, "void", "::")
+	cxxtypes.NewFunction("TDone", 0, 0, pub, false, nil, fv, "::")
+}
+
+func TestCompoundValues(t *testing.T) {
+	new_test_registry(fill_compound_registry)
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"std::pair<int, std::string>", "PairInt32String"},
+		{"std::tuple<int, double, std::string> const&", "TupleInt32Float64String"},
+		{"std::optional<double>", "*float64"},
+		{"std::optional<double>&", "*float64"},
+		{"std::variant<int, std::string>", "VariantInt32String"},
+		{"std::variant<int, std::string>&", "*VariantInt32String"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+
+	files := gen_files(t, nil)
+	for _, table := range []struct {
+		fname    string
+		expected []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"type PairInt32String struct {\n\tFirst int32\n\tSecond string\n}\n",
+				"type TupleInt32Float64String struct {\n\tF0 int32\n\tF1 float64\n\tF2 string\n}\n",
+				"type VariantInt32String interface {\n\tGocxxIsVariantInt32String()\n}\n",
+				"type VariantInt32String_Int32 int32\n",
+				"type VariantInt32String_String string\n",
+				"func TPair(arg_0 int32) PairInt32String",
+				"func TTuple(arg_0 TupleInt32Float64String) int32",
+				"func TLookup(arg_0 int32) (float64, bool)",
+				"func TOr(arg_0 *float64, arg_1 float64) float64",
+				"func TVariant(arg_0 *VariantInt32String) VariantInt32String",
+				"\tgo_ret, ok := _gocxx_cnt_",
+				"\treturn go_ret, ok\n",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  std::get<1>(*cxx_self) = std::string(((_gocxx_strview*)c_f1)[0].p, ((_gocxx_strview*)c_f1)[0].n);",
+				"  if (!*cxx_self) {\n    return 0;\n  }\n",
+				"    case 1: cxx_self->emplace<1>(std::string(",
+				"#include <vector>\n#include <optional>\n#include <variant>\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, nil)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ int32 = TTuple(TupleInt32Float64String{F0: 1, F2: TPair(1).Second})",
+		"func lookup() float64 { v, ok := TLookup(1); if !ok { return TOr(nil, 0) }; return TOr(&v, 1) }",
+		"func variant() VariantInt32String { var v VariantInt32String = VariantInt32String_String(\"a\"); return TVariant(&v) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	// the C++17 headers are only included when needed
+	for _, table := range []struct {
+		ids      []string
+		expected string
+	}{
+		{[]string{"Foo", "std::pair<int, std::string>"}, ""},
+		{[]string{"Foo", "std::optional<double>"}, "#include <optional>\n"},
+		{[]string{"std::variant<int, std::string>&", "std::variant<int, std::string>"}, "#include <variant>\n"},
+	} {
+		if o := compound_includes(table.ids); o != table.expected {
+			t.Errorf("expected [%s], got [%s] for %v", table.expected, o, table.ids)
+		}
+	}
+}
+
+func TestCallbacks(t *testing.T) {
+	new_test_registry(fill_compound_registry)
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"std::function<double (int, std::string const&)>", "func(int32, string) float64"},
+		{"std::function<void ()> const&", "func()"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+
+	files := gen_files(t, nil)
+	for _, table := range []struct {
+		fname    string
+		expected []string
+		absent   []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"//export _gocxx_mylib_gofunc_call\n",
+				"//export _gocxx_mylib_gofunc_release\n",
+				"func TApply(arg_0 func(int32, string) float64, arg_1 int32) float64",
+				"func TOnDone(arg_0 func())",
+				"\t\tc_arg_1 := (*C._gocxx_strview)(c_args[1])\n",
+				"\t\t*(*float64)(ret) = f(arg_0, arg_1)\n",
+			},
+			absent: []string{
+				// calling a C++ std::function from Go is not supported
+				"func TDone(",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  *cxx_self = [ref](int a0, std::string const& a1) -> double {\n",
+				"    _gocxx_strview c_a1 = { a1.data(), a1.size() };\n",
+				"    void **c_args = NULL;\n    ref->call(c_args, NULL);\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, table.absent)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ float64 = TApply(func(i int32, s string) float64 { return float64(i) }, 1)",
+		"func done() { TOnDone(func() {}) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_streams_registry populates the global registry with output and input
+// streams, and functions taking them.
+func fill_streams_registry() {
+	if cxxtypes.IdByName("TDump") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	n := cxxtypes.Parameter{Name: "n", Type: "int"}
+	ost := "std::basic_ostream<char, std::char_traits<char> >"
+	ist := "std::basic_istream<char, std::char_traits<char> >"
+	for _, n := range []string{ost, ist} {
+		cxxtypes.NewClassType(n, 8, "std")
+		cxxtypes.NewRefType(n+"&", n, "std")
+		cxxtypes.NewPtrType(n+"*", n, "std")
+	}
+	cxxtypes.NewTypedefType("std::ostream", ost, 8, "std")
+	cxxtypes.NewRefType("std::ostream&", "std::ostream", "std")
+	cxxtypes.NewFunction("operator<<", 0, cxxtypes.TS_Operator, pub, false,
+		[]cxxtypes.Parameter{{Name: "os", Type: "std::ostream&"}, {Name: "f", Type: "Foo const&"}},
+		"std::ostream&", "::")
+	cxxtypes.NewFunction("TDump", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "os", Type: ost + "*"}, n}, "void", "::")
+	cxxtypes.NewFunction("TLoad", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "is", Type: ist + "&"}}, "int", "::")
+	cxxtypes.NewFunction("TEcho", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "os", Type: "std::ostream&"}}, "std::ostream&", "::")
+}
+
+func TestStreams(t *testing.T) {
+	new_test_registry(fill_streams_registry)
+
+	for _, table := range []struct {
+		name     string
+		expected string
+	}{
+		{"std::ostream&", "io.Writer"},
+		{"std::basic_ostream<char, std::char_traits<char> >*", "io.Writer"},
+		{"std::basic_istream<char, std::char_traits<char> >&", "io.Reader"},
+	} {
+		n := gen_go_name_from_id(cxxtypes.IdByName(table.name))
+		if n != table.expected {
+			t.Errorf("expected [%s], got [%s] for [%s]",
+				table.expected, n, table.name)
+		}
+	}
+
+	files := gen_files(t, nil)
+	for _, table := range []struct {
+		fname    string
+		expected []string
+		absent   []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"func TDump(arg_0 io.Writer, arg_1 int32)",
+				"func TLoad(arg_0 io.Reader) int32",
+				"\tc_arg_0 := _gocxx_writer_from_go(arg_0)\n",
+				"\tc_arg_0 := _gocxx_reader_from_go(arg_0)\n",
+				"\tString() string\n",
+				"func (p GocxxcptrFoo) String() string {",
+			},
+			absent: []string{
+				// the stream can not be handed back to Go
+				"func TEcho(",
+				"type Std_basic_ostream",
+				"func (p GocxxcptrBase) String() string {",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"  _gocxx_gowriter_buf cxx_buf_0(c_arg_0); std::ostream cxx_arg_0(cxx_buf_0.get());\n",
+				"  _gocxx_goreader_buf cxx_buf_0(c_arg_0); std::istream cxx_arg_0(cxx_buf_0.get());\n",
+				"TDump((cxx_buf_0.get() ? &cxx_arg_0 : NULL), ",
+				"  cxx_os << *(Foo*)c_self;\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, table.absent)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"import (\"fmt\"; \"os\")",
+		"func dump() { TDump(os.Stdout, TLoad(os.Stdin)) }",
+		"var _ fmt.Stringer = GocxxcptrFoo(0)",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_templates_registry populates the global registry with instances of
+// class and function templates.
+func fill_templates_registry() {
+	if cxxtypes.IdByName("Box<int>") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	for _, targ := range []string{"int", "double"} {
+		v := cxxtypes.Parameter{Name: "v", Type: targ}
+		for _, tmpl := range []string{"Box", "Cell"} {
+			n := tmpl + "<" + targ + ">"
+			cls := cxxtypes.NewClassType(n, 8, "::")
+			fcts := []*cxxtypes.Function{
+				cxxtypes.NewFunction(n+"::"+tmpl, 0, m|cxxtypes.TS_Constructor, pub, false, []cxxtypes.Parameter{v}, "void", n),
+				cxxtypes.NewFunction(n+"::~"+tmpl, 0, m|cxxtypes.TS_Destructor, pub, false, nil, "void", n),
+				cxxtypes.NewFunction(n+"::get", cxxtypes.TQ_Const, m, pub, false, nil, targ, n),
+				cxxtypes.NewFunction(n+"::set", 0, m, pub, false, []cxxtypes.Parameter{v}, "void", n),
+			}
+			if tmpl == "Cell" && targ == "double" {
+				// not the same shape as Cell<int>
+				fcts = append(fcts,
+					cxxtypes.NewFunction(n+"::round", cxxtypes.TQ_Const, m, pub, false, nil, "int", n),
+				)
+			}
+			mbrs := []cxxtypes.Member{}
+			for _, f := range fcts {
+				mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+			}
+			cls.SetMembers(mbrs)
+		}
+		cxxtypes.NewFunction("twice<"+targ+">", 0, 0, pub, false, []cxxtypes.Parameter{v}, targ, "::")
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	new_test_registry(fill_containers_registry, fill_templates_registry)
+
+	for _, table := range []struct {
+		pat      string
+		name     string
+		expected bool
+	}{
+		{"std::vector<int>", "std::vector<int, std::allocator<int> >", true},
+		{"std::vector<int,std::allocator<int>>", "std::vector<int, std::allocator<int> >", true},
+		{"std::vector<double>", "std::vector<int, std::allocator<int> >", false},
+		{"std::vector", "std::vector<int, std::allocator<int> >", true},
+		{"std::vector<*>", "std::vector<Foo>", true},
+		{"std::map<std::basic_string<char>,double>", "std::map<std::basic_string<char>, double>", true},
+		{"twice<int>", "twice<int>", true},
+		{"Foo", "Foo", false},
+	} {
+		o := match_template(table.pat, cxxtypes.IdByName(table.name))
+		if o != table.expected {
+			t.Errorf("expected %v, got %v for [%s] and [%s]",
+				table.expected, o, table.pat, table.name)
+		}
+	}
+
+	// not selected by default
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", nil, []string{"Box", "twice"})
+
+	files = gen_files(t, map[string]interface{}{
+		"templates": "Box<int>=BoxInt, Box< double >=BoxDouble, Box=Boxed, Cell, twice<int>=TwiceInt",
+		"generics":  true,
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type BoxInt interface {",
+		"type BoxDouble interface {",
+		"func NewBoxInt(arg_0 int32) BoxInt {",
+		"func (p GocxxcptrBoxDouble)Get() float64 {",
+		"func TwiceInt(arg_0 int32) int32 {",
+		"type Boxed[T any] interface {\n\tGocxxcptr() uintptr\n\tGet() T\n\tSet(arg_0 T)\n}\n",
+		"func NewBoxed[T any](arg_0 T) Boxed[T] {",
+		"\tcase *int32:\n\t\to = NewBoxInt(any(arg_0).(int32))\n",
+		"\tcase *float64:\n\t\to = NewBoxDouble(any(arg_0).(float64))\n",
+		"\treturn o.(Boxed[T])\n",
+		// unnamed instances are named after their template arguments
+		"type CellInt32 interface {",
+		"func NewCellFloat64(arg_0 float64) CellFloat64 {",
+	}, []string{
+		// only twice<int> was selected
+		"twice_Sl_double_Sg_",
+		"TwiceFloat64",
+		"Cell_Sl_",
+		// Cell<int> and Cell<double> do not share the same shape
+		"type Cell[T any]",
+	})
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ Boxed[int32] = NewBoxInt(TwiceInt(1))",
+		"var _ float64 = NewBoxed(2.0).Get()",
+		"var _ int32 = NewCellFloat64(2).Round() + NewCellInt32(1).Get()",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	files = gen_files(t, map[string]interface{}{
+		"templates": "twice",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func TwiceInt32(arg_0 int32) int32 {",
+		"func TwiceFloat64(arg_0 float64) float64 {",
+	}, nil)
+}
+
+// fill_namespaces_registry populates the global registry with namespaces,
+// their classes and free functions, and a namespace alias.
+func fill_namespaces_registry() {
+	if cxxtypes.IdByName("Math") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	cxxtypes.NewNamespace("Math", "")
+	cxxtypes.NewNamespace("Math2", "")
+	{
+		n := "Math::Pt"
+		cls := cxxtypes.NewClassType(n, 8, "Math")
+		fcts := []*cxxtypes.Function{
+			cxxtypes.NewFunction(n+"::Pt", 0, m|cxxtypes.TS_Constructor, pub, false, []cxxtypes.Parameter{i}, "void", n),
+			cxxtypes.NewFunction(n+"::~Pt", 0, m|cxxtypes.TS_Destructor, pub, false, nil, "void", n),
+			cxxtypes.NewFunction(n+"::x", cxxtypes.TQ_Const, m, pub, false, nil, "int", n),
+		}
+		mbrs := []cxxtypes.Member{}
+		for _, f := range fcts {
+			mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+		}
+		cls.SetMembers(mbrs)
+	}
+	cxxtypes.NewFunction("Math::do_hello", 0, 0, pub, false, []cxxtypes.Parameter{i}, "int", "Math")
+	cxxtypes.NewFunction("Math2::do_hello", 0, 0, pub, false, []cxxtypes.Parameter{i}, "int", "Math2")
+	cxxtypes.NewNamespaceAlias("M", "Math", "")
+}
+
+func TestNamespaces(t *testing.T) {
+	new_test_registry(fill_namespaces_registry)
+
+	dir, err := ioutil.TempDir("", "go-cxxdict-")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// flat: free functions are prefixed with their namespaces
+	files := generate(t, dir, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func Math_do_hello(arg_0 int32) int32 {",
+		"func Math2_do_hello(arg_0 int32) int32 {",
+		"type Math_Pt interface {",
+		"func NewMath_Pt(arg_0 int32) Math_Pt {",
+		"type M_Pt = Math_Pt\n",
+		"var M_do_hello = Math_do_hello\n",
+		"var NewM_Pt = NewMath_Pt\n",
+	}, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{"Math::do_hello(", "Math2::do_hello("}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ int32 = Math_do_hello(1) + M_do_hello(2) + Math2_do_hello(3)",
+		"var _ M_Pt = NewMath_Pt(1)",
+		"var _ int32 = NewM_Pt(1).X()",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	// strip: the Math root namespace is dropped
+	files = generate(t, dir, map[string]interface{}{
+		"namespaces": "strip",
+		"ns-strip":   "Math",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func Do_hello(arg_0 int32) int32 {",
+		"func Math2_do_hello(arg_0 int32) int32 {",
+		"type Pt interface {",
+		"type M_Pt = Pt\n",
+		"var M_Do_hello = Do_hello\n",
+	}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ int32 = Do_hello(1) + M_Do_hello(2) + Math2_do_hello(3)",
+		"var _ M_Pt = NewPt(1)",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	// packages: one Go package per namespace
+	generate(t, dir, map[string]interface{}{
+		"namespaces": "packages",
+		"ns-import":  "example.com/mylib",
+	})
+	for _, table := range []struct {
+		fname string
+		strs  []string
+	}{
+		{
+			"math/mylib_cxxgo.plugin.go",
+			[]string{
+				"package math\n",
+				"import mylib \"example.com/mylib\"\n",
+				"type Pt = mylib.Math_Pt\n",
+				"var Do_hello = mylib.Math_do_hello\n",
+				"var NewPt = mylib.NewMath_Pt\n",
+			},
+		},
+		{
+			"math2/mylib_cxxgo.plugin.go",
+			[]string{
+				"package math2\n",
+				"var Do_hello = mylib.Math2_do_hello\n",
+			},
+		},
+		{
+			"m/mylib_cxxgo.plugin.go",
+			[]string{
+				"package m\n",
+				"type Pt = mylib.Math_Pt\n",
+			},
+		},
+	} {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, table.fname))
+		if err != nil {
+			t.Errorf("could not read [%s]: %v", table.fname, err)
+			continue
+		}
+		for _, str := range table.strs {
+			if !strings.Contains(string(buf), str) {
+				t.Errorf("expected [%s] in [%s]", str, table.fname)
+			}
+		}
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"namespaces": "nested"},
+		{"namespaces": "strip"},
+		{"namespaces": "packages"},
+	} {
+		gen := wrapper.NewGenerator()
+		for k, v := range args {
+			gen.Args[k] = v
+		}
+		p := &plugin{}
+		if err := p.Init(gen); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+// fill_nested_registry populates the global registry with the public,
+// protected and private nested types of a class.
+func fill_nested_registry() {
+	if cxxtypes.IdByName("Class::Inner") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	n := "Class"
+	in := n + "::Inner"
+	inner := cxxtypes.NewClassType(in, 8, n)
+	fcts := []*cxxtypes.Function{
+		cxxtypes.NewFunction(in+"::Inner", 0, m|cxxtypes.TS_Constructor, pub, false, []cxxtypes.Parameter{i}, "void", in),
+		cxxtypes.NewFunction(in+"::~Inner", 0, m|cxxtypes.TS_Destructor, pub, false, nil, "void", in),
+		cxxtypes.NewFunction(in+"::value", cxxtypes.TQ_Const, m, pub, false, nil, "int", in),
+	}
+	mbrs := []cxxtypes.Member{}
+	for _, f := range fcts {
+		mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, in))
+	}
+	inner.SetMembers(mbrs)
+	cxxtypes.NewClassType(n+"::Hidden", 8, n)
+	cxxtypes.NewClassType(n+"::Guarded", 8, n)
+	cxxtypes.NewRefType(n+"::Guarded&", n+"::Guarded", n)
+	touch := cxxtypes.NewFunction(n+"::touch", 0, m, pub, false,
+		[]cxxtypes.Parameter{{Name: "g", Type: n + "::Guarded&"}}, "void", n)
+	cxxtypes.NewEnumType(n+"::Kind", []cxxtypes.Member{
+		cxxtypes.NewMember(n+"::KA", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, pub, 0, n),
+	}, n)
+
+	cls := cxxtypes.IdByName(n).(*cxxtypes.ClassType)
+	cls.SetMembers(append(cls.Members,
+		cxxtypes.NewMember(in, in, cxxtypes.IK_Typ, cxxtypes.TK_Record, pub, 0, n),
+		cxxtypes.NewMember(n+"::Hidden", n+"::Hidden", cxxtypes.IK_Typ, cxxtypes.TK_Record, cxxtypes.AS_Private, 0, n),
+		cxxtypes.NewMember(n+"::Guarded", n+"::Guarded", cxxtypes.IK_Typ, cxxtypes.TK_Record, cxxtypes.AS_Protected, 0, n),
+		cxxtypes.NewMember(n+"::Kind", n+"::Kind", cxxtypes.IK_Typ, cxxtypes.TK_Enum, pub, 0, n),
+		cxxtypes.NewMember(touch.Name, touch.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n),
+	))
+}
+
+func TestNestedTypes(t *testing.T) {
+	new_test_registry(fill_nested_registry)
+
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type ClassInner interface {",
+		"func NewClassInner(arg_0 int32) ClassInner {",
+		"func (p GocxxcptrClassInner)Value() int32 {",
+		"type ClassKind int\n",
+	}, []string{
+		// neither private nor protected nested types are wrapped, nor
+		// the functions using them
+		"Hidden",
+		"Guarded",
+		"Touch",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", nil, []string{
+		"Hidden",
+		"Guarded",
+	})
+	// nested types are generated with their enclosing class
+	code := string(files["mylib_cxxgo.plugin.go"])
+	outer := strings.Index(code, "type Class interface {")
+	inner := strings.Index(code, "type ClassInner interface {")
+	next := strings.Index(code, "type Foo interface {")
+	if !(outer < inner && inner < next) {
+		t.Errorf("expected [ClassInner] between [Class] and [Foo] (%d, %d, %d)",
+			outer, inner, next)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ int32 = NewClassInner(1).Value()",
+		"var _ ClassKind",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	files = gen_files(t, map[string]interface{}{"nested-sep": "_"})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type Class_Inner interface {",
+		"type Class_Kind int\n",
+	}, nil)
+}
+
+// fill_typedefs_registry populates the global registry with typedefs of
+// fundamental types, of typedefs and of classes, and functions using them.
+func fill_typedefs_registry() {
+	if cxxtypes.IdByName("Math::Ssiz_t") != nil {
+		return
+	}
+	fill_namespaces_registry()
+
+	pub := cxxtypes.AS_Public
+
+	cxxtypes.NewTypedefType("Math::Ssiz_t", "int", 4, "Math")
+	cxxtypes.NewTypedefType("Math::Len_t", "Math::Ssiz_t", 4, "Math")
+	cxxtypes.NewTypedefType("Math::Flag_t", "bool", 1, "Math")
+	cxxtypes.NewTypedefType("Math::Foo_t", "Foo", 8, "Math")
+	cxxtypes.NewQualType("Math::Foo_t const", "Math::Foo_t", "Math", cxxtypes.TQ_Const)
+	cxxtypes.NewRefType("Math::Foo_t const&", "Math::Foo_t const", "Math")
+	cxxtypes.NewFunction("Math::double_len", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "n", Type: "Math::Len_t"}}, "Math::Len_t", "Math")
+	cxxtypes.NewFunction("Math::is_pos", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "n", Type: "Math::Ssiz_t"}}, "Math::Flag_t", "Math")
+	cxxtypes.NewFunction("Math::foo_value", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "f", Type: "Math::Foo_t const&"}}, "int", "Math")
+}
+
+func TestTypedefs(t *testing.T) {
+	new_test_registry(fill_typedefs_registry)
+
+	// named: Go named types for fundamental types, aliases for classes
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type Math_Ssiz_t int32\n",
+		"type Math_Len_t Math_Ssiz_t\n",
+		"type Math_Flag_t bool\n",
+		"type Math_Foo_t = Foo\n",
+		"func Math_double_len(arg_0 Math_Len_t) Math_Len_t {",
+		"func Math_is_pos(arg_0 Math_Ssiz_t) Math_Flag_t {",
+		"go_ret := Math_Flag_t(_gocxx_int2bool(c_ret))\n",
+		"func Math_foo_value(arg_0 Math_Foo_t) int32 {\n\tc_arg_0 := unsafe.Pointer(arg_0.GocxxPtrFoo())\n",
+	}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ Math_Flag_t = Math_is_pos(Math_Ssiz_t(Math_double_len(1)))",
+		"func value(f Foo) int32 { var t Math_Foo_t = f; return Math_foo_value(t) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	// alias: Go aliases only
+	files = gen_files(t, map[string]interface{}{"typedefs": "alias"})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type Math_Ssiz_t = int32\n",
+		"type Math_Len_t = Math_Ssiz_t\n",
+		"type Math_Flag_t = bool\n",
+		"func Math_is_pos(arg_0 Math_Ssiz_t) Math_Flag_t {",
+	}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ bool = Math_is_pos(Math_double_len(int32(1)))",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	gen := wrapper.NewGenerator()
+	gen.Args["typedefs"] = "opaque"
+	p := &plugin{}
+	if err := p.Init(gen); err == nil {
+		t.Errorf("expected an error for typedefs=opaque")
+	}
+}
+
+// fill_opaque_registry populates the global registry with an unselected
+// class, and functions using it or types which can not be represented in Go.
+func fill_opaque_registry() {
+	if cxxtypes.IdByName("Hdl") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	n := "Hdl"
+	cls := cxxtypes.NewClassType(n, 8, "::")
+	get := cxxtypes.NewFunction(n+"::get", cxxtypes.TQ_Const, m, pub, false, nil, "int", n)
+	cls.SetMembers([]cxxtypes.Member{
+		cxxtypes.NewMember(get.Name, get.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n),
+	})
+	cxxtypes.NewQualType(n+" const", n, "::", cxxtypes.TQ_Const)
+	cxxtypes.NewRefType(n+" const&", n+" const", "::")
+	cxxtypes.NewRefType(n+"&", n, "::")
+	cxxtypes.NewPtrType(n+"*", n, "::")
+	cxxtypes.NewArrayType(3, "int", 4, "::")
+	cxxtypes.NewFundamentalType("long double", 16, cxxtypes.TK_LongDouble, "::")
+	cxxtypes.NewFunction("TGetHdl", 0, 0, pub, false, nil, "Hdl&", "::")
+	cxxtypes.NewFunction("TFindHdl", 0, 0, pub, false, []cxxtypes.Parameter{i}, "Hdl*", "::")
+	cxxtypes.NewFunction("TUseHdl", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "h", Type: "Hdl const&"}}, "int", "::")
+	cxxtypes.NewFunction("TSum3", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "a", Type: "int[3]"}}, "int", "::")
+	cxxtypes.NewFunction("TLdbl", 0, 0, pub, false, []cxxtypes.Parameter{i}, "long double", "::")
+	cxxtypes.NewPtrType("void*", "void", "::")
+	cxxtypes.NewFunction("TVoidPtr", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "p", Type: "void*"}}, "void*", "::")
+}
+
+func TestOpaqueHandles(t *testing.T) {
+	new_test_registry(fill_opaque_registry)
+
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type Hdl interface {",
+		"type GocxxcptrHdl uintptr\n",
+		"func TGetHdl() Hdl {",
+		"func TFindHdl(arg_0 int32) Hdl {",
+		"\treturn GocxxcptrHdl(c_ret)\n",
+		"func TUseHdl(arg_0 Hdl) int32 {",
+		// void* is an untyped pointer
+		"func TVoidPtr(arg_0 unsafe.Pointer) unsafe.Pointer {",
+	}, []string{
+		// opaque handles have no method, unrepresentable functions are skipped
+		"GocxxcptrHdl)Get",
+		"TSum3",
+		"TLdbl",
+		"_go_unknown_",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		"*(void**)c_ret = (void*)&(",
+		"*(void**)c_ret = (void*)(",
+	}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ = TVoidPtr(TVoidPtr(nil))",
+		"var _ int32 = TUseHdl(TGetHdl()) + TUseHdl(TFindHdl(1))",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_diamond_registry populates the global registry with a diamond
+// hierarchy of polymorphic classes, with virtual and private bases.
+func fill_diamond_registry() {
+	if cxxtypes.IdByName("BaseV") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+
+	// diamond hierarchy: BaseDiamond -> BaseL, BaseR -> (virtual) BaseV
+	for _, c := range []struct {
+		n     string
+		bases []cxxtypes.Base
+	}{
+		{"BaseV", nil},
+		{"BaseL", []cxxtypes.Base{cxxtypes.NewBase(8, "BaseV", pub, true)}},
+		{"BaseR", []cxxtypes.Base{cxxtypes.NewBase(8, "BaseV", pub, true)}},
+		{"BaseDiamond", []cxxtypes.Base{
+			cxxtypes.NewBase(0, "BaseL", pub, false),
+			cxxtypes.NewBase(16, "BaseR", pub, false),
+			cxxtypes.NewBase(24, "Foo", cxxtypes.AS_Private, false),
+		}},
+	} {
+		n := c.n
+		cls := cxxtypes.NewClassType(n, 32, "::")
+		ctor := cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, nil, "void", n)
+		mbrs := []cxxtypes.Member{
+			cxxtypes.NewMember(ctor.Name, ctor.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n),
+		}
+		if c.bases == nil {
+			// BaseV is polymorphic
+			dtor := cxxtypes.NewFunction(n+"::~"+n, 0, m|cxxtypes.TS_Destructor|cxxtypes.TS_Virtual, pub, false, nil, "void", n)
+			vval := cxxtypes.NewFunction(n+"::vval", cxxtypes.TQ_Const, m, pub, false, nil, "int", n)
+			for _, f := range []*cxxtypes.Function{dtor, vval} {
+				mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+			}
+		}
+		cls.SetMembers(mbrs)
+		cls.SetBases(c.bases)
+	}
+	cxxtypes.NewQualType("BaseR const", "BaseR", "::", cxxtypes.TQ_Const)
+	cxxtypes.NewRefType("BaseR const&", "BaseR const", "::")
+	cxxtypes.NewFunction("TRval", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "r", Type: "BaseR const&"}}, "int", "::")
+}
+
+func TestMultipleInheritance(t *testing.T) {
+	new_test_registry(fill_diamond_registry)
+
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func (p GocxxcptrBaseDiamond) GocxxGetBaseL() BaseL {",
+		"func (p GocxxcptrBaseDiamond) GocxxGetBaseR() BaseR {",
+		"func (p GocxxcptrBaseL) GocxxGetBaseV() BaseV {",
+		"func (p GocxxcptrBaseR) GocxxGetBaseV() BaseV {",
+		"return GocxxcptrBaseR(C._gocxx_upcast_mylib_",
+		// the indirect bases, reached through the direct ones
+		"func (p GocxxcptrBaseDiamond) GocxxGetBaseV() BaseV {\n\treturn p.GocxxGetBaseL().GocxxGetBaseV()\n}\n",
+		"func (p GocxxcptrBaseDiamond) GocxxPtrBaseV() uintptr {\n\treturn p.GocxxGetBaseL().GocxxGetBaseV().GocxxPtrBaseV()\n}\n",
+		// class arguments are passed as the address of their base subobject
+		"\tc_arg_0 := unsafe.Pointer(arg_0.GocxxPtrBaseR())\n",
+	}, []string{
+		// the address of the derived object is never reused as is
+		"(p.Gocxxcptr())\n",
+		"GocxxGetFoo",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		"return static_cast< ::BaseL*>((::BaseDiamond*)c_self);",
+		"return static_cast< ::BaseR*>((::BaseDiamond*)c_self);",
+		"// upcasts [BaseR] to its virtual base [BaseV]",
+		"return static_cast< ::BaseV*>((::BaseR*)c_self);",
+	}, nil)
+	if n := strings.Count(string(files["mylib_cxxgo.plugin.h"]), "/* upcasts [Base"); n != 4 {
+		t.Errorf("expected 4 upcast functions of the diamond in [mylib_cxxgo.plugin.h], got %d", n)
+	}
+	// the diamond implements the interfaces of all its public bases
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ BaseR = GocxxcptrBaseDiamond(0)",
+		"var _ BaseV = GocxxcptrBaseDiamond(0)",
+		"var _ = TRval(GocxxcptrBaseDiamond(0))",
+		"var _ BaseV = GocxxcptrBaseDiamond(0).GocxxGetBaseL().GocxxGetBaseV()",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+func TestDynamicTypes(t *testing.T) {
+	new_test_registry(fill_diamond_registry, fill_inherited_registry)
+
+	files := gen_files(t, nil)
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func AsBaseL(b BaseV) (BaseL, bool) {",
+		"func AsBaseR(b BaseV) (BaseR, bool) {",
+		"func AsBaseDiamond(b BaseV) (BaseDiamond, bool) {",
+		"return GocxxcptrBaseDiamond(c), true",
+		"func GocxxMostDerived(o interface{ Gocxxcptr() uintptr }) interface{ Gocxxcptr() uintptr } {",
+		"func (p GocxxcptrBaseV) gocxxDynamic() (string, unsafe.Pointer) {",
+		"func (p GocxxcptrBaseDiamond) gocxxDynamic() (string, unsafe.Pointer) {",
+		"return GocxxcptrBaseDiamond(p)",
+		// the handles of the intermediate classes are downcast from their
+		// root subobject
+		"func (p GocxxcptrBaseV) gocxxRootBaseV() unsafe.Pointer {\n\treturn unsafe.Pointer(p)\n}\n",
+		"func (p GocxxcptrBaseL) gocxxRootBaseV() unsafe.Pointer {",
+		"func (p GocxxcptrBaseDiamond) gocxxRootBaseV() unsafe.Pointer {",
+		"\tc := C._gocxx_downcast_mylib_",
+	}, []string{
+		// only polymorphic classes have a dynamic type
+		"func AsBaseV(",
+		"GocxxcptrFoo) gocxxDynamic",
+		"func AsD1(",
+	})
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		"#include <typeinfo>",
+		"return dynamic_cast< ::BaseDiamond*>((::BaseV*)c_self);",
+		"return typeid(::BaseDiamond).name();",
+		"*(void**)c_ptr = dynamic_cast<void*>(self);",
+		"return (::BaseV*)(::BaseL*)(::BaseDiamond*)c_self;",
+		"return (::BaseV*)(::BaseL*)c_self;",
+	}, nil)
+	// BaseL, BaseR and BaseDiamond
+	if n := strings.Count(string(files["mylib_cxxgo.plugin.h"]), "void* _gocxx_downcast_mylib_"); n != 3 {
+		t.Errorf("expected 3 downcast functions in [mylib_cxxgo.plugin.h], got %d", n)
+	}
+	// e.g. AsBaseDiamond of a BaseL handle
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ = GocxxcptrBaseL(0).gocxxRootBaseV",
+		"var _, _ = AsBaseDiamond(GocxxcptrBaseL(0))",
+		"func vval(b BaseV) int32 { if d, ok := AsBaseDiamond(b); ok { return d.Vval() }; return b.Vval() }",
+		"var _ = GocxxMostDerived(GocxxcptrBaseR(0)).(BaseR)",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_inherited_registry populates the global registry with classes
+// inheriting, hiding and ambiguously inheriting the methods of their bases.
+func fill_inherited_registry() {
+	if cxxtypes.IdByName("D1") != nil {
+		return
+	}
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	d := cxxtypes.Parameter{Name: "d", Type: "double"}
+
+	// inherited methods: D1::set hides Base::set, D2 inherits it and
+	// WithTwoBases inherits it twice (ambiguous)
+	for _, n := range []string{"D1", "D2", "WithTwoBases"} {
+		cls := cxxtypes.NewClassType(n, 8, "::")
+		fcts := []*cxxtypes.Function{
+			cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, nil, "void", n),
+		}
+		if n == "D1" {
+			fcts = append(fcts, cxxtypes.NewFunction(n+"::set", 0, m, pub, false, []cxxtypes.Parameter{d}, "void", n))
+		}
+		mbrs := []cxxtypes.Member{}
+		for _, f := range fcts {
+			mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+		}
+		cls.SetMembers(mbrs)
+		bases := []cxxtypes.Base{cxxtypes.NewBase(0, "Base", pub, false)}
+		if n == "WithTwoBases" {
+			bases = append(bases, cxxtypes.NewBase(8, "Class", pub, false))
+		}
+		cls.SetBases(bases)
+	}
+}
+
+func TestInheritedMethods(t *testing.T) {
+	new_test_registry(fill_diamond_registry, fill_inherited_registry)
+
+	for _, table := range []struct {
+		args   map[string]interface{}
+		want   []string
+		nwant  []string
+		probes []string
+	}{
+		{
+			args: nil,
+			want: []string{
+				// D2 inherits all of Base
+				"func (p GocxxcptrD2) Set(args ...interface{}) {\n\tp.GocxxGetBase().Set(args...)\n}\n",
+				"func (p GocxxcptrD2) Reset(opts ...BaseResetOpt) {\n\tp.GocxxGetBase().Reset(opts...)\n}\n",
+				"func (p GocxxcptrD2) Less(arg_0 Base) bool {\n\treturn p.GocxxGetBase().Less(arg_0)\n}\n",
+				// D1::set hides Base::set
+				"func (p GocxxcptrD1) Reset(opts ...BaseResetOpt) {",
+				// one BaseV subobject, reached through the virtual bases
+				"func (p GocxxcptrBaseDiamond) Vval() int32 {\n\treturn p.GocxxGetBaseL().GocxxGetBaseV().Vval()\n}\n",
+				"func (p GocxxcptrBaseR) Vval() int32 {\n\treturn p.GocxxGetBaseV().Vval()\n}\n",
+				"// Vval is inherited from BaseV\n",
+			},
+			nwant: []string{
+				"func (p GocxxcptrD1) Set(args ...interface{})",
+				"func (p GocxxcptrD1) SetInt32(",
+				// Base::set and Class::set are ambiguous
+				"func (p GocxxcptrWithTwoBases) Set(",
+				"func (p GocxxcptrWithTwoBases) Reset(",
+				// private base
+				"func (p GocxxcptrBaseDiamond) Set(",
+				// not inherited
+				"func (p GocxxcptrD2) NewBase",
+			},
+			probes: []string{
+				"func set(d D2) bool { d.Set(int32(1)); d.Reset(); return d.Less(NewD2()) }",
+				"func reset(d D1) { d.Set(1.0); d.Reset(BaseResetWithI(1)) }",
+				"var _ int32 = NewBaseDiamond().Vval()",
+			},
+		},
+		{
+			args: map[string]interface{}{
+				"overloads": "typed",
+				"using":     "D1::set",
+			},
+			want: []string{
+				"\tSet(arg_0 float64)\n",
+				"func (p GocxxcptrD1) SetInt32(arg_0 int32) {\n\tp.GocxxGetBase().SetInt32(arg_0)\n}\n",
+				"func (p GocxxcptrD1) SetInt32Float64(arg_0 int32, arg_1 float64) {",
+			},
+			nwant: []string{
+				// Go name already used by D1::set
+				"func (p GocxxcptrD1) Set() {",
+			},
+			probes: []string{
+				"func set(d D1) { d.Set(1); d.SetInt32(1); d.SetInt32Float64(1, 2) }",
+			},
+		},
+	} {
+		files := gen_files(t, table.args)
+		check_code(t, fmt.Sprintf("args=%v", table.args), files, "mylib_cxxgo.plugin.go", table.want, table.nwant)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"], table.probes...) {
+			t.Errorf("args=%v: type error: %v", table.args, err)
+		}
+	}
+}
+
+// fill_protected_registry populates the global registry with classes with
+// protected methods, fields and virtual methods.
+func fill_protected_registry() {
+	if cxxtypes.IdByName("IAlg") != nil {
+		return
+	}
+	fill_strings_registry()
+
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	n := "IAlg"
+	prot := cxxtypes.AS_Protected
+	cls := cxxtypes.NewClassType(n, 16, "::")
+	fcts := []*cxxtypes.Function{
+		cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, prot, false, nil, "void", n),
+		cxxtypes.NewFunction(n+"::execute", 0, m, pub, false, []cxxtypes.Parameter{i}, "int", n),
+		cxxtypes.NewFunction(n+"::do_execute", 0, m|cxxtypes.TS_Virtual, prot, false, []cxxtypes.Parameter{i}, "int", n),
+		cxxtypes.NewFunction(n+"::run", 0, m, pub, false, nil, "int", n),
+		cxxtypes.NewFunction(n+"::run", 0, m, prot, false, []cxxtypes.Parameter{i}, "int", n),
+		cxxtypes.NewFunction(n+"::~"+n, 0, m|cxxtypes.TS_Destructor|cxxtypes.TS_Virtual, pub, false, nil, "void", n),
+		// hooks: overridden by Go
+		cxxtypes.NewFunction(n+"::label", cxxtypes.TQ_Const, m|cxxtypes.TS_Virtual|cxxtypes.TS_Abstract, prot, false,
+			[]cxxtypes.Parameter{{Name: "sfx", Type: "std::string const&"}}, "std::string", n),
+		cxxtypes.NewFunction(n+"::reset", 0, m|cxxtypes.TS_Virtual, prot, false, nil, "void", n),
+		// not a hook: Foo is not exchanged with Go funcs
+		cxxtypes.NewFunction(n+"::merge", 0, m|cxxtypes.TS_Virtual, prot, false,
+			[]cxxtypes.Parameter{{Name: "f", Type: "Foo const&"}}, "void", n),
+	}
+	cls.Spec |= cxxtypes.TS_Abstract
+	mbrs := []cxxtypes.Member{}
+	seen := map[string]bool{}
+	for _, f := range fcts {
+		if seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, f.Access, 0, n))
+	}
+	mbrs = append(mbrs, cxxtypes.NewMember(n+"::m_count", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, prot, 8, n))
+	cls.SetMembers(mbrs)
+
+	// can not be extended: no virtual destructor
+	n = "IAlgFixed"
+	cls = cxxtypes.NewClassType(n, 16, "::")
+	hook := cxxtypes.NewFunction(n+"::do_execute", 0, m|cxxtypes.TS_Virtual, prot, false, []cxxtypes.Parameter{i}, "int", n)
+	cls.SetMembers([]cxxtypes.Member{
+		cxxtypes.NewMember(hook.Name, hook.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, prot, 0, n),
+		cxxtypes.NewMember(n+"::m_count", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, prot, 8, n),
+	})
+}
+
+func TestProtectedMembers(t *testing.T) {
+	new_test_registry(fill_protected_registry)
+
+	files := gen_files(t, nil)
+
+	code := string(files["mylib_cxxgo.plugin.go"])
+	iface := func(n string) string {
+		iface := code[strings.Index(code, "type "+n+" interface {"):]
+		return iface[:strings.Index(iface, "\n}\n")+1]
+	}
+	for _, str := range []string{"\tExecute(arg_0 int32) int32\n", "\tRun() int32\n"} {
+		if !strings.Contains(iface("IAlg"), str) {
+			t.Errorf("expected [%s] in the IAlg interface", str)
+		}
+	}
+	for _, str := range []string{"Do_execute", "M_count", "Run(arg", "Label", "Reset", "Merge"} {
+		if strings.Contains(iface("IAlg"), str) {
+			t.Errorf("expected no [%s] in the IAlg interface", str)
+		}
+	}
+
+	// the protected virtual methods exchanging values with Go are
+	// overridden by the Go extensions
+	if got, want := iface("IAlgExtension"), "type IAlgExtension interface {\n"+
+		"\tDo_execute(self IAlgProtected, arg_0 int32) int32\n"+
+		"\tLabel(self IAlgProtected, arg_0 string) string\n"+
+		"\tReset(self IAlgProtected)\n"; got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+	// the protected members are only reached from the handle given to the
+	// Go extensions, which can not be implemented outside of the package.
+	// the protected run is renamed, as the public one is also a method of
+	// the handle.
+	prot := iface("IAlgProtected")
+	for _, str := range []string{
+		"\tIAlg\n",
+		"\tgocxxIsIAlgProtected()\n",
+		"\tDo_execute(arg_0 int32) int32\n",
+		"\tProtectedRun(arg_0 int32) int32\n",
+		"\tReset()\n",
+		"\tMerge(arg_0 Foo)\n",
+		"\tGetM_count() int32\n",
+		"\tSetM_count(v int32)\n",
+	} {
+		if !strings.Contains(prot, str) {
+			t.Errorf("expected [%s] in the IAlgProtected interface", str)
+		}
+	}
+	// the pure virtual methods have no implementation to call
+	if strings.Contains(prot, "Label") {
+		t.Errorf("expected no Label in the IAlgProtected interface")
+	}
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type gocxxcptrIAlgProtected struct {\n\tGocxxcptrIAlg\n}\n",
+		"(p gocxxcptrIAlgProtected)Do_execute(arg_0 int32) int32 {\n\tc_this := unsafe.Pointer(p.GocxxcptrIAlg)\n",
+		"func NewIAlgExtension(ext IAlgExtension) IAlg {\n\tc_fct := _gocxx_gofunc_new(",
+		"\t\tcase 1:\n\t\t\tc_args := unsafe.Slice((*unsafe.Pointer)(args), 3)\n\t\t\tc_arg_0 := (*C._gocxx_strview)(c_args[2])\n",
+		"\t\t\t*(*C._gocxx_strview)(ret) = _gocxx_strview_from_go(ext.Label(self, arg_0))\n",
+		"\t\tcase 2:\n\t\t\text.Reset(self)\n",
+	}, []string{
+		// protected constructors are not wrapped
+		"func NewIAlg(",
+		"func (p IAlgProtected)",
+		"IAlgProtected uintptr",
+		"Protected() IAlgProtected",
+	})
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"type myAlg struct{ n int32 }",
+		"func (a *myAlg) Do_execute(self IAlgProtected, i int32) int32 {",
+		"	self.SetM_count(self.GetM_count() + 1)",
+		"	return self.Do_execute(i) + self.ProtectedRun(i) + self.Run() + a.n",
+		"}",
+		"func (a *myAlg) Label(self IAlgProtected, sfx string) string { return sfx }",
+		"func (a *myAlg) Reset(self IAlgProtected)                    { self.Reset() }",
+		"var _ IAlg = NewIAlgExtension(&myAlg{})",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	cxx := string(files["mylib_cxxgo.plugin.cxx"])
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		" : public ::IAlg {\npublic:\n  using ::IAlg::do_execute;\n  using ::IAlg::run;\n  using ::IAlg::reset;\n  using ::IAlg::merge;\n  using ::IAlg::m_count;\n};\n",
+		// the Go overrides are not called back by the protected methods
+		"cxx_this->_gocxx_protected_mylib_",
+		"->m_count = *(int*)c_val;",
+		"  _gocxx_gofunc_ref m_go;\npublic:\n",
+		"  std::string label(std::string const& a0) const {\n    size_t c_hook = 1;\n",
+		"    _gocxx_strview c_a0 = { a0.data(), a0.size() };\n    void *c_args[] = { (void*)&c_hook, (void*)&c_self, (void*)&c_a0 };\n",
+		"  void reset() {\n    size_t c_hook = 2;\n",
+		"    int cxx_ret{};\n    m_go.call(c_args, (void*)&cxx_ret);\n    return cxx_ret;\n",
+	}, []string{
+		"label(std::string const& a0) const {\n    return",
+		"merge(Foo const& a0) {",
+	})
+	// the accessor class is declared before its use
+	if strings.Index(cxx, "class _gocxx_protected_mylib_") > strings.Index(cxx, "*cxx_this = (_gocxx_protected_mylib_") {
+		t.Errorf("expected the accessor class before the wrappers of the protected methods")
+	}
+
+	// the protected members of the classes which can not be extended are
+	// not wrapped
+	files = gen_files(t, map[string]interface{}{"select": "IAlg*"})
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"type IAlgFixed interface {",
+	}, []string{
+		"IAlgFixedExtension",
+		"IAlgFixedProtected",
+	})
+}
+
+// fill_copy_registry populates the global registry with classes which can
+// not be copied, or only moved, and a function taking an rvalue reference.
+func fill_copy_registry() {
+	if cxxtypes.IdByName("CopyNone") != nil {
+		return
+	}
+	fill_namespaces_registry()
+
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	op := m | cxxtypes.TS_Operator
+
+	// copy and move operations:
+	// CopyNone has a private copy constructor and assignment operator,
+	// CopyNoneD inherits them, CopyDeleted deletes its copy constructor
+	// and CopyMoveOnly only declares a move constructor
+	for _, n := range []string{"CopyNone", "CopyNoneD", "CopyDeleted", "CopyMoveOnly"} {
+		cls := cxxtypes.NewClassType(n, 8, "::")
+		cxxtypes.NewQualType(n+" const", n, "::", cxxtypes.TQ_Const)
+		cxxtypes.NewRefType(n+" const&", n+" const", "::")
+		cxxtypes.NewRefType(n+"&", n, "::")
+		cxxtypes.NewRefType(n+"&&", n, "::")
+		cref := []cxxtypes.Parameter{{Name: "rhs", Type: n + " const&"}}
+		rref := []cxxtypes.Parameter{{Name: "rhs", Type: n + "&&"}}
+		ctor := m | cxxtypes.TS_Constructor
+		cctor := ctor | cxxtypes.TS_CopyCtor
+		fcts := []*cxxtypes.Function{
+			cxxtypes.NewFunction(n+"::"+n, 0, ctor, pub, false, nil, "void", n),
+		}
+		switch n {
+		case "CopyNone":
+			fcts = append(fcts,
+				cxxtypes.NewFunction(n+"::"+n, 0, cctor, cxxtypes.AS_Private, false, cref, "void", n),
+				cxxtypes.NewFunction(n+"::operator=", 0, op, cxxtypes.AS_Private, false, cref, n+"&", n),
+			)
+		case "CopyNoneD":
+			fcts = append(fcts,
+				cxxtypes.NewFunction(n+"::"+n, 0, cctor, pub, false, cref, "void", n),
+			)
+		case "CopyDeleted":
+			fcts = append(fcts,
+				cxxtypes.NewFunction(n+"::"+n, 0, cctor|cxxtypes.TS_Deleted, pub, false, cref, "void", n),
+			)
+		case "CopyMoveOnly":
+			fcts = append(fcts,
+				cxxtypes.NewFunction(n+"::"+n, 0, ctor, pub, false, rref, "void", n),
+				cxxtypes.NewFunction(n+"::absorb", 0, m, pub, false, rref, "void", n),
+			)
+		}
+		mbrs := []cxxtypes.Member{}
+		seen := map[string]bool{}
+		for _, f := range fcts {
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, f.Access, 0, n))
+		}
+		cls.SetMembers(mbrs)
+		if n == "CopyNoneD" {
+			cls.SetBases([]cxxtypes.Base{cxxtypes.NewBase(0, "CopyNone", pub, false)})
+		}
+	}
+	cxxtypes.NewRefType("int&&", "int", "::")
+	cxxtypes.NewFunction("Math::sink", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: "int&&"}}, "void", "Math")
+}
+
+func TestCopyAndMove(t *testing.T) {
+	new_test_registry(fill_diamond_registry, fill_inherited_registry, fill_copy_registry)
+
+	files := gen_files(t, map[string]interface{}{"select": "Copy*"})
+
+	code := string(files["mylib_cxxgo.plugin.go"])
+	iface := func(n string) string {
+		iface := code[strings.Index(code, "type "+n+" interface {"):]
+		return iface[:strings.Index(iface, "\n}\n")+1]
+	}
+	for _, table := range []struct {
+		cls   string
+		clone bool
+		copy  bool
+	}{
+		{"Foo", true, true},
+		{"CopyNone", false, false},
+		{"CopyNoneD", false, false},
+		{"CopyDeleted", false, true},
+		{"CopyMoveOnly", false, false},
+	} {
+		clone := "func (p Gocxxcptr" + table.cls + ") Clone() " + table.cls + " {"
+		if got := strings.Contains(code, clone); got != table.clone {
+			t.Errorf("%s: expected Clone=%v, got %v", table.cls, table.clone, got)
+		}
+		assign := "func (p Gocxxcptr" + table.cls + ") CopyFrom(o " + table.cls + ") {"
+		if got := strings.Contains(code, assign); got != table.copy {
+			t.Errorf("%s: expected CopyFrom=%v, got %v", table.cls, table.copy, got)
+		}
+		// the copy methods are not part of the interfaces: the handles of
+		// the derived classes still implement the interfaces of their bases
+		if iface := iface(table.cls); strings.Contains(iface, "Clone") || strings.Contains(iface, "CopyFrom") {
+			t.Errorf("%s: expected no copy method in the interface", table.cls)
+		}
+	}
+
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		"return (void*)new ::Foo(*(::Foo*)c_self);",
+		"*(::Foo*)c_self = *(::Foo*)c_other;",
+		"  CopyMoveOnly* cxx_arg_0 = *(CopyMoveOnly**)(&c_arg_0);\n",
+		"absorb(std::move(*cxx_arg_0));\n}\n",
+		"sink(std::move(*cxx_arg_0));\n}\n",
+	}, []string{
+		// the moved-from objects are not destroyed: they may not be owned
+		// by the caller
+		"delete cxx_arg_",
+		// the copy constructors of the classes which can not be copied are
+		// not wrapped
+		"new CopyNone(*cxx_arg_0)",
+		"new CopyNoneD(*cxx_arg_0)",
+		"new CopyDeleted(*cxx_arg_0)",
+	})
+
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"\tAbsorb(arg_0 *CopyMoveOnly)\n",
+		"\tc_arg_0 := unsafe.Pointer((*arg_0).GocxxPtrCopyMoveOnly())\n\tdefer func() { *arg_0 = nil }()\n",
+		"func Math_sink(arg_0 int32) {",
+	}, nil)
+
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ Base = GocxxcptrD2(0)",
+		"var _ BaseV = GocxxcptrBaseL(0)",
+		"var _ BaseV = GocxxcptrBaseR(0)",
+		"var _ Foo = GocxxcptrFoo(0).Clone()",
+		"var _ D2 = GocxxcptrD2(0).Clone()",
+		"func copyFoo(f Foo) { GocxxcptrFoo(0).CopyFrom(f) }",
+		"func absorb(a, o CopyMoveOnly) { a.Absorb(&o); Math_sink(1) }",
+		"func assign(o CopyDeleted) { GocxxcptrCopyDeleted(0).CopyFrom(o) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_values_registry populates the global registry with functions
+// passing and returning classes by value.
+func fill_values_registry() {
+	if cxxtypes.IdByName("TMakeFoo") != nil {
+		return
+	}
+	fill_copy_registry()
+
+	pub := cxxtypes.AS_Public
+
+	cxxtypes.NewFunction("TMakeFoo", 0, 0, pub, false, nil, "Foo", "::")
+	cxxtypes.NewFunction("TUseFoo", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "f", Type: "Foo"}}, "int", "::")
+	cxxtypes.NewFunction("TMakeMove", 0, 0, pub, false, nil, "CopyMoveOnly", "::")
+	cxxtypes.NewFunction("TUseMove", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "o", Type: "CopyMoveOnly"}}, "void", "::")
+	cxxtypes.NewFunction("TUseNone", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "o", Type: "CopyNone"}}, "void", "::")
+	cxxtypes.NewFunction("TMakeNone", 0, 0, pub, false, nil, "CopyNone", "::")
+}
+
+func TestClassValues(t *testing.T) {
+	new_test_registry(fill_values_registry)
+
+	files := gen_files(t, map[string]interface{}{"select": "Copy*"})
+
+	check_code(t, "", files, "mylib_cxxgo.plugin.go", []string{
+		"func TMakeFoo() Foo {\n\tvar c_ret unsafe.Pointer\n",
+		"\treturn GocxxcptrFoo(c_ret)\n}\n",
+		"func TUseFoo(arg_0 Foo) int32 {",
+		"func TMakeMove() CopyMoveOnly {",
+		// move-only classes are moved into the by-value parameter
+		"func TUseMove(arg_0 *CopyMoveOnly) {",
+		"\tc_arg_0 := unsafe.Pointer((*arg_0).GocxxPtrCopyMoveOnly())\n\tdefer func() { *arg_0 = nil }()\n",
+	}, []string{"TUseNone", "TMakeNone"})
+
+	check_code(t, "", files, "mylib_cxxgo.plugin.cxx", []string{
+		"  *(void**)c_ret = (void*)new Foo(TMakeFoo());\n",
+		"  *(void**)c_ret = (void*)new Foo(cxx_this->operator-(",
+		"  *(void**)c_ret = (void*)new CopyMoveOnly(TMakeMove());\n",
+		"TUseFoo(*cxx_arg_0);\n",
+		"TUseMove(std::move(*cxx_arg_0));\n}\n",
+	}, nil)
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"var _ int32 = TUseFoo(TMakeFoo())",
+		"func move() { o := TMakeMove(); TUseMove(&o) }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+}
+
+// fill_outparams_registry populates the global registry with functions
+// returning values through pointer and reference parameters.
+func fill_outparams_registry() {
+	if cxxtypes.IdByName("TGetRange") != nil {
+		return
+	}
+	fill_views_registry()
+
+	pub := cxxtypes.AS_Public
+
+	cxxtypes.NewRefType("int&", "int", "::")
+	cxxtypes.NewRefType("double&", "double", "::")
+	cxxtypes.NewFunction("TGetRange", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			{Name: "lo", Type: "double*"},
+			{Name: "hi", Type: "double*"},
+		},
+		"int", "::")
+	cxxtypes.NewFunction("TIncr", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "v", Type: "int&"}}, "void", "::")
+	cxxtypes.NewFunction("TMinMax", 0, 0, pub, false,
+		[]cxxtypes.Parameter{
+			{Name: "data", Type: "double const*"},
+			{Name: "n", Type: "unsigned long"},
+			{Name: "lo", Type: "double&"},
+			{Name: "hi", Type: "double&"},
+		},
+		"void", "::")
+	for _, params := range [][]cxxtypes.Parameter{
+		{{Name: "n", Type: "int"}, {Name: "q", Type: "int&"}, {Name: "r", Type: "int&"}},
+		{{Name: "n", Type: "int"}, {Name: "d", Type: "int"}, {Name: "q", Type: "int&"}, {Name: "r", Type: "int&"}},
+	} {
+		cxxtypes.NewFunction("TDivMod", 0, 0, pub, false, params, "void", "::")
+	}
+}
+
+func TestOutParams(t *testing.T) {
+	new_test_registry(fill_outparams_registry)
+
+	for _, table := range []struct {
+		args     map[string]interface{}
+		expected []string
+		absent   []string
+		probes   []string
+	}{
+		{
+			args: nil,
+			expected: []string{
+				"func TGetRange(arg_0 *float64, arg_1 *float64) int32 {",
+				"func TIncr(arg_0 int32) {",
+				"func TMinMax(arg_0 *float64, arg_1 uint64, arg_2 float64, arg_3 float64) {",
+				"func TDivMod(args ...interface{}) {",
+				"\t\tTDivMod__GOCXX_0(arg_0, arg_1, arg_2)\n\t\treturn\n",
+			},
+			probes: []string{
+				"func rng() int32 { var lo, hi float64; TIncr(1); return TGetRange(&lo, &hi) }",
+			},
+		},
+		{
+			args: map[string]interface{}{
+				"out-refs": "out",
+				"params":   "TGetRange(lo)=out,TGetRange(1)=out,TMinMax(data)=slice",
+			},
+			expected: []string{
+				"func TGetRange() (int32, float64, float64) {\n\tvar arg_0 float64\n\tc_arg_0 := unsafe.Pointer(&arg_0)\n",
+				"\treturn go_ret, arg_0, arg_1\n}\n",
+				"func TIncr() int32 {",
+				"\treturn arg_0\n}\n",
+				"func TMinMax(arg_0 []float64) (float64, float64) {",
+				"\tc_arg_1 := C.ulong(len(arg_0))\n",
+				"\treturn arg_2, arg_3\n}\n",
+				// output parameters are not passed to the dispatcher
+				"func TDivMod(args ...interface{}) (int32, int32) {\n\targc := len(args)\n\tswitch argc {\n\tcase 1:\n",
+				"\t\treturn TDivMod__GOCXX_0(arg_0)\n",
+				"\tcase 2:\n",
+			},
+			probes: []string{
+				"func rng() float64 { _, lo, hi := TGetRange(); return lo + hi + float64(TIncr()) }",
+				"func minmax(v []float64) float64 { lo, hi := TMinMax(v); return hi - lo }",
+				"func divmod() int32 { q, r := TDivMod(int32(7), int32(2)); return q + r }",
+			},
+		},
+		{
+			args: map[string]interface{}{
+				"out-refs":  "inout",
+				"overloads": "typed",
+			},
+			expected: []string{
+				"func TIncr(arg_0 int32) int32 {\n\tvar c_ret = unsafe.Pointer(nil)\n\tc_arg_0 := unsafe.Pointer(&arg_0)\n",
+				"func TDivModInt32Int32Int32(arg_0 int32, arg_1 int32, arg_2 int32) (int32, int32) {",
+				"func TGetRange(arg_0 *float64, arg_1 *float64) int32 {",
+			},
+			absent: []string{
+				"var arg_0 int32",
+			},
+			probes: []string{
+				"func divmod() int32 { q, r := TDivModInt32Int32Int32(7, 0, 0); return q + r + TIncr(1) }",
+			},
+		},
+		{
+			// parameters which can not be returned are left as such
+			args: map[string]interface{}{
+				"params": []string{"TGetRange(lo)=inout", "TNorm(n)=out", "TNorm(n)=slice"},
+			},
+			expected: []string{
+				"func TGetRange(arg_0 float64, arg_1 *float64) (int32, float64) {",
+				"\treturn go_ret, arg_0\n",
+				"func TNorm(arg_0 *float64, arg_1 uint64) float64",
+			},
+			probes: []string{
+				"func rng(hi *float64) (int32, float64) { return TGetRange(1, hi) }",
+			},
+		},
+	} {
+		files := gen_files(t, table.args)
+		check_code(t, fmt.Sprintf("args=%v", table.args), files, "mylib_cxxgo.plugin.go", table.expected, table.absent)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"], table.probes...) {
+			t.Errorf("args=%v: type error: %v", table.args, err)
+		}
+	}
+
+	for _, v := range []interface{}{
+		"TGetRange(lo)",
+		"TGetRange()=out",
+		"TGetRange(lo)=ret",
+		42,
+	} {
+		if _, err := parse_param_rules(v); err == nil {
+			t.Errorf("expected an error for rules [%v]", v)
+		}
+	}
+}
+
+// fill_status_registry populates the global registry with functions
+// returning status codes, and an overridden virtual method returning one.
+func fill_status_registry() {
+	if cxxtypes.IdByName("TStatus") != nil {
+		return
+	}
+	fill_outparams_registry()
+
+	pub := cxxtypes.AS_Public
+	m := cxxtypes.TS_Method
+	i := cxxtypes.Parameter{Name: "i", Type: "int"}
+
+	cxxtypes.NewTypedefType("TStatus", "int", 4, "::")
+	cxxtypes.NewFunction("TInit", 0, 0, pub, false, nil, "int", "::")
+	cxxtypes.NewFunction("TCheck", 0, 0, pub, false, []cxxtypes.Parameter{i}, "TStatus", "::")
+	cxxtypes.NewFunction("TRead", 0, 0, pub, false,
+		[]cxxtypes.Parameter{{Name: "n", Type: "int&"}}, "TStatus", "::")
+	cxxtypes.NewEnumType("TCode", []cxxtypes.Member{
+		cxxtypes.NewMember("TOk", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, pub, 0, "::"),
+		cxxtypes.NewMember("TFailed", "int", cxxtypes.IK_Var, cxxtypes.TK_Int, pub, 0, "::"),
+	}, "::")
+	cxxtypes.NewFunction("TOpen", 0, 0, pub, false, nil, "TCode", "::")
+	// TMyAlg::run overrides TAlg::run
+	for _, n := range []string{"TAlg", "TMyAlg"} {
+		cls := cxxtypes.NewClassType(n, 8, "::")
+		fcts := []*cxxtypes.Function{
+			cxxtypes.NewFunction(n+"::"+n, 0, m|cxxtypes.TS_Constructor, pub, false, nil, "void", n),
+			cxxtypes.NewFunction(n+"::run", 0, m|cxxtypes.TS_Virtual, pub, false, nil, "int", n),
+		}
+		mbrs := []cxxtypes.Member{}
+		for _, f := range fcts {
+			mbrs = append(mbrs, cxxtypes.NewMember(f.Name, f.Name, cxxtypes.IK_Fct, cxxtypes.TK_FunctionProto, pub, 0, n))
+		}
+		cls.SetMembers(mbrs)
+		if n == "TMyAlg" {
+			cls.SetBases([]cxxtypes.Base{cxxtypes.NewBase(0, "TAlg", pub, false)})
+		}
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	new_test_registry(fill_values_registry, fill_protected_registry, fill_status_registry)
+
+	for _, table := range []struct {
+		args     map[string]interface{}
+		expected []string
+		absent   []string
+	}{
+		{
+			args: nil,
+			expected: []string{
+				"func TInit() int32 {",
+				"func TCheck(arg_0 int32) TStatus {",
+			},
+			absent: []string{
+				"StatusError",
+				"import \"strconv\"\n",
+			},
+		},
+		{
+			args: map[string]interface{}{
+				"errors":   "TStatus>=0, int==0@TInit",
+				"out-refs": "out",
+			},
+			expected: []string{
+				"import \"io\"\nimport \"strconv\"\nimport \"runtime\"\n",
+				"type StatusError struct {",
+				"func TInit() error {",
+				"\tgo_ret := int32(c_ret)\n\tvar err error\n\tif go_ret != 0 {\n\t\terr = &StatusError{Code: int64(go_ret), Fct: \"TInit\"}\n\t}\n\treturn err\n}\n",
+				"func TCheck(arg_0 int32) error {",
+				"\tif go_ret < 0 {\n\t\terr = &StatusError{Code: int64(go_ret), Fct: \"TCheck\"}\n",
+				// the status code comes after the output parameters
+				"func TRead() (int32, error) {",
+				"\treturn arg_0, err\n}\n",
+				// other functions returning an int are left as such
+				"func TUseFoo(arg_0 Foo) int32 {",
+			},
+		},
+		{
+			// a rule on an overrider applies to the declaration it
+			// overrides, and conversely
+			args: map[string]interface{}{
+				"errors": "int==0@TMyAlg::*",
+			},
+			expected: []string{
+				"func (p GocxxcptrTAlg)Run() error {",
+				"func (p GocxxcptrTMyAlg)Run() error {",
+			},
+		},
+		{
+			args: map[string]interface{}{
+				"errors": "int==0@TAlg::run",
+			},
+			expected: []string{
+				"func (p GocxxcptrTAlg)Run() error {",
+				"func (p GocxxcptrTMyAlg)Run() error {",
+			},
+			absent: []string{
+				// not virtual
+				"func (p GocxxcptrIAlg)Run() error {",
+			},
+		},
+	} {
+		files := gen_files(t, table.args)
+		check_code(t, fmt.Sprintf("args=%v", table.args), files, "mylib_cxxgo.plugin.go", table.expected, table.absent)
+		for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+			"var _ TAlg = NewTMyAlg()",
+		) {
+			t.Errorf("args=%v: type error: %v", table.args, err)
+		}
+	}
+
+	// the value of an enumerator is taken from C++
+	files := gen_files(t, map[string]interface{}{
+		"errors": "TCode==::TOk",
+	})
+	for _, table := range []struct {
+		fname    string
+		expected []string
+	}{
+		{
+			fname: "mylib_cxxgo.plugin.go",
+			expected: []string{
+				"var _gocxx_status_0 = int64(C._gocxx_status_mylib_0())\n",
+				"func TOpen() error {",
+				"\tif int64(go_ret) != _gocxx_status_0 {\n",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.cxx",
+			expected: []string{
+				"long long _gocxx_status_mylib_0(void)\n{\n  return (long long)(::TOk);\n}\n",
+			},
+		},
+		{
+			fname: "mylib_cxxgo.plugin.h",
+			expected: []string{
+				"long long _gocxx_status_mylib_0(void);\n",
+			},
+		},
+	} {
+		check_code(t, "", files, table.fname, table.expected, nil)
+	}
+	for _, err := range typecheck(t, files["mylib_cxxgo.plugin.go"],
+		"func open() error { if err := TOpen(); err != nil { return err.(*StatusError) }; return nil }",
+	) {
+		t.Errorf("type error: %v", err)
+	}
+
+	for _, v := range []interface{}{
+		"int",
+		"int==ok",
+		"TCode==TUnknown",
+		"==0",
+		42,
+	} {
+		if _, err := parse_status_rules(v); err == nil {
+			t.Errorf("expected an error for rules [%v]", v)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package cxxgo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// g_default_name_re matches the (possibly qualified) names of a C++
+// expression
+var g_default_name_re = regexp.MustCompile(`(::\s*)?[A-Za-z_]\w*(\s*::\s*[A-Za-z_]\w*)*`)
+
+// g_default_keywords are the names of a C++ expression which are not looked
+// up in the registry
+var g_default_keywords = map[string]bool{
+	"true": true, "false": true, "nullptr": true, "NULL": true, "this": true,
+	"sizeof": true, "alignof": true, "new": true, "delete": true,
+	"static_cast": true, "const_cast": true, "reinterpret_cast": true,
+	"dynamic_cast": true, "const": true, "volatile": true,
+	"void": true, "bool": true, "char": true, "wchar_t": true,
+	"char16_t": true, "char32_t": true, "short": true, "int": true,
+	"long": true, "float": true, "double": true,
+	"signed": true, "unsigned": true, "std": true,
+}
+
+// enumerator_names returns the scoped names of the enumerators of the
+// registry. (an enumerator is declared in the scope of its enum)
+func enumerator_names() map[string]bool {
+	names := map[string]bool{}
+	for _, n := range cxxtypes.IdNames() {
+		if t, ok := cxxtypes.IdByName(n).(*cxxtypes.EnumType); ok {
+			for i, _ := range t.Members {
+				names[t.Members[i].Name] = true
+			}
+		}
+	}
+	return names
+}
+
+// lookup_scopes returns the scopes where the names used in a declaration
+// of the scope id are looked up, innermost first: id, its bases (for a
+// class) and its enclosing scopes. The global scope is not included.
+func lookup_scopes(id cxxtypes.Id) []string {
+	scopes := []string{}
+	var bases func(id cxxtypes.Id)
+	bases = func(id cxxtypes.Id) {
+		for _, base := range class_base_list(id) {
+			bid := cxxtypes.IdByName(base.TypeBase)
+			scopes = append(scopes, bid.IdScopedName())
+			bases(bid)
+		}
+	}
+	for id != nil {
+		n := id.IdScopedName()
+		if n == "" || n == "::" || str_is_in_slice(n, scopes) {
+			break
+		}
+		scopes = append(scopes, n)
+		bases(id)
+		id = id.DeclScope()
+	}
+	return scopes
+}
+
+// qualify_default returns the default value expr of a parameter of fct,
+// with its names fully qualified, so the expression can be evaluated from
+// the global scope of the generated C++ code.
+// It returns false if a name of the expression can not be resolved.
+func qualify_default(fct *cxxtypes.Function, expr string, enums map[string]bool) (string, bool) {
+	scopes := lookup_scopes(fct.DeclScope())
+	if len(scopes) == 0 {
+		// declared in the global scope
+		return expr, true
+	}
+	known := func(n string) bool {
+		return cxxtypes.IdByName(n) != nil || enums[n]
+	}
+	// the string and character literals
+	quoted := make([]bool, len(expr))
+	for i, quote := 0, byte(0); i < len(expr); i++ {
+		switch {
+		case quote != 0 && expr[i] == '\\':
+			quoted[i] = true
+			i++
+		case quote != 0 && expr[i] == quote:
+			quote = 0
+		case quote == 0 && (expr[i] == '"' || expr[i] == '\''):
+			quote = expr[i]
+		}
+		if i < len(expr) {
+			quoted[i] = quoted[i] || quote != 0
+		}
+	}
+
+	ok := true
+	o := []string{}
+	beg := 0
+	for _, idx := range g_default_name_re.FindAllStringIndex(expr, -1) {
+		name := expr[idx[0]:idx[1]]
+		if quoted[idx[0]] {
+			continue
+		}
+		if idx[0] > 0 {
+			prev := expr[idx[0]-1]
+			if prev == '.' || prev == '_' || prev == '>' && idx[0] > 1 && expr[idx[0]-2] == '-' ||
+				'0' <= prev && prev <= '9' || 'a' <= prev && prev <= 'z' || 'A' <= prev && prev <= 'Z' {
+				// member access or literal suffix (e.g. 1.5f, 0xff)
+				continue
+			}
+		}
+		if strings.HasPrefix(name, "::") {
+			// already qualified
+			continue
+		}
+		name = strings.Replace(name, " ", "", -1)
+		first := strings.Split(name, "::")[0]
+		if g_default_keywords[first] {
+			continue
+		}
+		resolved := ""
+		for _, scope := range scopes {
+			if known(scope + "::" + first) {
+				resolved = "::" + scope + "::" + name
+				break
+			}
+		}
+		if resolved == "" {
+			if !known(first) {
+				ok = false
+			}
+			continue
+		}
+		o = append(o, expr[beg:idx[0]], resolved)
+		beg = idx[1]
+	}
+	o = append(o, expr[beg:])
+	return strings.Join(o, ""), ok
+}
+
+// qualify_defaults fully qualifies the default values of the parameters of
+// the functions of the registry listed in names (see qualify_default).
+// The default values which can not be qualified are discarded: their
+// parameters (and the preceding ones) are then required.
+func qualify_defaults(names []string) {
+	enums := enumerator_names()
+	for _, n := range names {
+		ovfct, ok := cxxtypes.IdByName(n).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for i, _ := range ovfct.Fcts {
+			fct := ovfct.Function(i)
+			for j, _ := range fct.Params {
+				param := fct.Param(j)
+				if !param.HasDefaultValue() {
+					continue
+				}
+				v, ok := qualify_default(fct, param.Default, enums)
+				if !ok {
+					fmt.Printf(":: ignoring the default value [%s] of parameter [%d] of [%s] (unresolved name)\n",
+						param.Default, j, fct.IdScopedName())
+					param.DefVal = false
+					param.Default = ""
+					continue
+				}
+				param.Default = v
+			}
+		}
+	}
+}
+
+// EOF
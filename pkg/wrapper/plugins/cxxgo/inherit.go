@@ -0,0 +1,239 @@
+package cxxgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// inherited_fct is a member function (set) of a base class, as found by the
+// C++ name lookup from a derived class.
+type inherited_fct struct {
+	path  []cxxtypes.Id // the bases from the derived class down to owner
+	owner cxxtypes.Id   // the class declaring the member function
+	mbr   *cxxtypes.Member
+	virt  bool // whether owner is reached through a virtual base
+}
+
+// class_member_named returns the member of the class id whose unqualified
+// name is n, or nil.
+func class_member_named(id cxxtypes.Id, n string) *cxxtypes.Member {
+	mbrs := class_member_list(id)
+	for i, _ := range mbrs {
+		if mbrs[i].IdName() == n {
+			return &mbrs[i]
+		}
+	}
+	return nil
+}
+
+// lookup_member looks up the name n in the scope of the class id (if own)
+// and of its bases, following the C++ rules: a name declared by a class
+// hides the same name in its bases, and a name found in distinct base
+// subobjects is ambiguous.
+// It returns whether n was found, and the member function it denotes if it
+// is an unambiguous member function reachable through public bases.
+func lookup_member(id cxxtypes.Id, n string, own bool) (*inherited_fct, bool) {
+	if own {
+		if mbr := class_member_named(id, n); mbr != nil {
+			if !mbr.IsFunctionMember() {
+				return nil, true
+			}
+			return &inherited_fct{owner: id, mbr: mbr}, true
+		}
+	}
+	var fct *inherited_fct
+	found := false
+	for _, base := range class_base_list(id) {
+		bid := cxxtypes.IdByName(base.TypeBase)
+		bfct, bfound := lookup_member(bid, n, true)
+		if !bfound {
+			continue
+		}
+		if bfct != nil && base.IsPublic() {
+			virt := bfct.virt
+			if len(bfct.path) == 0 {
+				virt = base.IsVirtual()
+			}
+			bfct = &inherited_fct{
+				path:  append([]cxxtypes.Id{bid}, bfct.path...),
+				owner: bfct.owner,
+				mbr:   bfct.mbr,
+				virt:  virt,
+			}
+		} else {
+			bfct = nil
+		}
+		if !found {
+			fct, found = bfct, true
+			continue
+		}
+		// only one subobject of a virtual base
+		if fct == nil || bfct == nil ||
+			fct.owner != bfct.owner || !fct.virt || !bfct.virt {
+			fmt.Printf(":: discarding inherited [%s::%s] (ambiguous)\n",
+				id.IdScopedName(), n)
+			fct = nil
+		}
+	}
+	return fct, found
+}
+
+// go_call_args returns the arguments forwarding the parameters of the Go
+// prototype of f (see go_prototype)
+func (f *cxxgo_function) go_call_args() string {
+	args := []string{}
+	nreq := f.nreq()
+	for i, _ := range f.f.Params[:nreq] {
+		if f.is_view_len(i) || f.dir(i) == "out" {
+			continue
+		}
+		args = append(args, fmt.Sprintf("arg_%d", i))
+	}
+	if nreq < len(f.f.Params) {
+		args = append(args, "opts...")
+	}
+	return strings.Join(args, ", ")
+}
+
+// go_method_names returns the Go names of the methods wrapping the member
+// functions of the class id.
+func (p *plugin) go_method_names(id cxxtypes.Id) map[string]bool {
+	names := map[string]bool{"String": p.stringers[id.IdScopedName()]}
+	for i, _ := range class_member_list(id) {
+		mbr := &class_member_list(id)[i]
+		if !mbr.IsFunctionMember() || !p.mbr_filter(mbr) {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for _, cgo_ovfct := range p.new_cxxgo_ovfcts(ovfct) {
+			names[cgo_ovfct.goname] = true
+			for _, f := range cgo_ovfct.fcts {
+				names[f.goname] = true
+			}
+		}
+	}
+	return names
+}
+
+// wrapInheritedMethods exposes on the interface of the class id the public
+// member functions of its public bases which are visible from id: the ones
+// neither hidden by a member of id with the same name (unless brought back
+// by a using-declaration) nor ambiguous.
+// The Go methods delegate to the wrapper of the base class, through the
+// GocxxGet<Base>() upcasts.
+func (p *plugin) wrapInheritedMethods(cid *cxxgo_id, id *cxxtypes.ClassType, bufs bufmap_t) error {
+	var err error
+	pkg := p.gen.Fd.Package
+	go_cls_impl_name := "Gocxxcptr" + cid.goname
+
+	// names of the member functions of the bases, in declaration order
+	names := []string{}
+	seen := map[string]bool{}
+	var collect func(id cxxtypes.Id)
+	collect = func(id cxxtypes.Id) {
+		for _, base := range class_base_list(id) {
+			bid := cxxtypes.IdByName(base.TypeBase)
+			for _, mbr := range class_member_list(bid) {
+				if n := mbr.IdName(); mbr.IsFunctionMember() && !seen[n] {
+					seen[n] = true
+					names = append(names, n)
+				}
+			}
+			collect(bid)
+		}
+	}
+	collect(id)
+
+	gonames := p.go_method_names(id)
+	for _, n := range names {
+		if class_member_named(id, n) != nil && !p.usings[id.IdScopedName()+"::"+n] {
+			// hidden by id::n
+			continue
+		}
+		fct, _ := lookup_member(id, n, false)
+		if fct == nil || !p.mbr_filter(fct.mbr) {
+			continue
+		}
+		wrapped := true
+		for _, bid := range fct.path {
+			if !str_is_in_slice(bid.IdScopedName(), p.ids) || g_opaques[bid.IdScopedName()] {
+				wrapped = false
+			}
+		}
+		if !wrapped {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(fct.mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+
+		upcasts := []string{}
+		for _, bid := range fct.path {
+			upcasts = append(upcasts,
+				fmt.Sprintf("GocxxGet%s()", get_cxxgo_id(pkg, bid).goname))
+		}
+		call := "p." + strings.Join(upcasts, ".")
+
+		for _, cgo_ovfct := range p.new_cxxgo_ovfcts(ovfct) {
+			f := cgo_ovfct.fcts[0].f
+			if f.IsConstructor() ||
+				f.IsDestructor() ||
+				f.IsCopyConstructor() ||
+				f.IsAssignOperator() {
+				continue
+			}
+			type method struct {
+				goname, proto, args, ret string
+			}
+			mths := []method{}
+			if cgo_ovfct.needs_dispatch() && p.ovl_typed {
+				for i, _ := range cgo_ovfct.fcts {
+					cf := &cgo_ovfct.fcts[i]
+					mths = append(mths, method{
+						cf.goname, cf.go_prototype(), cf.go_call_args(), cf.go_ret_name(),
+					})
+				}
+			}
+			if !cgo_ovfct.needs_dispatch() || p.ovl_dispatch {
+				cf := &cgo_ovfct.fcts[0]
+				args := cf.go_call_args()
+				if cgo_ovfct.needs_dispatch() {
+					args = "args..."
+				}
+				mths = append(mths, method{
+					cgo_ovfct.goname, cgo_ovfct.go_prototype(), args, cf.go_ret_name(),
+				})
+			}
+			for _, mth := range mths {
+				if gonames[mth.goname] {
+					fmt.Printf(":: discarding inherited [%s] (Go name [%s] already in use by [%s])\n",
+						f.Signature(), mth.goname, id.IdScopedName())
+					continue
+				}
+				gonames[mth.goname] = true
+
+				fmter(bufs["go_iface"], "\t%s\n", mth.proto)
+
+				ret := ""
+				if mth.ret != "" {
+					ret = "return "
+				}
+				fmter(bufs["go_impl"],
+					"\n// %s is inherited from %s\nfunc (p %s) %s {\n\t%s%s.%s(%s)\n}\n",
+					mth.goname, fct.owner.IdScopedName(),
+					go_cls_impl_name, mth.proto,
+					ret, call, mth.goname, mth.args,
+				)
+			}
+		}
+	}
+	return err
+}
+
+// EOF
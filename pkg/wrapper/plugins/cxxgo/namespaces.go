@@ -0,0 +1,367 @@
+package cxxgo
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// g_ns_strip holds the root namespaces dropped from the Go names by the
+// "strip" namespace strategy
+var g_ns_strip []string
+
+// strip_root_ns removes the root namespace dropped by the "strip" namespace
+// strategy from the scoped C++ name n, if any.
+// e.g. "Math::do_hello" -> "do_hello"
+func strip_root_ns(n string) string {
+	for _, ns := range g_ns_strip {
+		if strings.HasPrefix(n, ns+"::") {
+			return n[len(ns)+2:]
+		}
+	}
+	return n
+}
+
+// ns_goprefix returns the prefix of the Go names of the identifiers
+// declared in the namespace ns (e.g. "Math_"), or the empty string for the
+// global namespace and the stripped root namespaces.
+func ns_goprefix(ns string) string {
+	n := strip_root_ns(ns + "::")
+	if n == "" || n == "::" {
+		return ""
+	}
+	return g_cxxgo_trans.Replace(n)
+}
+
+// ns_rename replaces the namespace prefix from of the Go name n with to.
+// It returns the empty string if n does not hold that prefix.
+// e.g. ("Math_do_hello", "Math_", "M_") -> "M_do_hello"
+// e.g. ("NewMath_Foo", "Math_", "") -> "NewFoo"
+func ns_rename(n, from, to string) string {
+	switch {
+	case from == "":
+		return to + n
+	case strings.HasPrefix(n, from):
+		return to + n[len(from):]
+	case strings.Contains(n, from):
+		return strings.Replace(n, from, to, 1)
+	}
+	return ""
+}
+
+// ns_members returns the names of the identifiers to select with the
+// namespace ns: its members and the members of its nested namespaces.
+func ns_members(ns *cxxtypes.Namespace) []string {
+	names := []string{}
+	for _, n := range ns.Members {
+		switch id := cxxtypes.IdByName(n).(type) {
+		case nil, *cxxtypes.NamespaceAlias:
+			// nothing to select
+		case *cxxtypes.Namespace:
+			names = append(names, ns_members(id)...)
+		default:
+			if is_anon(n) ||
+				get_cxxgo_string(id) != nil ||
+				strip_stream(id) != nil {
+				continue
+			}
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// decl_scope returns the scoped name of the declaring scope of id
+func decl_scope(id cxxtypes.Id) string {
+	scope := id.DeclScope()
+	if scope == nil {
+		return ""
+	}
+	return scope.IdScopedName()
+}
+
+// cxxgo_ns_entries lists the Go names, in the generated package, of the
+// wrappers of the identifiers declared in a namespace.
+type cxxgo_ns_entries struct {
+	types []string
+	fcts  []string
+}
+
+func (e *cxxgo_ns_entries) empty() bool {
+	return len(e.types) == 0 && len(e.fcts) == 0
+}
+
+// add_ns_fcts adds the Go functions wrapping the C++ functions of ovfct
+// accepted by sel, together with the types and functions dealing with
+// their optional arguments.
+func (p *plugin) add_ns_fcts(e *cxxgo_ns_entries, ovfct *cxxtypes.OverloadFunctionSet, sel func(fct *cxxtypes.Function) bool) {
+	for _, cgo_ovfct := range p.new_cxxgo_ovfcts(ovfct) {
+		if !sel(&cgo_ovfct.fcts[0].f) {
+			continue
+		}
+		if !cgo_ovfct.needs_dispatch() || p.ovl_dispatch {
+			e.fcts = append(e.fcts, cgo_ovfct.goname)
+		}
+		for i, _ := range cgo_ovfct.fcts {
+			cfct := &cgo_ovfct.fcts[i]
+			if cgo_ovfct.needs_dispatch() && p.ovl_typed {
+				e.fcts = append(e.fcts, cfct.goname)
+			}
+			if nreq := cfct.nreq(); nreq < cfct.f.NumParam() {
+				n := cfct.go_opts_name()
+				e.types = append(e.types, n+"Opt", n+"Opts")
+				for j := nreq; j < cfct.f.NumParam(); j++ {
+					e.fcts = append(e.fcts, cfct.go_opt_name(j))
+				}
+			}
+		}
+	}
+}
+
+// ns_entries returns the Go names of the wrappers of the selected
+// identifiers declared in the namespace ns
+func (p *plugin) ns_entries(ns string) *cxxgo_ns_entries {
+	e := &cxxgo_ns_entries{}
+	is_free := func(fct *cxxtypes.Function) bool {
+		return !fct.IsMethod() && !fct.IsOperator()
+	}
+	is_lifecycle := func(fct *cxxtypes.Function) bool {
+		return fct.IsConstructor() || fct.IsCopyConstructor() || fct.IsDestructor()
+	}
+	seen := make(map[string]bool)
+	for _, n := range p.ids {
+		id := cxxtypes.IdByName(n)
+		// nested types go with their outermost enclosing class
+		top := id
+		for scope := nested_scope(top); scope != nil; scope = nested_scope(top) {
+			top = scope
+		}
+		if seen[n] || decl_scope(top) != ns {
+			continue
+		}
+		seen[n] = true
+		switch id := id.(type) {
+		case *cxxtypes.ClassType:
+			if get_cxxgo_container(id) != nil ||
+				get_cxxgo_smartptr(id) != nil ||
+				get_cxxgo_callback(id) != nil {
+				continue
+			}
+			e.types = append(e.types, gen_go_name_from_id(id))
+			if g_opaques[n] {
+				continue
+			}
+			for _, mbr := range id.Members {
+				if !mbr.IsFunctionMember() || !p.mbr_filter(&mbr) {
+					continue
+				}
+				ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+				if !ok {
+					continue
+				}
+				p.add_ns_fcts(e, ovfct, is_lifecycle)
+			}
+		case *cxxtypes.EnumType:
+			e.types = append(e.types, gen_go_name_from_id(id))
+		case *cxxtypes.TypedefType:
+			if _, ok := g_typedefs[n]; ok {
+				e.types = append(e.types, gen_go_name_from_id(id))
+			}
+		case *cxxtypes.OverloadFunctionSet:
+			p.add_ns_fcts(e, id, is_free)
+		}
+	}
+	sort.Strings(e.types)
+	sort.Strings(e.fcts)
+	return e
+}
+
+// wrapNamespaces wraps the namespaces (and the aliases of namespaces)
+// declaring the selected identifiers.
+func (p *plugin) wrapNamespaces() error {
+	var err error
+	names := cxxtypes.IdNames()
+	sort.Strings(names)
+	for _, n := range names {
+		switch id := cxxtypes.IdByName(n).(type) {
+		case *cxxtypes.Namespace:
+			if n == "" {
+				// the global namespace
+				continue
+			}
+			err = p.wrapNamespace(get_cxxgo_id(p.gen.Fd.Package, id), id)
+		case *cxxtypes.NamespaceAlias:
+			err = p.wrapNamespaceAlias(id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// wrapNamespace wraps the namespace id.
+// With the "packages" strategy, a Go package re-exporting the wrappers of
+// the identifiers declared in the namespace is generated, under the
+// unprefixed Go names of these identifiers. (e.g. math.Do_hello for
+// Math_do_hello)
+func (p *plugin) wrapNamespace(cid *cxxgo_id, id *cxxtypes.Namespace) error {
+	var err error
+	if p.ns_mode != "packages" {
+		return err
+	}
+	e := p.ns_entries(id.IdScopedName())
+	if e.empty() {
+		return err
+	}
+	fmt.Printf(":: wrapping namespace [%s]...\n", id.IdScopedName())
+	err = p.write_ns_package(id.IdScopedName(), id.IdScopedName(), e)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(":: wrapping namespace [%s]...[ok]\n", id.IdScopedName())
+	return err
+}
+
+// wrapNamespaceAlias wraps the namespace alias id as Go aliases of the
+// wrappers of the identifiers declared in the aliased namespace:
+// under the prefix of the alias (e.g. M_do_hello for Math_do_hello), or in
+// a Go package of its own with the "packages" strategy.
+func (p *plugin) wrapNamespaceAlias(id *cxxtypes.NamespaceAlias) error {
+	var err error
+	ns := id.Target()
+	if ns == nil {
+		return err
+	}
+	e := p.ns_entries(ns.IdScopedName())
+	if e.empty() {
+		return err
+	}
+	fmt.Printf(":: wrapping namespace alias [%s]...\n", id.IdScopedName())
+	if p.ns_mode == "packages" {
+		err = p.write_ns_package(id.IdScopedName(), ns.IdScopedName(), e)
+		if err != nil {
+			return err
+		}
+		fmt.Printf(":: wrapping namespace alias [%s]...[ok]\n", id.IdScopedName())
+		return err
+	}
+
+	bufs := new_bufmap("go_impl")
+	from := ns_goprefix(ns.IdScopedName())
+	to := ns_goprefix(id.IdScopedName())
+	fmter(bufs["go_impl"],
+		"\n// aliases for the namespace alias %s (of %s)\n",
+		id.IdScopedName(), ns.IdScopedName(),
+	)
+	for _, n := range e.types {
+		if o := ns_rename(n, from, to); o != "" && o != n {
+			fmter(bufs["go_impl"], "type %s = %s\n", o, n)
+		}
+	}
+	for _, n := range e.fcts {
+		if o := ns_rename(n, from, to); o != "" && o != n {
+			fmter(bufs["go_impl"], "var %s = %s\n", o, n)
+		}
+	}
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+	fmt.Printf(":: wrapping namespace alias [%s]...[ok]\n", id.IdScopedName())
+	return err
+}
+
+// ns_gopkg returns the directory (relative to the generated package) and
+// the name of the Go package of the namespace ns.
+// e.g. "Math::Detail" -> "math/detail", "detail"
+func ns_gopkg(ns string) (string, string) {
+	dirs := []string{}
+	for _, n := range strings.Split(ns, "::") {
+		dirs = append(dirs, strings.ToLower(g_cxxgo_trans.Replace(n)))
+	}
+	pkg := dirs[len(dirs)-1]
+	if token.Lookup(pkg).IsKeyword() {
+		pkg += "_"
+	}
+	return filepath.Join(dirs...), pkg
+}
+
+// write_ns_package writes the Go package of the namespace (or namespace
+// alias) ns, re-exporting the wrappers of the identifiers e declared in the
+// namespace target.
+func (p *plugin) write_ns_package(ns, target string, e *cxxgo_ns_entries) error {
+	var err error
+	fd := p.gen.Fd
+	dir, pkg := ns_gopkg(ns)
+	dir = filepath.Join(filepath.Dir(fd.Name), dir)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	bufs := new_bufmap("go_impl")
+	fmter(bufs["go_impl"],
+		"// Package %s re-exports the Go wrappers of the C++ namespace %s.\n",
+		pkg, ns,
+	)
+	if ns != target {
+		fmter(bufs["go_impl"], "// (an alias of the namespace %s)\n", target)
+	}
+	fmter(bufs["go_impl"],
+		"package %s\n\nimport %s %q\n",
+		pkg, fd.Package, p.ns_import,
+	)
+
+	pfx := ns_goprefix(target)
+	seen := map[string]bool{}
+	local := func(n string) string {
+		o := ns_rename(n, pfx, "")
+		if o == "" {
+			o = n
+		}
+		o = strings.Title(o)
+		if seen[o] || !token.IsExported(o) {
+			return ""
+		}
+		seen[o] = true
+		return o
+	}
+	if len(e.types) > 0 {
+		fmter(bufs["go_impl"], "\n")
+	}
+	for _, n := range e.types {
+		if o := local(n); o != "" {
+			fmter(bufs["go_impl"], "type %s = %s.%s\n", o, fd.Package, n)
+		}
+	}
+	if len(e.fcts) > 0 {
+		fmter(bufs["go_impl"], "\n")
+	}
+	for _, n := range e.fcts {
+		if o := local(n); o != "" {
+			fmter(bufs["go_impl"], "var %s = %s.%s\n", o, fd.Package, n)
+		}
+	}
+	fmter(bufs["go_impl"], "\n// EOF %s\n", pkg)
+
+	f, err := os.Create(filepath.Join(dir, filepath.Base(fd.Name)+"_"+p.Name()+".go"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = bufs["go_impl"].WriteTo(f)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// EOF
@@ -0,0 +1,143 @@
+package cxxgo
+
+import (
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// g_nested_sep separates the Go name of an enclosing class from the name of
+// its nested types. (e.g. OuterInner for Outer::Inner)
+var g_nested_sep = ""
+
+// nested_scope returns the class declaring the nested type id, or nil if id
+// is not a type declared inside a wrapped class.
+func nested_scope(id cxxtypes.Id) cxxtypes.Id {
+	switch id.(type) {
+	case *cxxtypes.ClassType, *cxxtypes.StructType,
+		*cxxtypes.EnumType, *cxxtypes.TypedefType:
+	default:
+		return nil
+	}
+	scope := id.DeclScope()
+	switch scope.(type) {
+	case *cxxtypes.ClassType, *cxxtypes.StructType:
+	default:
+		return nil
+	}
+	if get_cxxgo_container(scope) != nil ||
+		get_cxxgo_smartptr(scope) != nil ||
+		get_cxxgo_callback(scope) != nil ||
+		get_cxxgo_string(scope) != nil ||
+		strip_stream(scope) != nil {
+		// not wrapped as a Go type of its own
+		return nil
+	}
+	return scope
+}
+
+// nested_goname returns the Go name of the nested type id: the Go name of
+// its enclosing class followed by its own name, or the empty string if id
+// is not a nested type.
+// e.g. Outer::Inner -> OuterInner
+func nested_goname(id cxxtypes.Id) string {
+	scope := nested_scope(id)
+	if scope == nil {
+		return ""
+	}
+	n := strings.TrimPrefix(id.IdScopedName(), scope.IdScopedName()+"::")
+	return gen_go_name_from_id(scope) + g_nested_sep +
+		strings.Title(g_cxxgo_trans.Replace(n))
+}
+
+// nested_member returns the member describing the nested type id in its
+// enclosing class, or nil if there is none.
+func nested_member(id cxxtypes.Id) *cxxtypes.Member {
+	var mbrs []cxxtypes.Member
+	switch scope := nested_scope(id).(type) {
+	case *cxxtypes.ClassType:
+		mbrs = scope.Members
+	case *cxxtypes.StructType:
+		mbrs = scope.Members
+	}
+	for i, _ := range mbrs {
+		if mbrs[i].IsTypeMember() && mbrs[i].Name == id.IdScopedName() {
+			return &mbrs[i]
+		}
+	}
+	return nil
+}
+
+// nested_filter returns whether the nested type id (and each of its
+// enclosing classes, if nested themselves) passes the member filter.
+// The protected nested types do not: like the other protected members,
+// they are not part of the public Go API of their enclosing class.
+// Types which are not nested always pass.
+func (p *plugin) nested_filter(id cxxtypes.Id) bool {
+	for ; nested_scope(id) != nil; id = nested_scope(id) {
+		if mbr := nested_member(id); mbr != nil && !p.mbr_filter(mbr) {
+			return false
+		}
+	}
+	return true
+}
+
+// nested_ids returns the names of the types nested in the class id (and,
+// recursively, in its nested classes) which pass the member filter.
+func (p *plugin) nested_ids(id cxxtypes.Id) []string {
+	var mbrs []cxxtypes.Member
+	switch id := id.(type) {
+	case *cxxtypes.ClassType:
+		mbrs = id.Members
+	case *cxxtypes.StructType:
+		mbrs = id.Members
+	}
+	names := []string{}
+	for i, _ := range mbrs {
+		mbr := &mbrs[i]
+		if !mbr.IsTypeMember() || !p.mbr_filter(mbr) {
+			continue
+		}
+		nid := cxxtypes.IdByName(mbr.Name)
+		if nid == nil || nested_scope(nid) == nil {
+			continue
+		}
+		names = append(names, mbr.Name)
+		names = append(names, p.nested_ids(nid)...)
+	}
+	return names
+}
+
+// order_nested moves the nested types of ids right after their enclosing
+// class, so they are generated together.
+func order_nested(ids []string) []string {
+	sel := make(map[string]bool, len(ids))
+	for _, n := range ids {
+		sel[n] = true
+	}
+	roots := make([]string, 0, len(ids))
+	children := make(map[string][]string)
+	for _, n := range ids {
+		scope := nested_scope(cxxtypes.IdByName(n))
+		if scope != nil && sel[scope.IdScopedName()] {
+			children[scope.IdScopedName()] = append(children[scope.IdScopedName()], n)
+			continue
+		}
+		roots = append(roots, n)
+	}
+	o := make([]string, 0, len(ids))
+	var add func(n string)
+	add = func(n string) {
+		o = append(o, n)
+		for _, c := range children[n] {
+			add(c)
+		}
+		delete(children, n)
+	}
+	for _, n := range roots {
+		add(n)
+	}
+	return o
+}
+
+// EOF
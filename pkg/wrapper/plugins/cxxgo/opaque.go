@@ -0,0 +1,211 @@
+package cxxgo
+
+import (
+	"fmt"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// g_opaques holds the classes and structs which are not selected but are
+// reachable from the signatures of the wrapped functions: they are wrapped
+// as opaque handles. (a pointer wrapper, w/o any method)
+var g_opaques map[string]bool
+
+// is_plain_class returns whether id is a class or struct wrapped as a Go
+// interface of its own. (ie: not a container, smart pointer, callback,
+// string or stream)
+func is_plain_class(id cxxtypes.Id) bool {
+	switch id.(type) {
+	case *cxxtypes.ClassType, *cxxtypes.StructType:
+	default:
+		return false
+	}
+	return get_cxxgo_container(id) == nil &&
+		get_cxxgo_smartptr(id) == nil &&
+		get_cxxgo_callback(id) == nil &&
+		get_cxxgo_string(id) == nil &&
+		strip_stream(id) == nil
+}
+
+// class_of returns the class (or struct) behind the pointers, references,
+// qualifiers and typedefs of id, or nil.
+func class_of(id cxxtypes.Id) cxxtypes.Id {
+	for {
+		switch t := id.(type) {
+		case *cxxtypes.ClassType, *cxxtypes.StructType:
+			return t
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.RefType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.PtrType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.TypedefType:
+			id = cxxtypes.IdByName(t.Type)
+		default:
+			return nil
+		}
+	}
+	panic("unreachable")
+}
+
+// class_bases returns the names of the (non-private) bases of the class id,
+// and of their own bases.
+func class_bases(id cxxtypes.Id) []string {
+	names := []string{}
+	for _, base := range class_base_list(id) {
+		if base.IsPrivate() {
+			continue
+		}
+		names = append(names, base.TypeBase)
+		names = append(names, class_bases(cxxtypes.IdByName(base.TypeBase))...)
+	}
+	return names
+}
+
+// select_opaques marks as opaque handles the plain classes of deps which
+// are neither selected (sel) nor a base of a selected class.
+func select_opaques(sel, deps []string) {
+	full := make(map[string]bool, len(sel))
+	for _, n := range sel {
+		full[n] = true
+		for _, b := range class_bases(cxxtypes.IdByName(n)) {
+			full[b] = true
+		}
+	}
+	for _, n := range deps {
+		if full[n] || !is_plain_class(cxxtypes.IdByName(n)) {
+			continue
+		}
+		g_opaques[n] = true
+	}
+}
+
+// unsupported_type returns why values of type id can not be exchanged
+// between Go and C++, or the empty string if they can.
+func unsupported_type(id cxxtypes.Id) string {
+	for {
+		if id != nil && (get_cxxgo_string(id) != nil ||
+			strip_stream(id) != nil ||
+			get_cxxgo_container(id) != nil ||
+			get_cxxgo_smartptr(id) != nil ||
+			get_cxxgo_callback(id) != nil) {
+			return ""
+		}
+		switch t := id.(type) {
+		case nil:
+			return "unknown type"
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.RefType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.PtrType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.TypedefType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.FundamentalType:
+			if t.TypeKind() == cxxtypes.TK_Void || gen_go_fundamental_name(t) != "" {
+				return ""
+			}
+			if _, ok := _cxx2go_typemap[t.IdScopedName()]; ok {
+				return ""
+			}
+			return fmt.Sprintf("fundamental type [%s]", t.IdScopedName())
+		case *cxxtypes.ClassType, *cxxtypes.StructType, *cxxtypes.EnumType:
+			if mbr := nested_member(id); mbr != nil && !mbr.IsPublic() {
+				// not wrapped (see nested_filter)
+				return fmt.Sprintf("non-accessible nested type [%s]", id.IdScopedName())
+			}
+			return ""
+		case *cxxtypes.ArrayType:
+			return fmt.Sprintf("C array [%s]", t.IdScopedName())
+		case *cxxtypes.UnionType:
+			return fmt.Sprintf("union [%s]", t.IdScopedName())
+		case *cxxtypes.FunctionType:
+			return fmt.Sprintf("function type [%s]", t.IdScopedName())
+		default:
+			return fmt.Sprintf("type [%s] (%T)", id.IdScopedName(), id)
+		}
+	}
+	panic("unreachable")
+}
+
+// unsupported_fct returns why the function fct can not be wrapped, or the
+// empty string if it can.
+func unsupported_fct(fct *cxxtypes.Function) string {
+	for i, _ := range fct.Params {
+		if why := unsupported_type(cxxtypes.IdByName(fct.Params[i].Type)); why != "" {
+			return fmt.Sprintf("parameter %q: %s", fct.Params[i].Name, why)
+		}
+		if why := unsupported_value(cxxtypes.IdByName(fct.Params[i].Type)); why != "" {
+			return fmt.Sprintf("parameter %q: %s", fct.Params[i].Name, why)
+		}
+	}
+	if fct.Ret != "" && fct.Ret != "void" {
+		if why := unsupported_type(cxxtypes.IdByName(fct.Ret)); why != "" {
+			return "result: " + why
+		}
+		if why := unsupported_value(cxxtypes.IdByName(fct.Ret)); why != "" {
+			return "result: " + why
+		}
+	}
+	return ""
+}
+
+// unsupported_value returns why a value of type id can not be exchanged
+// between Go and C++, or the empty string if it can: the classes passed by
+// value are copied (or moved) into (or from) the C++ objects held by the Go
+// handles.
+func unsupported_value(id cxxtypes.Id) string {
+	cls := class_by_value(id)
+	if cls == nil || can_move(cls) {
+		return ""
+	}
+	return fmt.Sprintf("class [%s] passed by value can not be copied nor moved",
+		cls.IdScopedName())
+}
+
+// wrapOpaque wraps the class (or struct) id as an opaque handle: it can be
+// passed to and returned from the wrapped functions, but has no method.
+func (p *plugin) wrapOpaque(cid *cxxgo_id, id cxxtypes.Id) error {
+	var err error
+	fmt.Printf(":: wrapping opaque handle [%s]...\n", id.IdScopedName())
+
+	bufs := new_bufmap("go_iface")
+	go_cls_impl_name := "Gocxxcptr" + cid.goname
+	fmter(bufs["go_iface"],
+		`
+// %s is an opaque handle to the C++ type ::%s
+type %s interface {
+	Gocxxcptr() uintptr
+	GocxxIs%s()
+}
+
+type %s uintptr
+
+func (p %s) Gocxxcptr() uintptr {
+	return uintptr(p)
+}
+
+func (p %s) GocxxIs%s() {
+}
+`,
+		cid.goname, id.IdScopedName(),
+		cid.goname,
+		cid.goname,
+		go_cls_impl_name,
+		go_cls_impl_name,
+		go_cls_impl_name, cid.goname,
+	)
+
+	// commit buffers
+	_, err = bufs["go_iface"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping opaque handle [%s]...[ok]\n", id.IdScopedName())
+	return err
+}
+
+// EOF
@@ -0,0 +1,238 @@
+package cxxgo
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// param_rule selects the direction of a parameter of the functions whose
+// scoped name matches a pattern (see the 'params' argument)
+type param_rule struct {
+	fct   string // pattern of the scoped name of the function
+	param string // name (or index) of the parameter
+	dir   string // in|out|inout|slice
+}
+
+// parse_param_rules parses the value of the 'params' argument: a
+// comma-separated list (or a slice) of fct(param)=dir rules, e.g.
+//
+//	"get_range(lo)=out,get_range(hi)=out,Foo::update(0)=inout"
+//
+// where dir is one of:
+//   - in: the parameter is passed from Go as such (default),
+//   - out: the parameter is dropped from the Go prototype, and its value is
+//     returned as an additional Go result,
+//   - inout: the parameter is passed from Go by value, and its updated value
+//     is returned as an additional Go result,
+//   - slice: the parameter is the pointer of a (T*, length) pair, passed
+//     from Go as a single slice (see the 'views' argument)
+func parse_param_rules(v interface{}) ([]param_rule, error) {
+	var rules []string
+	switch v := v.(type) {
+	case string:
+		rules = strings.Split(v, ",")
+	case []string:
+		rules = v
+	default:
+		return nil, fmt.Errorf(
+			"cxxgo: invalid value for argument 'params' [%v] (expected a comma-separated list of fct(param)=dir)",
+			v)
+	}
+	o := []param_rule{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		beg := strings.LastIndex(rule, "(")
+		end := strings.LastIndex(rule, ")=")
+		if beg <= 0 || end < beg {
+			return nil, fmt.Errorf(
+				"cxxgo: invalid rule [%s] in argument 'params' (expected fct(param)=dir)",
+				rule)
+		}
+		r := param_rule{
+			fct:   strings.TrimLeft(strings.TrimSpace(rule[:beg]), ":"),
+			param: strings.TrimSpace(rule[beg+1 : end]),
+			dir:   strings.TrimSpace(rule[end+2:]),
+		}
+		switch r.dir {
+		case "in", "out", "inout", "slice":
+		default:
+			return nil, fmt.Errorf(
+				"cxxgo: invalid direction [%s] in argument 'params' (expected in|out|inout|slice)",
+				r.dir)
+		}
+		if r.param == "" {
+			return nil, fmt.Errorf(
+				"cxxgo: missing parameter in rule [%s] of argument 'params'",
+				rule)
+		}
+		o = append(o, r)
+	}
+	return o, nil
+}
+
+// match returns whether the rule applies to the i-th parameter of fct
+func (r *param_rule) match(fct *cxxtypes.Function, i int) bool {
+	matched, err := path.Match(r.fct, fct.IdScopedName())
+	if err == path.ErrBadPattern || !matched {
+		return false
+	}
+	if idx, err := strconv.Atoi(r.param); err == nil {
+		return idx == i
+	}
+	return r.param == fct.Param(i).Name
+}
+
+// out_type returns the type of the value a non-const pointer or lvalue
+// reference to a fundamental type id refers to, if the parameter may be
+// exchanged with Go as an output parameter, or nil.
+func out_type(id cxxtypes.Id) cxxtypes.Id {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	var elt cxxtypes.Type
+	switch tt := resolve_typedef(t).(type) {
+	case *cxxtypes.PtrType:
+		elt = tt.UnderlyingType()
+	case *cxxtypes.RefType:
+		if tt.TypeKind() != cxxtypes.TK_LValueRef {
+			return nil
+		}
+		elt = tt.UnderlyingType()
+	default:
+		return nil
+	}
+	if (elt.Qualifiers() & cxxtypes.TQ_Const) != 0 {
+		return nil
+	}
+	elt = cxxtypes.UnqualifiedType(elt)
+	ft, ok := resolve_typedef(elt).(*cxxtypes.FundamentalType)
+	if !ok || container_elt_kind(ft) != "value" {
+		return nil
+	}
+	if _, ptr := resolve_typedef(t).(*cxxtypes.PtrType); ptr {
+		switch ft.TypeKind() {
+		case cxxtypes.TK_Char_S, cxxtypes.TK_Char_U:
+			// C strings
+			return nil
+		}
+	}
+	return elt.(cxxtypes.Id)
+}
+
+// param_dirs returns the direction (in|out|inout|slice) of each parameter of
+// fct, from the 'params' rules or, for non-const lvalue references to
+// fundamental types, from the 'out-refs' argument.
+// Parameters which can not be handled in the selected direction are passed
+// as input parameters.
+func (p *plugin) param_dirs(fct *cxxtypes.Function) []string {
+	if len(p.params) == 0 && p.out_refs == "" {
+		return nil
+	}
+	nreq := fct.NumParam() - fct.NumDefaultParam()
+	dirs := make([]string, fct.NumParam())
+	for i, _ := range fct.Params {
+		dirs[i] = "in"
+		id := cxxtypes.IdByName(fct.Param(i).Type)
+		if ref, ok := id.(*cxxtypes.RefType); ok && p.out_refs != "" &&
+			ref.TypeKind() == cxxtypes.TK_LValueRef && out_type(id) != nil {
+			dirs[i] = p.out_refs
+		}
+		for j, _ := range p.params {
+			if p.params[j].match(fct, i) {
+				dirs[i] = p.params[j].dir
+			}
+		}
+		why := ""
+		switch dirs[i] {
+		case "out", "inout":
+			switch {
+			case i >= nreq:
+				why = "optional parameter"
+			case out_type(id) == nil:
+				why = "not a pointer nor a reference to a fundamental type"
+			}
+		case "slice":
+			if i+1 >= nreq || view_elt_type(id) == nil || !is_view_len_type(cxxtypes.IdByName(fct.Param(i+1).Type)) {
+				why = "not followed by a length parameter"
+			}
+		}
+		if why != "" {
+			fmt.Printf(":: ignoring '%s' parameter [%d] of [%s] (%s)\n",
+				dirs[i], i, fct.Signature(), why)
+			dirs[i] = "in"
+		}
+	}
+	return dirs
+}
+
+// dir returns the direction (in|out|inout|slice) of the i-th parameter
+func (f *cxxgo_function) dir(i int) string {
+	if i >= len(f.dirs) {
+		return "in"
+	}
+	return f.dirs[i]
+}
+
+// is_out returns whether the value of the i-th parameter is returned as an
+// additional Go result
+func (f *cxxgo_function) is_out(i int) bool {
+	return f.dir(i) == "out" || f.dir(i) == "inout"
+}
+
+// nout returns the number of output parameters dropped from the Go prototype
+func (f *cxxgo_function) nout() int {
+	n := 0
+	for i, _ := range f.dirs {
+		if f.dir(i) == "out" {
+			n += 1
+		}
+	}
+	return n
+}
+
+// go_outs returns the Go variables holding the output parameters, in order
+func (f *cxxgo_function) go_outs() []string {
+	outs := []string{}
+	for i, _ := range f.dirs {
+		if f.is_out(i) {
+			outs = append(outs, fmt.Sprintf("arg_%d", i))
+		}
+	}
+	return outs
+}
+
+// with_outs appends the output parameters to the Go results of the return
+// statements of the Go code lines, or adds a return statement to them.
+func (f *cxxgo_function) with_outs(lines []string) []string {
+	outs := f.go_outs()
+	if len(outs) == 0 {
+		return lines
+	}
+	found := false
+	o := make([]string, 0, len(lines)+1)
+	for _, line := range lines {
+		sub := strings.SplitAfter(line, "\n")
+		for i, s := range sub {
+			stmt := strings.TrimLeft(s, "\t")
+			if strings.HasPrefix(stmt, "return ") {
+				found = true
+				sub[i] = strings.TrimSuffix(s, "\n") + ", " + strings.Join(outs, ", ") + "\n"
+			}
+		}
+		o = append(o, strings.Join(sub, ""))
+	}
+	if !found {
+		o = append(o, "\treturn "+strings.Join(outs, ", ")+"\n")
+	}
+	return o
+}
+
+// EOF
@@ -0,0 +1,556 @@
+package cxxgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// protected_accessor_name returns the name of the C++ class giving access to
+// the protected members of the class id.
+func protected_accessor_name(pkg string, id cxxtypes.Id) string {
+	return fmt.Sprintf("_gocxx_protected_%s_%s", pkg, get_iid_str(id))
+}
+
+// protected_field_type returns the fundamental type of the protected data
+// member mbr, and whether it is const, or nil if it can not be exchanged
+// with Go.
+func protected_field_type(mbr *cxxtypes.Member) (cxxtypes.Id, bool) {
+	id := cxxtypes.IdByName(mbr.Type)
+	cst := false
+	if cvr, ok := id.(*cxxtypes.CvrQualType); ok {
+		cst = (cvr.Qualifiers() & cxxtypes.TQ_Const) != 0
+		id = cxxtypes.IdByName(cvr.Type)
+	}
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil, false
+	}
+	ft, ok := resolve_typedef(t).(*cxxtypes.FundamentalType)
+	if !ok || ft.TypeKind() == cxxtypes.TK_Void || gen_go_fundamental_name(ft) == "" {
+		return nil, false
+	}
+	return id, cst
+}
+
+// cxxgo_protected holds the protected members of a class, made public by its
+// accessor class (see wrapAccessors)
+type cxxgo_protected struct {
+	fcts   []*cxxgo_overload_fct_set_t // the protected methods
+	fields []*cxxtypes.Member          // the protected data members
+	usings []string                    // the names made public by the accessor
+	hooks  []*cxxgo_hook               // the protected methods overridden by Go
+}
+
+// cxxgo_hook is a protected virtual method of a class, overridden by its
+// extension class to call back a Go method. (see wrapProtected)
+// Like for the callbacks, only fundamental types and std::string are
+// exchanged.
+type cxxgo_hook struct {
+	f      *cxxtypes.Function
+	goname string
+	kinds  []string // how the parameters are exchanged (see callback_kind)
+	rkind  string   // how the result is exchanged ("" for void)
+}
+
+// new_cxxgo_hook returns the hook overriding the protected virtual method
+// fct, or nil if its parameters or result can not be exchanged with Go.
+func new_cxxgo_hook(fct *cxxtypes.Function) *cxxgo_hook {
+	h := &cxxgo_hook{
+		f:      fct,
+		goname: gen_go_name_from_id(fct),
+	}
+	for i, _ := range fct.Params {
+		k := callback_kind(fct.Params[i].Type)
+		if k == "" {
+			return nil
+		}
+		h.kinds = append(h.kinds, k)
+	}
+	if fct.Ret != "" && fct.Ret != "void" {
+		h.rkind = callback_kind(fct.Ret)
+		if h.rkind == "" {
+			return nil
+		}
+		// the result is returned by value
+		if s := get_cxxgo_string(cxxtypes.IdByName(fct.Ret)); s != nil && s.ref {
+			return nil
+		}
+	}
+	return h
+}
+
+// public_go_names returns the Go names of the public methods of the class
+// id and of its bases.
+func (p *plugin) public_go_names(id cxxtypes.Id) map[string]bool {
+	names := p.go_method_names(id)
+	for _, base := range class_base_list(id) {
+		for n, _ := range p.public_go_names(cxxtypes.IdByName(base.TypeBase)) {
+			names[n] = true
+		}
+	}
+	return names
+}
+
+// class_dtor returns the destructor declared by the class id, or nil.
+func class_dtor(id cxxtypes.Id) *cxxtypes.Function {
+	for _, mbr := range class_member_list(id) {
+		if !mbr.IsFunctionMember() {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for ifct, _ := range ovfct.Fcts {
+			if fct := ovfct.Function(ifct); fct.IsDestructor() {
+				return fct
+			}
+		}
+	}
+	return nil
+}
+
+// has_virtual_dtor returns whether the class id declares or inherits a
+// virtual destructor.
+func has_virtual_dtor(id cxxtypes.Id) bool {
+	if dtor := class_dtor(id); dtor != nil && dtor.IsVirtual() {
+		return true
+	}
+	for _, base := range class_base_list(id) {
+		if has_virtual_dtor(cxxtypes.IdByName(base.TypeBase)) {
+			return true
+		}
+	}
+	return false
+}
+
+// protected_members returns the protected methods and data members of the
+// class id which are wrapped, and its protected virtual methods which can be
+// overridden by Go.
+// The protected methods named like a public one are prefixed with
+// "Protected", as both are methods of the <Class>Protected handle.
+func (p *plugin) protected_members(id *cxxtypes.ClassType) *cxxgo_protected {
+	prot := &cxxgo_protected{}
+	public := p.public_go_names(id)
+	for i, _ := range id.Members {
+		mbr := &id.Members[i]
+		if is_anon(mbr.Name) {
+			continue
+		}
+		switch {
+		case mbr.IsFunctionMember():
+			ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+			if !ok {
+				continue
+			}
+			n := 0
+			for _, cgo_ovfct := range p.new_cxxgo_protected_ovfcts(ovfct) {
+				f := cgo_ovfct.fcts[0].f
+				if f.IsConstructor() ||
+					f.IsDestructor() ||
+					f.IsCopyConstructor() ||
+					f.IsAssignOperator() {
+					continue
+				}
+				if public[cgo_ovfct.goname] {
+					cgo_ovfct.goname = "Protected" + cgo_ovfct.goname
+					for i, _ := range cgo_ovfct.fcts {
+						cgo_ovfct.fcts[i].goname = "Protected" + cgo_ovfct.fcts[i].goname
+					}
+				}
+				prot.fcts = append(prot.fcts, cgo_ovfct)
+				n += 1
+			}
+			if n > 0 {
+				prot.usings = append(prot.usings, mbr.IdName())
+			}
+			for ifct, _ := range ovfct.Fcts {
+				fct := ovfct.Function(ifct)
+				if !fct.IsProtected() || !fct.IsVirtual() ||
+					fct.IsDestructor() || fct.IsOperator() || fct.IsDeleted() {
+					continue
+				}
+				h := new_cxxgo_hook(fct)
+				if h == nil {
+					fmt.Printf(":: not overriding [%s] (unsupported parameter or result)\n",
+						fct.Signature())
+					continue
+				}
+				prot.hooks = append(prot.hooks, h)
+			}
+		case mbr.IsDataMember() && mbr.IsProtected():
+			if t, _ := protected_field_type(mbr); t == nil {
+				fmt.Printf(":: discarding protected [%s] (unsupported type [%s])\n",
+					mbr.IdScopedName(), mbr.Type)
+				continue
+			}
+			prot.fields = append(prot.fields, mbr)
+			prot.usings = append(prot.usings, mbr.IdName())
+		}
+	}
+	// overloaded hooks
+	count := make(map[string]int, len(prot.hooks))
+	for _, h := range prot.hooks {
+		count[h.goname] += 1
+	}
+	for i, h := range prot.hooks {
+		if count[h.goname] > 1 {
+			h.goname += fmt.Sprintf("_%d", i)
+		}
+	}
+	return prot
+}
+
+// unextendable returns why the class id can not be extended by Go, or the
+// empty string if it can: the extension class has to be default
+// constructed, destroyed through the Go handles of id, and to override all
+// the pure virtual methods.
+func unextendable(id *cxxtypes.ClassType, prot *cxxgo_protected) string {
+	if len(prot.hooks) == 0 {
+		return "no protected virtual method to override"
+	}
+	hooked := make(map[string]bool, len(prot.hooks))
+	for _, h := range prot.hooks {
+		hooked[h.f.Signature()] = true
+	}
+	ctors, dflt, pure := 0, false, 0
+	for _, mbr := range id.Members {
+		if !mbr.IsFunctionMember() {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for ifct, _ := range ovfct.Fcts {
+			fct := ovfct.Function(ifct)
+			if fct.IsPureVirtual() {
+				if !hooked[fct.Signature()] {
+					return fmt.Sprintf("pure virtual [%s] can not be overridden", fct.Signature())
+				}
+				pure += 1
+			}
+			if fct.IsConstructor() && !fct.IsCopyConstructor() &&
+				self_ref_kind(fct) == cxxtypes.TK_Invalid {
+				ctors += 1
+				if !fct.IsPrivate() && !fct.IsDeleted() &&
+					fct.NumParam() == fct.NumDefaultParam() {
+					dflt = true
+				}
+			}
+		}
+	}
+	if ctors > 0 && !dflt {
+		return "no default constructor"
+	}
+	if dtor := class_dtor(id); !has_virtual_dtor(id) ||
+		dtor != nil && (dtor.IsPrivate() || dtor.IsDeleted()) {
+		return "no virtual destructor"
+	}
+	if cxxtypes.IsAbstractType(id) {
+		if pure == 0 {
+			// declared by a base, or not recorded
+			return "abstract class"
+		}
+		for _, base := range class_base_list(id) {
+			if t, ok := cxxtypes.IdByName(base.TypeBase).(cxxtypes.Type); ok && cxxtypes.IsAbstractType(t) {
+				return fmt.Sprintf("abstract base [%s]", base.TypeBase)
+			}
+		}
+	}
+	return ""
+}
+
+// wrapAccessors declares the accessor classes of the selected classes which
+// can be extended by Go: they derive from the class and make its protected
+// members public, so the C++ wrappers can reach them.
+func (p *plugin) wrapAccessors() error {
+	var err error
+	pkg := p.gen.Fd.Package
+	cxx := p.gen.Fd.Files["cxx"]
+
+	for _, n := range p.ids {
+		id, ok := cxxtypes.IdByName(n).(*cxxtypes.ClassType)
+		if !ok || !is_plain_class(id) || g_opaques[n] {
+			continue
+		}
+		prot := p.protected_members(id)
+		if len(prot.usings)+len(prot.hooks) == 0 {
+			continue
+		}
+		if why := unextendable(id, prot); why != "" {
+			fmt.Printf(":: discarding protected members of [%s] (%s)\n", n, why)
+			continue
+		}
+		p.protected[n] = prot
+		_, err = fmt.Fprintf(cxx,
+			"\n// gives access to the protected members of [%s]\nclass %s : public ::%s {\npublic:\n",
+			n, protected_accessor_name(pkg, id), n,
+		)
+		if err != nil {
+			return err
+		}
+		for _, u := range prot.usings {
+			_, err = fmt.Fprintf(cxx, "  using ::%s::%s;\n", n, u)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(cxx, "};\n")
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// wrapProtected wraps the extension of the class id by Go: a C++ class
+// deriving from id, whose protected virtual methods call back the methods of
+// a <Class>Extension Go value, registered with the other Go funcs called
+// from C++ (see _gocxx_gofunc_new).
+// The protected methods and data members of id are not part of its Go
+// interface: they are methods of the <Class>Protected handle, given to the
+// <Class>Extension methods. The C++ side reaches them through the accessor
+// class of id. (see wrapAccessors)
+func (p *plugin) wrapProtected(cid *cxxgo_id, id *cxxtypes.ClassType) error {
+	var err error
+	pkg := p.gen.Fd.Package
+
+	prot := p.protected[id.IdScopedName()]
+	if prot == nil {
+		return err
+	}
+	fcts, fields := prot.fcts, prot.fields
+
+	fmt.Printf(":: wrapping protected members of [%s]...\n", id.IdScopedName())
+	n := id.IdScopedName()
+	acc := protected_accessor_name(pkg, id)
+	ext := fmt.Sprintf("_gocxx_extension_%s_%s", pkg, get_iid_str(id))
+	go_prot_name := cid.goname + "Protected"
+	go_prot_impl_name := "gocxxcptr" + go_prot_name
+	go_ext_name := cid.goname + "Extension"
+
+	bufs := new_bufmap("go_iface", "go_impl", "cxx_tail", "cgo_head")
+
+	// the Go side: the hooks, called back with the protected handle
+	fmter(bufs["go_iface"],
+		"\n// %[1]s is implemented by the Go types extending the C++ class\n// ::%[2]s (see New%[1]s): its methods override the protected virtual\n// methods of ::%[2]s, and are given the extended object.\ntype %[1]s interface {\n",
+		go_ext_name, n,
+	)
+	for _, h := range prot.hooks {
+		fmter(bufs["go_iface"], "\t%s\n", h.go_prototype(go_prot_name))
+	}
+	fmter(bufs["go_iface"],
+		"}\n\n// %[1]s is a C++ ::%[2]s extended by Go, with its protected\n// members. It is only given to the methods of %[3]s.\ntype %[1]s interface {\n\t%[4]s\n\tgocxxIs%[1]s()\n\n",
+		go_prot_name, n, go_ext_name, cid.goname,
+	)
+	for _, cgo_ovfct := range fcts {
+		if cgo_ovfct.needs_dispatch() && p.ovl_typed {
+			for i, _ := range cgo_ovfct.fcts {
+				fmter(bufs["go_iface"], "\t%s\n", cgo_ovfct.fcts[i].go_prototype())
+			}
+		}
+		if !cgo_ovfct.needs_dispatch() || p.ovl_dispatch {
+			fmter(bufs["go_iface"], "\t%s\n", cgo_ovfct.go_prototype())
+		}
+	}
+
+	fmter(bufs["go_impl"],
+		"\ntype %[1]s struct {\n\tGocxxcptr%[2]s\n}\n\nfunc (p %[1]s) gocxxIs%[3]s() {\n}\n",
+		go_prot_impl_name, cid.goname, go_prot_name,
+	)
+	fmter(bufs["go_impl"],
+		"\n// New%[1]s creates a C++ ::%[2]s whose protected virtual methods\n// call the methods of ext.\nfunc New%[1]s(ext %[1]s) %[3]s {\n\tc_fct := _gocxx_gofunc_new(func(args, ret unsafe.Pointer) {\n\t\tc_hook := unsafe.Slice((*unsafe.Pointer)(args), 2)\n\t\tself := %[4]s{*(*Gocxxcptr%[3]s)(c_hook[1])}\n\t\tswitch *(*C.size_t)(c_hook[0]) {\n",
+		go_ext_name, n, cid.goname, go_prot_impl_name,
+	)
+	for ih, h := range prot.hooks {
+		fmter(bufs["go_impl"], "\t\tcase %d:\n", ih)
+		if len(h.kinds) > 0 {
+			fmter(bufs["go_impl"],
+				"\t\t\tc_args := unsafe.Slice((*unsafe.Pointer)(args), %d)\n",
+				2+len(h.kinds),
+			)
+		}
+		go_args := []string{"self"}
+		for i, k := range h.kinds {
+			if k == "string" {
+				fmter(bufs["go_impl"],
+					"\t\t\tc_arg_%d := (*C._gocxx_strview)(c_args[%d])\n\t\t\targ_%d := C.GoStringN(c_arg_%d.p, C.int(c_arg_%d.n))\n",
+					i, 2+i, i, i, i,
+				)
+			} else {
+				fmter(bufs["go_impl"],
+					"\t\t\targ_%d := *(*%s)(c_args[%d])\n",
+					i, kind_goname(h.f.Params[i].Type, k), 2+i,
+				)
+			}
+			go_args = append(go_args, fmt.Sprintf("arg_%d", i))
+		}
+		call := fmt.Sprintf("ext.%s(%s)", h.goname, strings.Join(go_args, ", "))
+		switch h.rkind {
+		case "":
+			fmter(bufs["go_impl"], "\t\t\t%s\n", call)
+		case "string":
+			// the C copy is released by C++
+			fmter(bufs["go_impl"],
+				"\t\t\t*(*C._gocxx_strview)(ret) = _gocxx_strview_from_go(%s)\n",
+				call,
+			)
+		default:
+			fmter(bufs["go_impl"],
+				"\t\t\t*(*%s)(ret) = %s\n",
+				kind_goname(h.f.Ret, h.rkind), call,
+			)
+		}
+	}
+	fmter(bufs["go_impl"],
+		"\t\t}\n\t})\n\tvar c_ptr Gocxxcptr%[1]s\n\tC.%[2]s_new(unsafe.Pointer(&c_ptr), unsafe.Pointer(&c_fct))\n\treturn c_ptr\n}\n",
+		cid.goname, ext,
+	)
+
+	// the C++ side: the extension class packs the arguments of the hooks,
+	// along with their index and the extended object
+	fmter(bufs["cxx_tail"],
+		"\n// extends [%[1]s] by Go: calls back the Go methods registered as c_fct\nclass %[2]s : public %[3]s {\n  _gocxx_gofunc_ref m_go;\npublic:\n  %[2]s(void *c_fct) : m_go(*(_gocxx_gofunc*)c_fct) {}\n",
+		n, ext, acc,
+	)
+	for ih, h := range prot.hooks {
+		params := make([]string, 0, len(h.kinds))
+		c_args := []string{"(void*)&c_hook", "(void*)&c_self"}
+		body := new(bytes.Buffer)
+		for i, k := range h.kinds {
+			params = append(params, fmt.Sprintf("%s a%d", h.f.Params[i].Type, i))
+			if k == "string" {
+				fmter(body, "    _gocxx_strview c_a%d = { a%d.data(), a%d.size() };\n", i, i, i)
+				c_args = append(c_args, fmt.Sprintf("(void*)&c_a%d", i))
+			} else {
+				c_args = append(c_args, fmt.Sprintf("(void*)&a%d", i))
+			}
+		}
+		ret := "void"
+		if h.rkind != "" {
+			ret = h.f.Ret
+		}
+		cst := ""
+		if h.f.IsConst() {
+			cst = " const"
+		}
+		fmter(bufs["cxx_tail"],
+			"  %s %s(%s)%s {\n    size_t c_hook = %d;\n    void *c_self = (void*)static_cast<const ::%s*>(this);\n%s    void *c_args[] = { %s };\n",
+			ret, h.f.IdName(), strings.Join(params, ", "), cst,
+			ih, n, body.String(), strings.Join(c_args, ", "),
+		)
+		switch h.rkind {
+		case "":
+			fmter(bufs["cxx_tail"], "    m_go.call(c_args, NULL);\n")
+		case "string":
+			fmter(bufs["cxx_tail"],
+				"    _gocxx_strview c_ret = { NULL, 0 };\n    m_go.call(c_args, (void*)&c_ret);\n    %s cxx_ret(c_ret.p ? c_ret.p : \"\", c_ret.n);\n    free((void*)c_ret.p);\n    return cxx_ret;\n",
+				h.f.Ret,
+			)
+		default:
+			fmter(bufs["cxx_tail"],
+				"    %s cxx_ret{};\n    m_go.call(c_args, (void*)&cxx_ret);\n    return cxx_ret;\n",
+				h.f.Ret,
+			)
+		}
+		fmter(bufs["cxx_tail"], "  }\n")
+	}
+	fmter(bufs["cxx_tail"],
+		"};\n\n// creates a [%[1]s] extended by Go\nvoid %[2]s_new(void *c_this, void *c_fct)\n{\n  *((void**)c_this) = (void*)static_cast< ::%[1]s*>(new %[2]s(c_fct));\n}\n",
+		n, ext,
+	)
+	fmter(bufs["cgo_head"],
+		"\n/* creates a [%s] extended by Go */\nvoid %s_new(void *c_this, void *c_fct);\n",
+		n, ext,
+	)
+
+	for _, mbr := range fields {
+		t, cst := protected_field_type(mbr)
+		dm_name := strings.Title(mbr.IdName())
+		dm_typename := gen_go_name_from_id(t)
+		cn := fmt.Sprintf("_gocxx_protected_%s_%s", pkg, get_iid_str(mbr))
+
+		fmter(bufs["go_iface"], "\tGet%s() %s\n", dm_name, dm_typename)
+		fmter(bufs["go_impl"],
+			"\n// Get%[1]s returns the protected field [%[2]s]\nfunc (p %[3]s) Get%[1]s() %[4]s {\n\tvar v %[4]s\n\tC.%[5]s_get(unsafe.Pointer(p.Gocxxcptr%[6]s), unsafe.Pointer(&v))\n\treturn v\n}\n",
+			dm_name, mbr.IdScopedName(), go_prot_impl_name, dm_typename, cn, cid.goname,
+		)
+		fmter(bufs["cxx_tail"],
+			"\n// gets [%s]\nvoid %s_get(void *c_self, void *c_val)\n{\n  *(%s*)c_val = ((%s*)c_self)->%s;\n}\n",
+			mbr.IdScopedName(), cn, t.IdScopedName(), acc, mbr.IdName(),
+		)
+		fmter(bufs["cgo_head"],
+			"\n/* gets [%s] */\nvoid %s_get(void *c_self, void *c_val);\n",
+			mbr.IdScopedName(), cn,
+		)
+		if cst {
+			continue
+		}
+		fmter(bufs["go_iface"], "\tSet%s(v %s)\n", dm_name, dm_typename)
+		fmter(bufs["go_impl"],
+			"\n// Set%[1]s sets the protected field [%[2]s]\nfunc (p %[3]s) Set%[1]s(v %[4]s) {\n\tC.%[5]s_set(unsafe.Pointer(p.Gocxxcptr%[6]s), unsafe.Pointer(&v))\n}\n",
+			dm_name, mbr.IdScopedName(), go_prot_impl_name, dm_typename, cn, cid.goname,
+		)
+		fmter(bufs["cxx_tail"],
+			"\n// sets [%s]\nvoid %s_set(void *c_self, void *c_val)\n{\n  ((%s*)c_self)->%s = *(%s*)c_val;\n}\n",
+			mbr.IdScopedName(), cn, acc, mbr.IdName(), t.IdScopedName(),
+		)
+		fmter(bufs["cgo_head"],
+			"\n/* sets [%s] */\nvoid %s_set(void *c_self, void *c_val);\n",
+			mbr.IdScopedName(), cn,
+		)
+	}
+	fmter(bufs["go_iface"], "}\n")
+
+	// commit buffers
+	_, err = bufs["go_iface"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx_tail"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	for _, cgo_ovfct := range fcts {
+		err = p.wrapOverloads(get_cxxgo_id(pkg, cgo_ovfct.ovfct), cgo_ovfct)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf(":: wrapping protected members of [%s]...[ok]\n", id.IdScopedName())
+	return err
+}
+
+// go_prototype returns the Go prototype of the method overriding the hook,
+// given the protected handle named prot.
+// e.g. Do_execute(self IAlgProtected, arg_0 int32) int32
+func (h *cxxgo_hook) go_prototype(prot string) string {
+	args := []string{"self " + prot}
+	for i, k := range h.kinds {
+		args = append(args, fmt.Sprintf("arg_%d %s", i, kind_goname(h.f.Params[i].Type, k)))
+	}
+	n := h.goname + "(" + strings.Join(args, ", ") + ")"
+	if h.rkind != "" {
+		n += " " + kind_goname(h.f.Ret, h.rkind)
+	}
+	return n
+}
+
+// EOF
@@ -0,0 +1,315 @@
+package cxxgo
+
+import (
+	"fmt"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// class_base_list returns the bases of the class (or struct) id
+func class_base_list(id cxxtypes.Id) []cxxtypes.Base {
+	switch id := id.(type) {
+	case *cxxtypes.ClassType:
+		return id.Bases
+	case *cxxtypes.StructType:
+		return id.Bases
+	}
+	return nil
+}
+
+// class_member_list returns the members of the class (or struct) id
+func class_member_list(id cxxtypes.Id) []cxxtypes.Member {
+	switch id := id.(type) {
+	case *cxxtypes.ClassType:
+		return id.Members
+	case *cxxtypes.StructType:
+		return id.Members
+	}
+	return nil
+}
+
+// is_polymorphic returns whether the class id declares or inherits a
+// virtual function. (ie: whether dynamic_cast and typeid can be applied to
+// its instances)
+func is_polymorphic(id cxxtypes.Id) bool {
+	for _, mbr := range class_member_list(id) {
+		if !mbr.IsFunctionMember() {
+			continue
+		}
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for _, fct := range ovfct.Fcts {
+			if fct.IsVirtual() {
+				return true
+			}
+		}
+	}
+	for _, base := range class_base_list(id) {
+		if is_polymorphic(cxxtypes.IdByName(base.TypeBase)) {
+			return true
+		}
+	}
+	return false
+}
+
+// poly_root returns the topmost polymorphic class reached from the
+// polymorphic class id by following its first public polymorphic bases.
+func poly_root(id cxxtypes.Id) cxxtypes.Id {
+	for {
+		var next cxxtypes.Id
+		for _, base := range class_base_list(id) {
+			bid := cxxtypes.IdByName(base.TypeBase)
+			if base.IsPublic() && is_polymorphic(bid) {
+				next = bid
+				break
+			}
+		}
+		if next == nil {
+			return id
+		}
+		id = next
+	}
+	panic("unreachable")
+}
+
+// base_path returns the chain of public bases leading from the class id to
+// its base subobject of class root (id excluded, root included), if root
+// is an unambiguous public base of id.
+func base_path(id, root cxxtypes.Id) ([]cxxtypes.Id, bool) {
+	type step struct {
+		path []cxxtypes.Id
+		key  string // identifies the base subobject reached by path
+	}
+	var walk func(id cxxtypes.Id, cur step) []step
+	walk = func(id cxxtypes.Id, cur step) []step {
+		if id.IdScopedName() == root.IdScopedName() {
+			return []step{cur}
+		}
+		o := []step{}
+		for _, base := range class_base_list(id) {
+			if !base.IsPublic() {
+				continue
+			}
+			bid := cxxtypes.IdByName(base.TypeBase)
+			next := step{
+				path: append(append([]cxxtypes.Id{}, cur.path...), bid),
+				key:  cur.key + "/" + bid.IdScopedName(),
+			}
+			if base.IsVirtual() {
+				// only one subobject of a virtual base
+				next.key = bid.IdScopedName()
+			}
+			o = append(o, walk(bid, next)...)
+		}
+		return o
+	}
+	steps := walk(id, step{key: id.IdScopedName()})
+	if len(steps) == 0 {
+		return nil, false
+	}
+	for _, s := range steps[1:] {
+		if s.key != steps[0].key {
+			return nil, false
+		}
+	}
+	return steps[0].path, true
+}
+
+// wrapDynamic gives access to the dynamic type of the instances of the
+// wrapped polymorphic classes:
+//   - As<D>(b B) (D, bool) downcasts (w/ a dynamic_cast) a handle to the
+//     topmost polymorphic base B of D, whatever the class of the handle:
+//     the handles of B and of the classes deriving from it give access to
+//     their B subobject through their gocxxRoot<B>() method,
+//   - GocxxMostDerived(o) returns the handle of the most-derived wrapped class
+//     of the object held by o, looked up by RTTI name.
+func (p *plugin) wrapDynamic() error {
+	var err error
+	pkg := p.gen.Fd.Package
+
+	ids := []cxxtypes.Id{}
+	for _, n := range p.ids {
+		id, ok := cxxtypes.IdByName(n).(*cxxtypes.ClassType)
+		if !ok || !is_plain_class(id) || g_opaques[n] || !is_polymorphic(id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return err
+	}
+
+	fmt.Printf(":: wrapping dynamic types...\n")
+	bufs := new_bufmap("go_impl", "cxx_tail", "cgo_head")
+
+	fmter(bufs["go_impl"], `
+// gocxx_rtti_registry holds the handle constructors of the wrapped
+// polymorphic classes, keyed by the RTTI name of the C++ class.
+var gocxx_rtti_registry = map[string]func(unsafe.Pointer) interface{ Gocxxcptr() uintptr }{}
+
+// GocxxMostDerived returns the handle of the most-derived wrapped class of
+// the C++ object held by o (as given by typeid), or o itself if that class
+// is not polymorphic or not wrapped.
+func GocxxMostDerived(o interface{ Gocxxcptr() uintptr }) interface{ Gocxxcptr() uintptr } {
+	d, ok := o.(interface {
+		gocxxDynamic() (string, unsafe.Pointer)
+	})
+	if !ok || o.Gocxxcptr() == 0 {
+		return o
+	}
+	n, ptr := d.gocxxDynamic()
+	if f, ok := gocxx_rtti_registry[n]; ok {
+		return f(ptr)
+	}
+	return o
+}
+
+func init() {
+`)
+
+	for _, id := range ids {
+		cid := get_cxxgo_id(pkg, id)
+		n := id.IdScopedName()
+		rtti := fmt.Sprintf("_gocxx_rtti_%s_%s", pkg, get_iid_str(id))
+		dyn := fmt.Sprintf("_gocxx_dynamic_%s_%s", pkg, get_iid_str(id))
+
+		fmter(bufs["go_impl"],
+			"\tgocxx_rtti_registry[C.GoString(C.%s())] = func(p unsafe.Pointer) interface{ Gocxxcptr() uintptr } {\n\t\treturn Gocxxcptr%s(p)\n\t}\n",
+			rtti, cid.goname,
+		)
+
+		fmter(bufs["cxx_tail"],
+			"\n// returns the RTTI name of [%s]\nconst char* %s()\n{\n  return typeid(::%s).name();\n}\n",
+			n, rtti, n,
+		)
+		fmter(bufs["cxx_tail"],
+			"\n// returns the RTTI name and the address of the most-derived object of [%s]\nconst char* %s(void *c_self, void *c_ptr)\n{\n  ::%s *self = (::%s*)c_self;\n  *(void**)c_ptr = dynamic_cast<void*>(self);\n  return typeid(*self).name();\n}\n",
+			n, dyn, n, n,
+		)
+		fmter(bufs["cgo_head"],
+			"\n/* returns the RTTI name of [%s] */\nconst char* %s(void);\n",
+			n, rtti,
+		)
+		fmter(bufs["cgo_head"],
+			"\n/* returns the RTTI name and the address of the most-derived object of [%s] */\nconst char* %s(void *c_self, void *c_ptr);\n",
+			n, dyn,
+		)
+	}
+	fmter(bufs["go_impl"], "}\n")
+
+	// the topmost polymorphic bases with downcasts
+	roots := []cxxtypes.Id{}
+	for _, id := range ids {
+		root := poly_root(id)
+		if root == id || !str_is_in_slice(root.IdScopedName(), p.ids) {
+			continue
+		}
+		dup := false
+		for _, r := range roots {
+			dup = dup || r == root
+		}
+		if !dup {
+			roots = append(roots, root)
+		}
+	}
+
+	for _, id := range ids {
+		cid := get_cxxgo_id(pkg, id)
+		n := id.IdScopedName()
+		dyn := fmt.Sprintf("_gocxx_dynamic_%s_%s", pkg, get_iid_str(id))
+
+		for _, root := range roots {
+			bid := get_cxxgo_id(pkg, root)
+			if root == id {
+				fmter(bufs["go_impl"],
+					"\nfunc (p Gocxxcptr%s) gocxxRoot%s() unsafe.Pointer {\n\treturn unsafe.Pointer(p)\n}\n",
+					cid.goname, bid.goname,
+				)
+				continue
+			}
+			path, ok := base_path(id, root)
+			if !ok {
+				continue
+			}
+			cn := fmt.Sprintf("_gocxx_root_%s_%s_%s", pkg, get_iid_str(id), get_iid_str(root))
+			fmter(bufs["go_impl"],
+				"\nfunc (p Gocxxcptr%s) gocxxRoot%s() unsafe.Pointer {\n\treturn C.%s(unsafe.Pointer(p))\n}\n",
+				cid.goname, bid.goname, cn,
+			)
+			cast := fmt.Sprintf("(::%s*)c_self", n)
+			for _, b := range path {
+				cast = fmt.Sprintf("(::%s*)%s", b.IdScopedName(), cast)
+			}
+			fmter(bufs["cxx_tail"],
+				"\n// converts [%s] to its polymorphic root [%s]\nvoid* %s(void *c_self)\n{\n  return %s;\n}\n",
+				n, root.IdScopedName(), cn, cast,
+			)
+			fmter(bufs["cgo_head"],
+				"\n/* converts [%s] to its polymorphic root [%s] */\nvoid* %s(void *c_self);\n",
+				n, root.IdScopedName(), cn,
+			)
+		}
+		fmter(bufs["go_impl"],
+			"\nfunc (p Gocxxcptr%s) gocxxDynamic() (string, unsafe.Pointer) {\n\tvar c_ptr unsafe.Pointer\n\tc_name := C.%s(unsafe.Pointer(p), unsafe.Pointer(&c_ptr))\n\treturn C.GoString(c_name), c_ptr\n}\n",
+			cid.goname, dyn,
+		)
+
+		root := poly_root(id)
+		if root == cxxtypes.Id(id) || !str_is_in_slice(root.IdScopedName(), p.ids) {
+			continue
+		}
+		bid := get_cxxgo_id(pkg, root)
+		cn := fmt.Sprintf("_gocxx_downcast_%s_%s_%s", pkg, get_iid_str(root), get_iid_str(id))
+		fmter(bufs["go_impl"],
+			`
+// As%[1]s returns b as a %[1]s if the C++ object it holds is a ::%[3]s
+// (see dynamic_cast)
+func As%[1]s(b %[2]s) (%[1]s, bool) {
+	r, ok := b.(interface {
+		gocxxRoot%[2]s() unsafe.Pointer
+	})
+	if !ok || b.Gocxxcptr() == 0 {
+		return nil, false
+	}
+	c := C.%[4]s(r.gocxxRoot%[2]s())
+	if c == nil {
+		return nil, false
+	}
+	return Gocxxcptr%[1]s(c), true
+}
+`,
+			cid.goname, bid.goname, n, cn,
+		)
+		fmter(bufs["cxx_tail"],
+			"\n// downcasts [%s] to [%s]\nvoid* %s(void *c_self)\n{\n  return dynamic_cast< ::%s*>((::%s*)c_self);\n}\n",
+			root.IdScopedName(), n, cn, n, root.IdScopedName(),
+		)
+		fmter(bufs["cgo_head"],
+			"\n/* downcasts [%s] to [%s] */\nvoid* %s(void *c_self);\n",
+			root.IdScopedName(), n, cn,
+		)
+	}
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx_tail"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping dynamic types...[ok]\n")
+	return err
+}
+
+// EOF
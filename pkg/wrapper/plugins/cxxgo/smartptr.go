@@ -0,0 +1,222 @@
+package cxxgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// cxxgo_smartptr describes a std::shared_ptr or std::unique_ptr to a
+// wrapped class. It is presented to Go as a handle holding a heap-allocated
+// copy of the smart pointer, on which the methods of the class can be
+// called directly.
+type cxxgo_smartptr struct {
+	id   *cxxtypes.ClassType
+	kind string      // "shared" or "unique"
+	elt  cxxtypes.Id // the class pointed at
+	cxx  string      // C++ name of the class pointed at
+}
+
+// get_cxxgo_smartptr returns the description of the smart pointer id
+// (modulo typedefs and cv-qualifiers), or nil if id is not a smart pointer
+// to a class.
+func get_cxxgo_smartptr(id cxxtypes.Id) *cxxgo_smartptr {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return nil
+	}
+	cls, ok := resolve_typedef(t).(*cxxtypes.ClassType)
+	if !ok {
+		return nil
+	}
+	n := cls.IdScopedName()
+	kind := ""
+	switch {
+	case strings.HasPrefix(n, "std::shared_ptr<"):
+		kind = "shared"
+	case strings.HasPrefix(n, "std::unique_ptr<"):
+		kind = "unique"
+	default:
+		return nil
+	}
+	targs := template_args(n)
+	if len(targs) < 1 {
+		return nil
+	}
+	if kind == "unique" && len(targs) > 1 && !strings.HasPrefix(targs[1], "std::default_delete<") {
+		// FIXME: custom deleters
+		return nil
+	}
+	elt := cxxtypes.IdByName(targs[0])
+	if elt == nil || container_elt_kind(elt) != "handle" {
+		return nil
+	}
+	return &cxxgo_smartptr{
+		id:   cls,
+		kind: kind,
+		elt:  elt,
+		cxx:  targs[0],
+	}
+}
+
+// strip_smartptr returns the smart pointer held by id (modulo typedefs,
+// cv-qualifiers and references), whether it is passed by reference and
+// whether it is const-qualified.
+func strip_smartptr(id cxxtypes.Id) (sp *cxxgo_smartptr, ref bool, cst bool) {
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			cst = cst || (iid.Qualifiers()&cxxtypes.TQ_Const) != 0
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.TypedefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.RefType:
+			if ref {
+				return nil, false, false
+			}
+			ref = true
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.ClassType:
+			sp = get_cxxgo_smartptr(iid)
+		}
+		break
+	}
+	if sp == nil {
+		return nil, false, false
+	}
+	return sp, ref, cst
+}
+
+// elt_goname returns the Go type of the class pointed at
+func (sp *cxxgo_smartptr) elt_goname() string {
+	return gen_go_name_from_id(sp.elt)
+}
+
+// goname returns the name of the Go handle type (e.g. SharedPtrFoo)
+func (sp *cxxgo_smartptr) goname() string {
+	return strings.Title(sp.kind) + "Ptr" + sp.elt_goname()
+}
+
+// cname returns the prefix of the C helpers for the smart pointer
+func (sp *cxxgo_smartptr) cname(pkg string) string {
+	return fmt.Sprintf("_gocxx_sptr_%s_%s", pkg, get_iid_str(sp.id))
+}
+
+// go_helper returns the name of the Go function creating a handle from a
+// C++ smart pointer.
+func (sp *cxxgo_smartptr) go_helper() string {
+	return fmt.Sprintf("_gocxx_sptr_%s_to_go", get_iid_str(sp.id))
+}
+
+// cxx_arg returns the C++ statement declaring the variable n, referring to
+// the smart pointer held by the Go handle c (or to an empty one if c is
+// nil).
+func (sp *cxxgo_smartptr) cxx_arg(n, c string) string {
+	spn := sp.id.IdScopedName()
+	return fmt.Sprintf("%s %s_empty; %s& %s = %s ? *(%s*)%s : %s_empty;",
+		spn, n, spn, n, c, spn, c, n,
+	)
+}
+
+func (p *plugin) wrapSmartPtr(cid *cxxgo_id, sp *cxxgo_smartptr) error {
+	var err error
+	fmt.Printf(":: wrapping smart pointer [%s]...\n", sp.id.IdScopedName())
+
+	pkg := p.gen.Fd.Package
+	bufs := new_bufmap(
+		"cxx",
+		"cgo_head",
+		"go_impl",
+	)
+
+	spn := sp.id.IdScopedName()
+	cn := sp.cname(pkg)
+
+	// C helpers
+	fmter(bufs["cgo_head"],
+		"\n/* helpers for [%s] */\nvoid* %s_new(void *c_obj);\nvoid* %s_take(void *c_self);\nvoid %s_delete(void *c_self);\nvoid* %s_get(void *c_self);\n",
+		spn, cn, cn, cn, cn,
+	)
+	fmter(bufs["cxx"],
+		"\n// helpers for [%s]\nvoid* %s_new(void *c_obj)\n{\n  return new %s((%s*)c_obj);\n}\n",
+		spn, cn, spn, sp.cxx,
+	)
+	take := "new %s(*cxx_self)"
+	if sp.kind == "unique" {
+		take = "new %s(std::move(*cxx_self))"
+	}
+	fmter(bufs["cxx"],
+		"\n// returns a heap-allocated smart pointer taken from c_self, or NULL if it is empty\nvoid* %s_take(void *c_self)\n{\n  %s *cxx_self = (%s*)c_self;\n  if (!*cxx_self) {\n    return NULL;\n  }\n  return "+take+";\n}\n",
+		cn, spn, spn, spn,
+	)
+	fmter(bufs["cxx"],
+		"\nvoid %s_delete(void *c_self)\n{\n  delete (%s*)c_self;\n}\n",
+		cn, spn,
+	)
+	fmter(bufs["cxx"],
+		"\nvoid* %s_get(void *c_self)\n{\n  return (void*)((%s*)c_self)->get();\n}\n",
+		cn, spn,
+	)
+	if sp.kind == "shared" {
+		fmter(bufs["cgo_head"], "long %s_use_count(void *c_self);\n", cn)
+		fmter(bufs["cxx"],
+			"\nlong %s_use_count(void *c_self)\n{\n  return ((%s*)c_self)->use_count();\n}\n",
+			cn, spn,
+		)
+	}
+
+	// Go handle
+	goname := sp.goname()
+	elt := sp.elt_goname()
+	doc := "The reference held by the handle is released by Close, or when the\n// handle is garbage collected."
+	if sp.kind == "unique" {
+		doc = "The object is owned by the handle: it is deleted by Close, or when the\n// handle is garbage collected. Passing the handle by value to a C++\n// function transfers the ownership of the object, and closes the handle."
+	}
+	fmter(bufs["go_impl"],
+		"\n// %s holds a C++ %s.\n// The methods of %s are called directly on the handle.\n// %s\ntype %s struct {\n\t%s\n\tc unsafe.Pointer // the C++ smart pointer\n}\n",
+		goname, spn, elt, doc, goname, elt,
+	)
+	fmter(bufs["go_impl"],
+		"\n// %s returns a handle holding the C++ smart pointer c, or nil if c is nil\nfunc %s(c unsafe.Pointer) *%s {\n\tif c == nil {\n\t\treturn nil\n\t}\n\tp := &%s{Gocxxcptr%s(C.%s_get(c)), c}\n\t_gocxx_set_finalizer(p, (*%s).Close)\n\treturn p\n}\n",
+		sp.go_helper(), sp.go_helper(), goname,
+		goname, elt, cn,
+		goname,
+	)
+	fmter(bufs["go_impl"],
+		"\n// New%s returns a %s taking ownership of obj.\n// obj must not be deleted afterwards.\nfunc New%s(obj %s) *%s {\n\treturn %s(C.%s_new(unsafe.Pointer(%s)))\n}\n",
+		goname, goname, goname, elt, goname, sp.go_helper(), cn, go_cptr(p.gen.Fd.Package, sp.elt, "obj"),
+	)
+	fmter(bufs["go_impl"],
+		"\n// Close releases the C++ smart pointer held by p.\n// p must not be used afterwards.\nfunc (p *%s) Close() error {\n\tif p.c != nil {\n\t\tC.%s_delete(p.c)\n\t\tp.c = nil\n\t\tp.%s = nil\n\t\t_gocxx_set_finalizer(p, nil)\n\t}\n\treturn nil\n}\n",
+		goname, cn, elt,
+	)
+	if sp.kind == "shared" {
+		fmter(bufs["go_impl"],
+			"\n// UseCount returns the number of C++ shared_ptr referring to the object.\nfunc (p *%s) UseCount() int {\n\treturn int(C.%s_use_count(p.c))\n}\n",
+			goname, cn,
+		)
+	}
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping smart pointer [%s]...[ok]\n", sp.id.IdScopedName())
+	return err
+}
@@ -0,0 +1,322 @@
+package cxxgo
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// status_rule maps the status codes of a C++ type, returned by the
+// functions whose scoped name matches a pattern, to a Go error
+// (see the 'errors' argument)
+type status_rule struct {
+	typ  string // scoped name of the status type (or of a typedef to it)
+	op   string // comparison operator of the success predicate
+	val  string // Go value the status code is compared to
+	fct  string // pattern of the scoped name of the functions (optional)
+	enum string // scoped name of the enumerator val stands for, if any
+}
+
+var g_status_re = regexp.MustCompile(`^(.+?)\s*(==|!=|>=|<=|>|<)\s*([^<>=!]+?)\s*$`)
+
+// parse_status_rules parses the value of the 'errors' argument: a
+// comma-separated list (or a slice) of type<op>value[@fct] rules, e.g.
+//
+//	"StatusCode==0,int>=0@App::*,Code==Code::Ok"
+//
+// a status code is a success when the predicate (code <op> value) holds.
+// the value is an integer or an enumerator of the registry, whose value is
+// then taken from C++.
+// the rules are tried in order, the first one matching the return type (or
+// one of its typedefs) and the scoped name of a function is applied. a
+// virtual function is mapped like the functions it overrides and the ones
+// overriding it (see status_family).
+func parse_status_rules(v interface{}) ([]status_rule, error) {
+	var rules []string
+	switch v := v.(type) {
+	case string:
+		rules = strings.Split(v, ",")
+	case []string:
+		rules = v
+	default:
+		return nil, fmt.Errorf(
+			"cxxgo: invalid value for argument 'errors' [%v] (expected a comma-separated list of type<op>value[@fct])",
+			v)
+	}
+	o := []status_rule{}
+	var enums map[string]bool
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		r := status_rule{}
+		if i := strings.LastIndex(rule, "@"); i >= 0 {
+			r.fct = strings.TrimLeft(strings.TrimSpace(rule[i+1:]), ":")
+			rule = rule[:i]
+		}
+		m := g_status_re.FindStringSubmatch(rule)
+		if m == nil {
+			return nil, fmt.Errorf(
+				"cxxgo: invalid rule [%s] in argument 'errors' (expected type<op>value[@fct])",
+				rule)
+		}
+		r.typ = strings.TrimLeft(strings.TrimSpace(m[1]), ":")
+		r.op = m[2]
+		r.val = m[3]
+		if _, err := strconv.ParseInt(r.val, 0, 64); err != nil {
+			if enums == nil {
+				enums = enumerator_names()
+			}
+			n := strings.TrimLeft(r.val, ":")
+			if !enums[n] {
+				return nil, fmt.Errorf(
+					"cxxgo: invalid status code [%s] in argument 'errors' (expected an integer or an enumerator)",
+					r.val)
+			}
+			r.enum = n
+			r.val = fmt.Sprintf("_gocxx_status_%d", len(o))
+		}
+		o = append(o, r)
+	}
+	return o, nil
+}
+
+// match returns whether the rule applies to the result of fct
+func (r *status_rule) match(fct *cxxtypes.Function) bool {
+	if r.fct != "" {
+		matched, err := path.Match(r.fct, fct.IdScopedName())
+		if err == path.ErrBadPattern || !matched {
+			return false
+		}
+	}
+	if fct.Ret == "" || !is_status_type(cxxtypes.IdByName(fct.Ret)) {
+		return false
+	}
+	id := cxxtypes.IdByName(fct.Ret)
+	for id != nil {
+		if id.IdScopedName() == r.typ {
+			return true
+		}
+		switch t := id.(type) {
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(t.Type)
+		case *cxxtypes.TypedefType:
+			id = cxxtypes.IdByName(t.Type)
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// fail returns the Go condition of a failure of the status code v
+func (r *status_rule) fail(v string) string {
+	ops := map[string]string{
+		"==": "!=", "!=": "==",
+		">=": "<", "<": ">=",
+		"<=": ">", ">": "<=",
+	}
+	if r.enum != "" {
+		// the value of the enumerator is a Go variable
+		v = "int64(" + v + ")"
+	}
+	return fmt.Sprintf("%s %s %s", v, ops[r.op], r.val)
+}
+
+// is_status_type returns whether values of the type id may be status codes:
+// integers or enumerations, returned by value.
+func is_status_type(id cxxtypes.Id) bool {
+	t, ok := id.(cxxtypes.Type)
+	if !ok {
+		return false
+	}
+	switch tt := resolve_typedef(t).(type) {
+	case *cxxtypes.EnumType:
+		return true
+	case *cxxtypes.FundamentalType:
+		switch tt.TypeKind() {
+		case cxxtypes.TK_SChar, cxxtypes.TK_Short, cxxtypes.TK_Int,
+			cxxtypes.TK_Long, cxxtypes.TK_LongLong,
+			cxxtypes.TK_UChar, cxxtypes.TK_UShort, cxxtypes.TK_UInt,
+			cxxtypes.TK_ULong, cxxtypes.TK_ULongLong:
+			return gen_go_fundamental_name(tt) != ""
+		}
+	}
+	return false
+}
+
+// same_params returns whether the functions f and g have the same
+// parameter types and cv-qualifiers. (ie: whether one may override the
+// other, if they have the same name)
+func same_params(f, g *cxxtypes.Function) bool {
+	if f.NumParam() != g.NumParam() || f.IsConst() != g.IsConst() {
+		return false
+	}
+	for i, _ := range f.Params {
+		if f.Param(i).Type != g.Param(i).Type {
+			return false
+		}
+	}
+	return true
+}
+
+// overridden returns the virtual member functions of the bases of the class
+// of fct which fct overrides, directly or not.
+func overridden(fct *cxxtypes.Function) []*cxxtypes.Function {
+	if !fct.IsMethod() || fct.IsStatic() || fct.IsConstructor() || fct.IsDestructor() {
+		return nil
+	}
+	o := []*cxxtypes.Function{}
+	var walk func(id cxxtypes.Id)
+	walk = func(id cxxtypes.Id) {
+		for _, base := range class_base_list(id) {
+			bid := cxxtypes.IdByName(base.TypeBase)
+			if mbr := class_member_named(bid, fct.IdName()); mbr != nil && mbr.IsFunctionMember() {
+				if ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet); ok {
+					for _, f := range ovfct.Fcts {
+						if f.IsVirtual() && same_params(f, fct) && !fct_is_in_slice(f, o) {
+							o = append(o, f)
+						}
+					}
+				}
+			}
+			walk(bid)
+		}
+	}
+	walk(cxxtypes.IdByName(fct.Scope))
+	return o
+}
+
+// fct_is_in_slice returns whether the function f is in the slice fcts
+func fct_is_in_slice(f *cxxtypes.Function, fcts []*cxxtypes.Function) bool {
+	for _, ff := range fcts {
+		if ff == f {
+			return true
+		}
+	}
+	return false
+}
+
+// status_family returns the functions whose results are mapped like the
+// result of fct: fct itself and, for a virtual function, the declarations
+// it overrides and the other overriders of these declarations. Their Go
+// methods must have the same Go results, for the Go types of the derived
+// classes to implement the Go interfaces of their bases.
+func status_family(fct *cxxtypes.Function) []*cxxtypes.Function {
+	family := []*cxxtypes.Function{fct}
+	if !fct.IsVirtual() {
+		return family
+	}
+	roots := []*cxxtypes.Function{}
+	for _, f := range append(overridden(fct), fct) {
+		family = append(family, f)
+		if len(overridden(f)) == 0 {
+			roots = append(roots, f)
+		}
+	}
+	for _, n := range cxxtypes.IdNames() {
+		ovfct, ok := cxxtypes.IdByName(n).(*cxxtypes.OverloadFunctionSet)
+		if !ok || ovfct.Fcts[0].IdName() != fct.IdName() {
+			continue
+		}
+		for _, f := range ovfct.Fcts {
+			if fct_is_in_slice(f, family) || !f.IsVirtual() {
+				continue
+			}
+			for _, g := range overridden(f) {
+				if fct_is_in_slice(g, roots) {
+					family = append(family, f)
+					break
+				}
+			}
+		}
+	}
+	return family
+}
+
+// status_rule returns the rule mapping the result of fct to a Go error, if
+// any: the first one matching fct or a function of its family (see
+// status_family).
+func (p *plugin) status_rule(fct *cxxtypes.Function) *status_rule {
+	family := status_family(fct)
+	for i, _ := range p.status {
+		for _, f := range family {
+			if p.status[i].match(f) {
+				return &p.status[i]
+			}
+		}
+	}
+	return nil
+}
+
+// wrapStatusValues gives access to the values of the enumerators the status
+// codes are compared to, through Go variables initialized from C++.
+func (p *plugin) wrapStatusValues() error {
+	pkg := p.gen.Fd.Package
+	bufs := new_bufmap("cxx", "hdr", "go")
+	for i, _ := range p.status {
+		r := &p.status[i]
+		if r.enum == "" {
+			continue
+		}
+		cn := fmt.Sprintf("_gocxx_status_%s_%d", pkg, i)
+		fmter(bufs["hdr"],
+			"\n/* value of [%s] */\nlong long %s(void);\n",
+			r.enum, cn)
+		fmter(bufs["cxx"],
+			"\n// value of [%s]\nlong long %s(void)\n{\n  return (long long)(::%s);\n}\n",
+			r.enum, cn, r.enum)
+		fmter(bufs["go"],
+			"\n// %s is the value of [%s]\nvar %s = int64(C.%s())\n",
+			r.val, r.enum, r.val, cn)
+	}
+	for _, k := range []string{"cxx", "hdr", "go"} {
+		_, err := bufs[k].WriteTo(p.gen.Fd.Files[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// with_status replaces the return statement of the status code in the Go
+// code lines by the one of a Go error, nil on success.
+func (f *cxxgo_function) with_status(lines []string) []string {
+	results := append(f.go_outs(), "err")
+	o := make([]string, 0, len(lines)+1)
+	for _, line := range lines {
+		if line != "\treturn go_ret\n" {
+			o = append(o, line)
+			continue
+		}
+		o = append(o,
+			"\tvar err error\n",
+			fmt.Sprintf("\tif %s {\n\t\terr = &StatusError{Code: int64(go_ret), Fct: %s}\n\t}\n",
+				f.status.fail("go_ret"), strconv.Quote(f.f.IdScopedName())),
+			"\treturn "+strings.Join(results, ", ")+"\n",
+		)
+	}
+	return o
+}
+
+// _go_status_error is the Go error type of the status codes reporting a
+// failure, generated when the 'errors' argument is set.
+var _go_status_error string = `
+// StatusError is returned by the functions whose C++ status code reports a
+// failure.
+type StatusError struct {
+	Code int64  // the status code returned by the C++ function
+	Fct  string // the C++ function
+}
+
+func (e *StatusError) Error() string {
+	return e.Fct + ": status code " + strconv.FormatInt(e.Code, 10)
+}
+`
+
+// EOF
@@ -0,0 +1,174 @@
+package cxxgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// cxxgo_stream describes a (reference or pointer to a) std::ostream or
+// std::istream parameter. It is presented to Go as an io.Writer or an
+// io.Reader: the C++ wrapper builds a stream on a std::streambuf adapter
+// calling the Go writer or reader back (see _gocxx_gowriter_buf and
+// _gocxx_goreader_buf).
+type cxxgo_stream struct {
+	kind string // "ostream" or "istream"
+	ptr  bool   // whether the stream is passed by pointer
+}
+
+// strip_stream returns the description of the stream a (reference or
+// pointer to a) std::ostream or std::istream type refers to, or nil if id
+// is not such a stream.
+func strip_stream(id cxxtypes.Id) *cxxgo_stream {
+	st := &cxxgo_stream{}
+	indirect := false
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.TypedefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.PtrType:
+			if indirect {
+				return nil
+			}
+			indirect = true
+			st.ptr = true
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.RefType:
+			if indirect {
+				return nil
+			}
+			indirect = true
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.ClassType:
+			switch n := iid.IdScopedName(); {
+			case is_std_stream(n, "ostream"):
+				st.kind = "ostream"
+			case is_std_stream(n, "istream"):
+				st.kind = "istream"
+			default:
+				return nil
+			}
+			return st
+		}
+		return nil
+	}
+	panic("unreachable")
+}
+
+// is_std_stream returns whether n names the std::<kind> class (or one of
+// its typedefs), kind being "ostream" or "istream"
+func is_std_stream(n, kind string) bool {
+	if n == "std::"+kind {
+		return true
+	}
+	pfx := "std::basic_" + kind + "<char"
+	return strings.HasPrefix(n, pfx) &&
+		len(n) > len(pfx) && strings.ContainsRune(",> ", rune(n[len(pfx)]))
+}
+
+// goname returns the Go type of the stream
+func (st *cxxgo_stream) goname() string {
+	if st.kind == "ostream" {
+		return "io.Writer"
+	}
+	return "io.Reader"
+}
+
+// go_helper returns the name of the Go function registering a Go writer
+// or reader to be called back from C++.
+func (st *cxxgo_stream) go_helper() string {
+	if st.kind == "ostream" {
+		return "_gocxx_writer_from_go"
+	}
+	return "_gocxx_reader_from_go"
+}
+
+// cxx_buf returns the C++ std::streambuf adapter of the stream
+func (st *cxxgo_stream) cxx_buf() string {
+	if st.kind == "ostream" {
+		return "_gocxx_gowriter_buf"
+	}
+	return "_gocxx_goreader_buf"
+}
+
+// stream_operator_class returns the scoped name of the class printed by
+// fct, if fct is a free "std::ostream& operator<<(std::ostream&, const T&)"
+func stream_operator_class(fct *cxxtypes.Function) string {
+	if fct.IsMethod() || fct.IdName() != "operator<<" || len(fct.Params) != 2 {
+		return ""
+	}
+	if st := strip_stream(cxxtypes.IdByName(fct.Params[0].Type)); st == nil || st.kind != "ostream" || st.ptr {
+		return ""
+	}
+	id := cxxtypes.IdByName(fct.Params[1].Type)
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.RefType:
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.ClassType:
+			return iid.IdScopedName()
+		}
+		return ""
+	}
+	panic("unreachable")
+}
+
+// wrapStringer generates the String method of the Go type of a class
+// printable to a std::ostream.
+func (p *plugin) wrapStringer(cid *cxxgo_id, id *cxxtypes.ClassType, cls_bufs bufmap_t) error {
+	var err error
+	for _, mbr := range id.Members {
+		if mbr.IsFunctionMember() && strings.Title(mbr.IdName()) == "String" {
+			fmt.Printf(":: discarding String for [%s] (already defined)\n", id.IdScopedName())
+			return err
+		}
+	}
+
+	bufs := new_bufmap(
+		"cxx",
+		"cgo_head",
+	)
+	cls := id.IdScopedName()
+	cn := fmt.Sprintf("_gocxx_str_%s_%s", p.gen.Fd.Package, get_iid_str(id))
+
+	fmter(bufs["cgo_head"],
+		"\n/* prints [%s] */\nvoid %s(void *c_self, void *c_ret);\n",
+		cls, cn,
+	)
+	fmter(bufs["cxx"],
+		"\n// prints [%s] through its operator<<\nvoid %s(void *c_self, void *c_ret)\n{\n  std::ostringstream cxx_os;\n  cxx_os << *(%s*)c_self;\n  std::string cxx_str = cxx_os.str();\n  _gocxx_strview_set((_gocxx_strview*)c_ret, cxx_str.data(), cxx_str.size());\n}\n",
+		cls, cn, cls,
+	)
+
+	fmter(cls_bufs["go_iface"],
+		"\t// String returns the output of the C++ operator<< of the object.\n\tString() string\n",
+	)
+	fmter(cls_bufs["go_impl"],
+		"\nfunc (p Gocxxcptr%s) String() string {\n\tvar c_ret C._gocxx_strview\n\tC.%s(unsafe.Pointer(p), unsafe.Pointer(&c_ret))\n\treturn _gocxx_strview_to_go(c_ret)\n}\n",
+		cid.goname, cn,
+	)
+
+	_, err = bufs["cxx"].WriteTo(p.gen.Fd.Files["cxx"])
+	if err != nil {
+		return err
+	}
+
+	_, err = bufs["cgo_head"].WriteTo(p.gen.Fd.Files["hdr"])
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+// EOF
@@ -0,0 +1,192 @@
+package cxxgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// cxxgo_string describes a C++ string-like type.
+// All of them (std::string, std::string_view, TString and C strings) are
+// exchanged with Go the same way: through a _gocxx_strview holding a C copy
+// of the characters, which is converted from and to a Go string.
+type cxxgo_string struct {
+	kind string // "std::string", "std::string_view", "TString" or "char*"
+	cxx  string // C++ name of the string type (w/o cv-qualifiers)
+	ptr  bool   // whether the string is held by pointer
+	ref  bool   // whether the string is held by reference
+	cst  bool   // whether the string is const-qualified
+
+	gotype string // Go named type of the string value, if a typedef (see g_typedefs)
+}
+
+// get_cxxgo_string returns the description of the string-like type id
+// (modulo typedefs, cv-qualifiers, pointers and references), or nil if id
+// is not a string.
+func get_cxxgo_string(id cxxtypes.Id) *cxxgo_string {
+	s := &cxxgo_string{}
+	for {
+		switch iid := id.(type) {
+		case *cxxtypes.CvrQualType:
+			s.cst = s.cst || (iid.Qualifiers()&cxxtypes.TQ_Const) != 0
+			id = cxxtypes.IdByName(iid.Type)
+			continue
+		case *cxxtypes.TypedefType:
+			if named := g_typedefs[iid.IdScopedName()]; named && s.gotype == "" {
+				s.gotype = typedef_goname(iid)
+			}
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.PtrType:
+			if s.ptr || s.ref {
+				return nil
+			}
+			s.ptr = true
+			s.cst = false
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.RefType:
+			if s.ptr || s.ref {
+				return nil
+			}
+			s.ref = true
+			s.cst = false
+			id = iid.UnderlyingType().(cxxtypes.Id)
+			continue
+		case *cxxtypes.FundamentalType:
+			switch iid.TypeKind() {
+			case cxxtypes.TK_Char_S, cxxtypes.TK_Char_U:
+				// only C strings. (a reference to a char is a char)
+				if !s.ptr {
+					return nil
+				}
+				s.kind = "char*"
+				s.cxx = "char*"
+				if s.cst {
+					s.cxx = "const char*"
+				}
+				// the pointer is the string
+				s.ptr = false
+				return s
+			}
+		case *cxxtypes.ClassType:
+			n := iid.IdScopedName()
+			switch {
+			case is_std_string(n):
+				s.kind = "std::string"
+			case is_std_string_view(n):
+				s.kind = "std::string_view"
+			case n == "TString":
+				s.kind = "TString"
+			default:
+				return nil
+			}
+			s.cxx = n
+			return s
+		}
+		return nil
+	}
+	panic("unreachable")
+}
+
+// is_std_string_view returns whether n names the std::string_view class
+// (or one of its typedefs)
+func is_std_string_view(n string) bool {
+	if n == "std::string_view" {
+		return true
+	}
+	const pfx = "std::basic_string_view<char"
+	return strings.HasPrefix(n, pfx) &&
+		len(n) > len(pfx) && strings.ContainsRune(",> ", rune(n[len(pfx)]))
+}
+
+// indirect returns whether the string is an output parameter (a non-const
+// pointer or reference): a Go *string is then updated with the value of the
+// C++ string after the call. C strings are always input parameters.
+func (s *cxxgo_string) indirect() bool {
+	return s.kind != "char*" && !s.cst && (s.ptr || s.ref)
+}
+
+// goname returns the Go type of the string
+func (s *cxxgo_string) goname() string {
+	n := "string"
+	if s.gotype != "" {
+		n = s.gotype
+	}
+	if s.indirect() {
+		return "*" + n
+	}
+	return n
+}
+
+// go_from_go returns the Go expression converting the Go string v into a
+// _gocxx_strview
+func (s *cxxgo_string) go_from_go(v string) string {
+	if s.gotype != "" {
+		v = "string(" + v + ")"
+	}
+	return "_gocxx_strview_from_go(" + v + ")"
+}
+
+// go_to_go returns the Go expression converting the _gocxx_strview v into
+// a Go string
+func (s *cxxgo_string) go_to_go(v string) string {
+	v = "_gocxx_strview_to_go(" + v + ")"
+	if s.gotype != "" {
+		v = s.gotype + "(" + v + ")"
+	}
+	return v
+}
+
+// value returns the description of the string value (w/o pointer or
+// reference) held by s.
+func (s *cxxgo_string) value() *cxxgo_string {
+	return &cxxgo_string{kind: s.kind, cxx: s.cxx, cst: s.cst, gotype: s.gotype}
+}
+
+// cxx_init returns the C++ expression building the string value from the
+// _gocxx_strview expression v, or the default value of the string if v is
+// empty.
+func (s *cxxgo_string) cxx_init(v string) string {
+	switch {
+	case s.kind == "char*" && v == "":
+		return "NULL"
+	case s.kind == "char*":
+		return fmt.Sprintf("(%s)%s.p", s.cxx, v)
+	case v == "":
+		return s.cxx + "()"
+	}
+	return fmt.Sprintf("%s(%s.p, %s.n)", s.cxx, v, v)
+}
+
+// cxx_data returns the C++ expressions of the characters and number of
+// characters of the string expression n. (n is a pointer if s.ptr)
+func (s *cxxgo_string) cxx_data(n string) (string, string) {
+	data, size := "data()", "size()"
+	if s.kind == "TString" {
+		data, size = "Data()", "Length()"
+	}
+	switch {
+	case s.kind == "char*":
+		return n, fmt.Sprintf("(%s ? strlen(%s) : 0)", n, n)
+	case s.ptr:
+		return fmt.Sprintf("(%s ? %s->%s : NULL)", n, n, data),
+			fmt.Sprintf("(%s ? %s->%s : 0)", n, n, size)
+	}
+	return n + "." + data, n + "." + size
+}
+
+// cxx_ret_decl returns the C++ declaration of the variable holding the
+// value returned by the wrapped function. the declaration is completed by
+// the function call.
+func (s *cxxgo_string) cxx_ret_decl() string {
+	switch {
+	case s.kind == "char*":
+		return "const char* cxx_ret = "
+	case s.ptr:
+		return fmt.Sprintf("const %s* cxx_ret = ", s.cxx)
+	}
+	// binding a temporary to a const reference extends its lifetime
+	return fmt.Sprintf("const %s& cxx_ret = ", s.cxx)
+}
@@ -0,0 +1,517 @@
+package cxxgo
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// tmpl_sel is an entry of the 'templates' argument: a pattern of template
+// instances to select (e.g. "NS::tmpl<int>", "NS::tmpl<*>"), with an
+// optional Go name. A pattern without template arguments (e.g. "NS::tmpl")
+// selects all the instances of a template, and names its Go generic facade.
+type tmpl_sel struct {
+	pat    string
+	goname string
+}
+
+// g_tmpl_sels holds the template instances to select and name
+var g_tmpl_sels []tmpl_sel
+
+// parse_tmpl_sels parses the value of the 'templates' argument: a
+// comma-separated list of "pattern[=GoName]" entries, or a map of pattern
+// to Go name.
+func parse_tmpl_sels(v interface{}) ([]tmpl_sel, error) {
+	entries := []string{}
+	switch v := v.(type) {
+	case string:
+		entries = append(entries, split_args(v)...)
+	case []string:
+		entries = append(entries, v...)
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for k, _ := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			entries = append(entries, k+"="+v[k])
+		}
+	default:
+		return nil, fmt.Errorf(
+			"cxxgo: invalid value for argument 'templates' [%v] (expected a comma-separated list of pattern[=GoName])",
+			v)
+	}
+	sels := make([]tmpl_sel, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		sel := tmpl_sel{pat: e}
+		if i := strings.LastIndex(e, "="); i >= 0 && !strings.ContainsAny(e[i+1:], "<>:") {
+			sel.pat = strings.TrimSpace(e[:i])
+			sel.goname = strings.TrimSpace(e[i+1:])
+			if !token.IsIdentifier(sel.goname) {
+				return nil, fmt.Errorf(
+					"cxxgo: invalid Go name [%s] for template instances [%s]",
+					sel.goname, sel.pat)
+			}
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+// is_ident_char returns whether c may be part of a C++ identifier
+func is_ident_char(c byte) bool {
+	return c == '_' ||
+		('0' <= c && c <= '9') ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z')
+}
+
+// norm_tmpl_name removes the blanks of n which are not needed to separate
+// two words, so "std::map<int, std::vector<int> >" and
+// "std::map<int,std::vector<int>>" compare equal.
+func norm_tmpl_name(n string) string {
+	n = strings.TrimSpace(n)
+	o := make([]byte, 0, len(n))
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c == ' ' || c == '\t' {
+			if len(o) > 0 && is_ident_char(o[len(o)-1]) &&
+				i+1 < len(n) && is_ident_char(n[i+1]) {
+				o = append(o, ' ')
+			}
+			continue
+		}
+		o = append(o, c)
+	}
+	return string(o)
+}
+
+// template_instance returns the template description of a class or
+// function, or nil if id is not a template instance.
+func template_instance(id cxxtypes.Id) *cxxtypes.TemplateInstance {
+	var ti *cxxtypes.TemplateInstance
+	switch id := id.(type) {
+	case *cxxtypes.ClassType:
+		ti = &id.TemplateInstance
+	case *cxxtypes.StructType:
+		ti = &id.TemplateInstance
+	case *cxxtypes.Function:
+		ti = &id.TemplateInstance
+	case *cxxtypes.OverloadFunctionSet:
+		if len(id.Fcts) > 0 {
+			ti = &id.Function(0).TemplateInstance
+		}
+	}
+	if ti == nil || !ti.IsTemplateInstance() {
+		return nil
+	}
+	return ti
+}
+
+// match_template returns whether the template instance id matches the
+// pattern pat. Blanks are not significant and trailing (defaulted)
+// template arguments may be omitted from the pattern, so "std::vector<Foo>"
+// matches "std::vector<Foo, std::allocator<Foo> >".
+// A pattern without template arguments matches all the instances of the
+// templates it matches.
+func match_template(pat string, id cxxtypes.Id) bool {
+	ti := template_instance(id)
+	if ti == nil {
+		return false
+	}
+	match := func(pat, n string) bool {
+		ok, err := path.Match(norm_tmpl_name(pat), norm_tmpl_name(n))
+		return err == nil && ok
+	}
+	ptmpl, pargs := cxxtypes.SplitTemplateName(pat)
+	if ptmpl == "" {
+		return match(pat, ti.TemplateName())
+	}
+	if !match(ptmpl, ti.TemplateName()) || len(pargs) > ti.NumTemplateArg() {
+		return false
+	}
+	for i, parg := range pargs {
+		if !match(parg, ti.TemplateArg(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// tmpl_goname returns the Go name chosen for the template instance id
+// (see the 'templates' argument), or the empty string.
+func tmpl_goname(id cxxtypes.Id) string {
+	for _, sel := range g_tmpl_sels {
+		if sel.goname == "" {
+			continue
+		}
+		if tmpl, _ := cxxtypes.SplitTemplateName(sel.pat); tmpl == "" {
+			// names a generic facade
+			continue
+		}
+		if match_template(sel.pat, id) {
+			return sel.goname
+		}
+	}
+	return ""
+}
+
+// tmpl_facade_goname returns the Go name of the generic facade of the
+// template tmpl: the one chosen through the 'templates' argument or, by
+// default, the one the template would have as a class.
+func tmpl_facade_goname(tmpl string) string {
+	for _, sel := range g_tmpl_sels {
+		if sel.goname == "" {
+			continue
+		}
+		if t, _ := cxxtypes.SplitTemplateName(sel.pat); t != "" {
+			continue
+		}
+		ok, err := path.Match(norm_tmpl_name(sel.pat), norm_tmpl_name(tmpl))
+		if err == nil && ok {
+			return sel.goname
+		}
+	}
+	return strings.Title(g_cxxgo_trans.Replace(tmpl))
+}
+
+// tmpl_default_goname returns the default Go name of the template instance
+// id: the Go name tmpl of its template, followed by the Go names of its
+// template arguments (e.g. Cell<int> -> CellInt32, like VectorFoo).
+// It returns the empty string if a template argument has no Go name.
+func tmpl_default_goname(id cxxtypes.Id, tmpl string) string {
+	ti := template_instance(id)
+	if ti == nil {
+		return ""
+	}
+	words := []string{tmpl}
+	for i := 0; i < ti.NumTemplateArg(); i++ {
+		arg := ti.TemplateArg(i)
+		n := arg
+		if aid := cxxtypes.IdByName(arg); aid != nil {
+			n = gen_go_name_from_id(aid)
+			if n == "" || strings.HasPrefix(n, "_go_unknown_") {
+				return ""
+			}
+		} else if strings.IndexFunc(arg, is_not_alnum) >= 0 {
+			// e.g. a negative or a cast value
+			return ""
+		}
+		for _, w := range strings.FieldsFunc(n, is_not_alnum) {
+			words = append(words, strings.ToUpper(w[:1])+w[1:])
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// cxxgo_tmpl_inst describes the Go API of an instance of a class template,
+// with the Go type of its template argument replaced by the type parameter
+// T: instances sharing the same shape can be handled through a Go generic
+// facade.
+type cxxgo_tmpl_inst struct {
+	id     *cxxtypes.ClassType
+	goname string            // the Go name of the instance
+	goarg  string            // the Go type of the template argument
+	mths   []string          // the Go prototypes of the methods
+	ctors  []cxxgo_tmpl_ctor // the Go constructors
+}
+
+// cxxgo_tmpl_ctor describes a Go constructor of a class template instance
+type cxxgo_tmpl_ctor struct {
+	goname string      // e.g. NewTmplInt
+	suffix string      // the Go name, minus "New" and the instance name
+	params [][2]string // names and Go types of the parameters
+}
+
+// subst_goarg replaces the Go type goarg with the type parameter T in s
+func subst_goarg(s, goarg string) string {
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(goarg) + `\b`)
+	return re.ReplaceAllString(s, "T")
+}
+
+// split_go_prototype splits a Go function prototype, as generated by
+// go_prototype, into its name and its parameters.
+func split_go_prototype(proto string) (string, [][2]string) {
+	beg := strings.Index(proto, "(")
+	if beg < 0 {
+		return proto, nil
+	}
+	end := -1
+	lvl := 0
+loop:
+	for i := beg; i < len(proto); i++ {
+		switch proto[i] {
+		case '(':
+			lvl += 1
+		case ')':
+			lvl -= 1
+			if lvl == 0 {
+				end = i
+				break loop
+			}
+		}
+	}
+	if end < 0 {
+		return proto[:beg], nil
+	}
+	params := [][2]string{}
+	for _, arg := range split_args(proto[beg+1 : end]) {
+		if arg == "" {
+			continue
+		}
+		i := strings.Index(arg, " ")
+		if i < 0 {
+			return proto[:beg], nil
+		}
+		params = append(params, [2]string{arg[:i], strings.TrimSpace(arg[i+1:])})
+	}
+	return proto[:beg], params
+}
+
+// new_cxxgo_tmpl_inst returns the Go API of the class template instance
+// id, or nil if its template argument has no Go type a type parameter can
+// stand for.
+func (p *plugin) new_cxxgo_tmpl_inst(id *cxxtypes.ClassType) *cxxgo_tmpl_inst {
+	if id.NumTemplateArg() != 1 {
+		return nil
+	}
+	aid := cxxtypes.IdByName(id.TemplateArg(0))
+	if aid == nil {
+		return nil
+	}
+	goarg := gen_go_name_from_id(aid)
+	if !token.IsIdentifier(goarg) || strings.HasPrefix(goarg, "_go_unknown_") {
+		return nil
+	}
+	inst := &cxxgo_tmpl_inst{
+		id:     id,
+		goname: get_cxxgo_id(p.gen.Fd.Package, id).goname,
+		goarg:  goarg,
+	}
+
+	// same members, in the same order, as wrapClass
+	names := []string{}
+	for _, mbr := range id.Members {
+		if !p.mbr_filter(&mbr) || !mbr.IsFunctionMember() {
+			continue
+		}
+		if str_is_in_slice(mbr.Name, names) {
+			continue
+		}
+		names = append(names, mbr.Name)
+		ovfct, ok := cxxtypes.IdByName(mbr.Name).(*cxxtypes.OverloadFunctionSet)
+		if !ok {
+			continue
+		}
+		for _, cgo_ovfct := range p.new_cxxgo_ovfcts(ovfct) {
+			fct := cgo_ovfct.fcts[0].f
+			if fct.IsDestructor() || fct.IsCopyConstructor() {
+				continue
+			}
+			protos := []string{}
+			if cgo_ovfct.needs_dispatch() && p.ovl_typed {
+				for i, _ := range cgo_ovfct.fcts {
+					protos = append(protos, cgo_ovfct.fcts[i].go_prototype())
+				}
+			}
+			if !cgo_ovfct.needs_dispatch() || p.ovl_dispatch {
+				protos = append(protos, cgo_ovfct.go_prototype())
+			}
+			if !fct.IsConstructor() {
+				for _, proto := range protos {
+					inst.mths = append(inst.mths, subst_goarg(proto, goarg))
+				}
+				continue
+			}
+			for _, proto := range protos {
+				n, params := split_go_prototype(proto)
+				pfx := "New" + inst.goname
+				if params == nil || !strings.HasPrefix(n, pfx) {
+					return nil
+				}
+				for _, param := range params {
+					if strings.HasPrefix(param[1], "...") &&
+						subst_goarg(param[1], goarg) != param[1] {
+						// FIXME: variadic parameters of type T
+						return nil
+					}
+				}
+				inst.ctors = append(inst.ctors, cxxgo_tmpl_ctor{
+					goname: n,
+					suffix: n[len(pfx):],
+					params: params,
+				})
+			}
+		}
+	}
+	return inst
+}
+
+// same_shape returns whether the Go APIs of the instances a and b only
+// differ by the Go type of their template argument.
+func (a *cxxgo_tmpl_inst) same_shape(b *cxxgo_tmpl_inst) bool {
+	if strings.Join(a.mths, "\n") != strings.Join(b.mths, "\n") {
+		return false
+	}
+	if len(a.ctors) != len(b.ctors) {
+		return false
+	}
+	for i, actor := range a.ctors {
+		bctor := b.ctors[i]
+		if actor.suffix != bctor.suffix || len(actor.params) != len(bctor.params) {
+			return false
+		}
+		for j, ap := range actor.params {
+			bp := bctor.params[j]
+			if ap[0] != bp[0] ||
+				subst_goarg(ap[1], a.goarg) != subst_goarg(bp[1], b.goarg) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// wrapGenerics generates the Go generic facades of the class templates
+// whose selected instances share the same shape.
+func (p *plugin) wrapGenerics() error {
+	tmpls := []string{}
+	insts := map[string][]*cxxtypes.ClassType{}
+	for _, n := range p.ids {
+		id, ok := cxxtypes.IdByName(n).(*cxxtypes.ClassType)
+		if !ok || !id.IsTemplateInstance() {
+			continue
+		}
+		if get_cxxgo_container(id) != nil ||
+			get_cxxgo_smartptr(id) != nil ||
+			get_cxxgo_callback(id) != nil {
+			// not wrapped as classes
+			continue
+		}
+		tmpl := id.TemplateName()
+		if _, dup := insts[tmpl]; !dup {
+			tmpls = append(tmpls, tmpl)
+		}
+		insts[tmpl] = append(insts[tmpl], id)
+	}
+	for _, tmpl := range tmpls {
+		if len(insts[tmpl]) < 2 {
+			continue
+		}
+		err := p.wrapGeneric(tmpl, insts[tmpl])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrapGeneric generates the Go generic facade of the class template tmpl
+// (e.g. Tmpl[T]), dispatching to the wrappers of its instances ids.
+func (p *plugin) wrapGeneric(tmpl string, ids []*cxxtypes.ClassType) error {
+	var err error
+	pkg := p.gen.Fd.Package
+	goname := tmpl_facade_goname(tmpl)
+
+	insts := make([]*cxxgo_tmpl_inst, 0, len(ids))
+	goargs := map[string]bool{}
+	for _, id := range ids {
+		inst := p.new_cxxgo_tmpl_inst(id)
+		if inst == nil {
+			fmt.Printf(":: discarding generic facade for [%s] ([%s] has no Go type parameter)\n",
+				tmpl, id.IdScopedName())
+			return err
+		}
+		if goargs[inst.goarg] {
+			fmt.Printf(":: discarding generic facade for [%s] (more than one instance for [%s])\n",
+				tmpl, inst.goarg)
+			return err
+		}
+		goargs[inst.goarg] = true
+		if len(insts) > 0 && !insts[0].same_shape(inst) {
+			fmt.Printf(":: discarding generic facade for [%s] ([%s] and [%s] differ)\n",
+				tmpl, insts[0].id.IdScopedName(), id.IdScopedName())
+			return err
+		}
+		insts = append(insts, inst)
+	}
+	for _, n := range p.ids {
+		if get_cxxgo_id(pkg, cxxtypes.IdByName(n)).goname == goname {
+			fmt.Printf(":: discarding generic facade for [%s] (Go name [%s] already in use)\n",
+				tmpl, goname)
+			return err
+		}
+	}
+
+	fmt.Printf(":: wrapping generic facade [%s]...\n", tmpl)
+	bufs := new_bufmap("go_impl")
+
+	doc := make([]string, 0, len(insts))
+	types := make([]string, 0, len(insts))
+	for _, inst := range insts {
+		doc = append(doc, fmt.Sprintf("//  - %s (T = %s)\n", inst.goname, inst.goarg))
+		types = append(types, inst.goarg)
+	}
+	fmter(bufs["go_impl"],
+		"\n// %s is the Go generic facade of the C++ class template %s.\n// It is implemented by:\n%stype %s[T any] interface {\n\tGocxxcptr() uintptr\n",
+		goname, tmpl, strings.Join(doc, ""), goname,
+	)
+	for _, mth := range insts[0].mths {
+		fmter(bufs["go_impl"], "\t%s\n", mth)
+	}
+	fmter(bufs["go_impl"], "}\n")
+
+	for i, ctor := range insts[0].ctors {
+		params := make([]string, 0, len(ctor.params))
+		for _, param := range ctor.params {
+			params = append(params, param[0]+" "+subst_goarg(param[1], insts[0].goarg))
+		}
+		fmter(bufs["go_impl"],
+			"\n// New%s%s creates a new %s<T>, for T one of %s.\n// It panics for any other T.\nfunc New%s%s[T any](%s) %s[T] {\n\tvar o interface{}\n\tswitch any((*T)(nil)).(type) {\n",
+			goname, ctor.suffix, tmpl, strings.Join(types, ", "),
+			goname, ctor.suffix, strings.Join(params, ", "), goname,
+		)
+		for _, inst := range insts {
+			args := make([]string, 0, len(ctor.params))
+			for _, param := range inst.ctors[i].params {
+				switch {
+				case strings.HasPrefix(param[1], "..."):
+					args = append(args, param[0]+"...")
+				case subst_goarg(param[1], inst.goarg) != param[1]:
+					args = append(args, fmt.Sprintf("any(%s).(%s)", param[0], param[1]))
+				default:
+					args = append(args, param[0])
+				}
+			}
+			fmter(bufs["go_impl"],
+				"\tcase *%s:\n\t\to = %s(%s)\n",
+				inst.goarg, inst.ctors[i].goname, strings.Join(args, ", "),
+			)
+		}
+		fmter(bufs["go_impl"],
+			"\tdefault:\n\t\tpanic(\"%s: no instance of %s<T> for this T\")\n\t}\n\treturn o.(%s[T])\n}\n",
+			pkg, tmpl, goname,
+		)
+	}
+
+	// commit buffers
+	_, err = bufs["go_impl"].WriteTo(p.gen.Fd.Files["go"])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(":: wrapping generic facade [%s]...[ok]\n", tmpl)
+	return err
+}
+
+// EOF
@@ -0,0 +1,89 @@
+package cxxgo
+
+import (
+	"strings"
+
+	"github.com/sbinet/go-cxxdict/pkg/cxxtypes"
+)
+
+// g_typedefs holds the typedefs wrapped as Go types: true for a Go named
+// type (e.g. type Ssiz_t int32), false for a Go alias (e.g. type Foo_t = Foo).
+// The other typedefs are replaced by the Go type they resolve to.
+var g_typedefs map[string]bool
+
+// is_go_typedef returns whether id is a typedef which may be wrapped as a Go
+// type. (the typedefs of the standard library and the ones already mapped to
+// a Go type, such as int32_t, are not.)
+func is_go_typedef(id cxxtypes.Id) bool {
+	if _, ok := id.(*cxxtypes.TypedefType); !ok {
+		return false
+	}
+	n := id.IdScopedName()
+	if strings.HasPrefix(n, "std::") || strings.HasPrefix(n, "__") ||
+		strings.HasPrefix(id.IdName(), "_") || is_anon(n) {
+		return false
+	}
+	_, builtin := _cxx2go_builtins[n]
+	return !builtin
+}
+
+// is_named_typedef returns whether the typedef id can be wrapped as a Go
+// named type: values of such types are converted explicitly from and to
+// the Go type of their underlying type. (fundamental types, enums and
+// strings)
+func is_named_typedef(id *cxxtypes.TypedefType) bool {
+	if s := get_cxxgo_string(id); s != nil {
+		return !s.ptr && !s.ref
+	}
+	switch resolve_typedef(id).(type) {
+	case *cxxtypes.FundamentalType, *cxxtypes.EnumType:
+		return true
+	}
+	return false
+}
+
+// is_bool returns whether id is bool, or a typedef resolving to bool
+func is_bool(id cxxtypes.Id) bool {
+	t, ok := id.(cxxtypes.Type)
+	return ok && resolve_typedef(t).TypeName() == "bool"
+}
+
+// typedef_goname returns the Go name of the Go type wrapping the typedef id
+func typedef_goname(id *cxxtypes.TypedefType) string {
+	if o := nested_goname(id); o != "" {
+		return o
+	}
+	n := g_cxxgo_trans.Replace(strip_root_ns(id.IdScopedName()))
+	return strings.Title(n)
+}
+
+// select_typedefs decides which of the selected typedefs are wrapped as Go
+// named types or as Go aliases.
+func (p *plugin) select_typedefs() {
+	for _, n := range p.ids {
+		id := cxxtypes.IdByName(n)
+		if !is_go_typedef(id) {
+			continue
+		}
+		td := id.(*cxxtypes.TypedefType)
+		g_typedefs[n] = !p.typedef_alias && is_named_typedef(td)
+	}
+
+	// the Go names of the selected ids were cached before the typedefs were
+	// known: compute them again. (e.g. Math::Foo_t const&)
+	names := []string{}
+	for _, n := range p.ids {
+		if _, builtin := _cxx2go_builtins[n]; builtin {
+			continue
+		}
+		if _, ok := _cxx2go_typemap[n]; ok {
+			delete(_cxx2go_typemap, n)
+			names = append(names, n)
+		}
+	}
+	for _, n := range names {
+		_cxx2go_typemap[n] = gen_go_name_from_id(cxxtypes.IdByName(n))
+	}
+}
+
+// EOF
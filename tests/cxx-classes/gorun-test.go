@@ -57,6 +57,17 @@ func main() {
 
 	fmt.Printf("call d1 methods via mylib.Base...[done]\n")
 
+	fmt.Printf("\n/// test downcasts from mylib.Base\n")
+	if d, ok := mylib.AsD1(b); ok {
+		fmt.Printf("mylib.AsD1(b).Name() = \"%s\"\n", d.Name())
+	}
+	if _, ok := mylib.AsD2(b); !ok {
+		fmt.Printf("mylib.AsD2(b)...[not a D2]\n")
+	}
+	if d, ok := mylib.GocxxMostDerived(b).(mylib.D1); ok {
+		fmt.Printf("mylib.GocxxMostDerived(b).Name() = \"%s\"\n", d.Name())
+	}
+
 	mylib.DeleteD1(d1)
 
 	fmt.Printf("mylib.NewD1(\"d12\")...\n")
@@ -67,4 +78,10 @@ func main() {
 	mylib.DeleteBase(d12)
 	fmt.Printf("delete d12 via ~Base...[ok]\n")
 
+	fmt.Printf("\n/// test methods inherited from the base-class\n")
+	d2 := mylib.NewD2("d2")
+	fmt.Printf("d2.Do_hello(\"you\")...\n")
+	d2.Do_hello("you")
+	mylib.DeleteD2(d2)
+
 }
@@ -22,9 +22,9 @@ func main() {
 		fmt.Printf("mylib.NewAlg(\"%s\")...[ok]\n", alg.Name())
 
 		fmt.Printf("adding alg to app...\n")
-		sc := app.AddAlg(alg)
-		if sc != 0 {
-			fmt.Printf("adding alg to app...[err]\n")
+		err := app.AddAlg(alg)
+		if err != nil {
+			fmt.Printf("adding alg to app...[err]: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("adding alg to app...[ok]\n")
@@ -32,9 +32,9 @@ func main() {
 	}
 
 	fmt.Printf("running app...\n")
-	sc := app.Run()
-	if sc != 0 {
-		fmt.Printf("running app...[err]\n")
+	err := app.Run()
+	if err != nil {
+		fmt.Printf("running app...[err]: %v\n", err)
 		os.Exit(1)
 	}
 	fmt.Printf("running app...[ok]\n")
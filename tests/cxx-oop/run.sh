@@ -65,7 +65,10 @@ function gen_cxxdict() {
         || return 1
 
     echo ":: go-gencxxwrapper..."
-    go-gencxxwrapper -fname ./ids.db || return 1
+    # the 'Sc' status codes are returned as Go errors
+    go-gencxxwrapper -fname ./ids.db \
+        -errors 'int==0@Alg::*,int==0@App::*' \
+        || return 1
     gofmt -w . || return 1
 
     /bin/cp mylib_cxxgo.plugin.h ${GOCXXDICTTESTROOT}/include/.
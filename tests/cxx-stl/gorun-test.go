@@ -24,8 +24,12 @@ func main() {
 	fmt.Printf("c.N_ints()= ?\n")
 	fmt.Printf("c.N_ints()= %v\n", *c.N_ints())
 
-	fmt.Printf("c.Ints().Size()= ?\n")
-	fmt.Printf("c.Ints().Size()= %v\n", c.Ints().Size())
+	fmt.Printf("len(c.Ints())= ?\n")
+	fmt.Printf("len(c.Ints())= %v\n", len(c.Ints()))
+
+	fmt.Printf("c.Set_ints([]int32{1, 2, 3})...\n")
+	c.Set_ints([]int32{1, 2, 3})
+	fmt.Printf("c.Ints()= %v\n", c.Ints())
 
 	fmt.Printf("c.Nbr_doubles()= ?\n")
 	fmt.Printf("c.Nbr_doubles()= %v\n", c.Nbr_doubles())
@@ -34,6 +38,7 @@ func main() {
 	c.Add(0.66)
 	fmt.Printf("c.Nbr_doubles()= ?\n")
 	fmt.Printf("c.Nbr_doubles()= %v\n", c.Nbr_doubles())
+	fmt.Printf("c.Doubles()= %v\n", c.Doubles())
 
 	fmt.Printf("mylib.DeleteClass(c)...\n")
 	mylib.DeleteClass(c)
@@ -54,6 +59,12 @@ func main() {
 	nn = n.Name()
 	fmt.Printf("n.Name() = \"%s\"\n", nn)
 
+	fmt.Printf("n.Greet(\"you\") = \"%s\"\n", n.Greet("you"))
+
+	out := "name: "
+	n.AppendName(&out)
+	fmt.Printf("n.AppendName(&out) = \"%s\"\n", out)
+
 	fmt.Printf("mylib.DeleteNamed(n)...\n")
 	mylib.DeleteNamed(n)
 	fmt.Printf("mylib.DeleteNamed(n)... [ok]\n")
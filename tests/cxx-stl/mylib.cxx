@@ -26,6 +26,12 @@ Class::doubles() const
   return m_doubles;
 }
 
+void
+Class::set_ints(const std::vector<int>& ints)
+{
+  m_ints = ints;
+}
+
 void
 Class::add(int i)
 {
@@ -56,3 +62,15 @@ Named::setName(const std::string& name)
 {
   m_name = name;
 }
+
+std::string
+Named::greet(const char* who) const
+{
+  return "hello " + std::string(who) + ", I am " + m_name;
+}
+
+void
+Named::appendName(std::string& out) const
+{
+  out += m_name;
+}
@@ -19,6 +19,7 @@ public:
 
   const std::vector<int>& ints() const;
   const std::vector<double>& doubles() const;
+  void set_ints(const std::vector<int>& ints);
 
   int nbr_ints() const { return m_ints.size(); }
   int nbr_doubles() const { return m_doubles.size(); }
@@ -40,6 +41,9 @@ public:
 
   const std::string& name() const;
   void setName(const std::string& name);
+
+  std::string greet(const char* who) const;
+  void appendName(std::string& out) const;
 };
 
 #endif /* !MYLIB_HH */